@@ -0,0 +1,120 @@
+package geoautogroup
+
+import (
+    "fmt"
+    "path"
+    "time"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-time-index"
+)
+
+// SetAdjacentImageWindow has images that can't be matched to a location record
+// borrow the location of the nearest image (from any camera) that has its own
+// location and was taken within the given window. This must be called before
+// grouping. A zero window disables this (the default).
+func (fg *FindGroups) SetAdjacentImageWindow(window time.Duration) {
+    fg.adjacentImageWindow = window
+
+    if window == 0 {
+        fg.locatedImageTs = nil
+        return
+    }
+
+    // Only take the images that have their own location. Images that are
+    // matched to location records while grouping will not be used.
+
+    fg.locatedImageTs = make(timeindex.TimeSlice, 0)
+    for _, te := range fg.imageTs {
+        items := make([]interface{}, 0)
+        for _, item := range te.Items {
+            if item.(*geoindex.GeographicRecord).HasGeographic == true {
+                items = append(items, item)
+            }
+        }
+
+        if len(items) == 0 {
+            continue
+        }
+
+        locatedTe := timeindex.TimeEntry{
+            Time:  te.Time,
+            Items: items,
+        }
+
+        fg.locatedImageTs = append(fg.locatedImageTs, locatedTe)
+    }
+}
+
+// findAdjacentLocatedImage returns the located image nearest in time to the
+// given image or nil if there isn't one within the window.
+func (fg *FindGroups) findAdjacentLocatedImage(imageGr *geoindex.GeographicRecord) (adjacentGr *geoindex.GeographicRecord) {
+    position := timeindex.SearchTimes(fg.locatedImageTs, imageGr.Timestamp)
+
+    var nearestDelta time.Duration
+    for _, i := range []int{position - 1, position} {
+        if i < 0 || i >= len(fg.locatedImageTs) {
+            continue
+        }
+
+        te := fg.locatedImageTs[i]
+
+        delta := imageGr.Timestamp.Sub(te.Time)
+        if delta < 0 {
+            delta = -delta
+        }
+
+        if delta > fg.adjacentImageWindow || adjacentGr != nil && delta >= nearestDelta {
+            continue
+        }
+
+        for _, item := range te.Items {
+            candidateGr := item.(*geoindex.GeographicRecord)
+            if candidateGr.Filepath == imageGr.Filepath {
+                continue
+            }
+
+            adjacentGr = candidateGr
+            nearestDelta = delta
+
+            break
+        }
+    }
+
+    return adjacentGr
+}
+
+// snapToAdjacentImage assigns the location of the nearest located image to
+// the given image. It returns false if this is disabled or there was no
+// located image within the window.
+func (fg *FindGroups) snapToAdjacentImage(imageGr *geoindex.GeographicRecord) (snapped bool) {
+    if fg.adjacentImageWindow == 0 {
+        return false
+    }
+
+    adjacentGr := fg.findAdjacentLocatedImage(imageGr)
+    if adjacentGr == nil {
+        return false
+    }
+
+    imageGr.Latitude = adjacentGr.Latitude
+    imageGr.Longitude = adjacentGr.Longitude
+    imageGr.S2CellId = adjacentGr.S2CellId
+
+    timeDelta := imageGr.Timestamp.Sub(adjacentGr.Timestamp)
+
+    comment := fmt.Sprintf("Borrowed geographic info from adjacent image [%s] with timestamp [%s] (%.6f, %.6f) TIME-DELTA=[%v]", path.Base(adjacentGr.Filepath), adjacentGr.Timestamp.Format(time.RFC3339), adjacentGr.Latitude, adjacentGr.Longitude, timeDelta)
+    imageGr.AddComment(comment)
+    imageGr.AddRelated(adjacentGr, GeographicRelationshipSourceAdjacentImage)
+
+    fields := map[string]interface{}{
+        "adjacent_filepath": adjacentGr.Filepath,
+        "time_delta":        timeDelta.String(),
+    }
+
+    fg.tracer.Debug(imageGr.Filepath, TraceStageAdjacent, comment, fields)
+
+    imageGr.HasGeographic = true
+
+    return true
+}
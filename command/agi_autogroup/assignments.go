@@ -0,0 +1,171 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "encoding/csv"
+    "encoding/json"
+
+    "github.com/dsoprea/go-logging"
+)
+
+// Formats of the assignments export.
+const (
+    assignmentsFormatCsv      = "csv"
+    assignmentsFormatColumnar = "columnar-json"
+)
+
+var (
+    // assignmentsColumns are the columns of the assignments export in order.
+    assignmentsColumns = []string{
+        "source_filepath",
+        "destination_filepath",
+        "timestamp_utc",
+        "timestamp_local",
+        "camera_model",
+        "latitude",
+        "longitude",
+        "location_method",
+        "nearest_city",
+        "country",
+        "group",
+        "trip",
+        "merge_notes",
+    }
+)
+
+// getAssignmentRows returns one row per grouped image in the order of
+// `assignmentsColumns`. The coordinates are float64s and everything else is a
+// string. Values that we don't have (the destination of images that weren't
+// copied, the coordinates of images without a location, etc..) are nil.
+func getAssignmentRows(outputGroups []*outputGroup, fileMappings map[string]imageFileMapping) (rows [][]interface{}) {
+    rows = make([][]interface{}, 0)
+
+    // optional returns nil for empty strings.
+    optional := func(value string) interface{} {
+        if value == "" {
+            return nil
+        }
+
+        return value
+    }
+
+    for _, og := range outputGroups {
+        groupId := og.GroupKey.KeyPhrase()
+        nearestCity := og.CityRecord.CityAndProvinceState()
+
+        for _, imageGr := range og.Records {
+            var destinationFilepath interface{}
+            if ifm, found := fileMappings[imageGr.Filepath]; found == true {
+                destinationFilepath = ifm.OutputFilepath
+            }
+
+            var latitude, longitude interface{}
+            if imageGr.HasGeographic == true {
+                latitude = imageGr.Latitude
+                longitude = imageGr.Longitude
+            }
+
+            row := []interface{}{
+                imageGr.Filepath,
+                destinationFilepath,
+                imageGr.Timestamp.UTC().Format(time.RFC3339),
+                imageGr.Timestamp.Local().Format(time.RFC3339),
+                optional(og.GroupKey.CameraModel),
+                latitude,
+                longitude,
+                optional(og.LocationMethods[imageGr.Filepath]),
+                optional(nearestCity),
+                optional(og.CityRecord.Country),
+                groupId,
+                optional(og.Trip),
+                optional(strings.Join(og.MergeNotes[imageGr.Filepath], "; ")),
+            }
+
+            rows = append(rows, row)
+        }
+    }
+
+    return rows
+}
+
+// formatAssignmentValue returns the CSV representation of a value from
+// `getAssignmentRows`. Missing values are empty.
+func formatAssignmentValue(value interface{}) string {
+    switch v := value.(type) {
+    case float64:
+        return strconv.FormatFloat(v, 'f', 6, 64)
+    case string:
+        return v
+    }
+
+    return ""
+}
+
+// writeAssignments writes the final assignment of every grouped image as a
+// flat table. The "csv" format has one row per image. The "columnar-json"
+// format is a single object mapping each column name to an array of values,
+// which loads much faster than CSV for large libraries (e.g. with
+// `pandas.DataFrame(json.load(f))`). The coordinates are written as numbers
+// and missing values as nulls.
+func writeAssignments(outputGroups []*outputGroup, fileMappings map[string]imageFileMapping, filepath, format string) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    rows := getAssignmentRows(outputGroups, fileMappings)
+
+    f, err := os.Create(filepath)
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    if format == assignmentsFormatCsv {
+        w := csv.NewWriter(f)
+
+        err = w.Write(assignmentsColumns)
+        log.PanicIf(err)
+
+        for _, row := range rows {
+            record := make([]string, len(row))
+            for i, value := range row {
+                record[i] = formatAssignmentValue(value)
+            }
+
+            err = w.Write(record)
+            log.PanicIf(err)
+        }
+
+        w.Flush()
+
+        err = w.Error()
+        log.PanicIf(err)
+    } else if format == assignmentsFormatColumnar {
+        columns := make(map[string][]interface{}, len(assignmentsColumns))
+        for i, name := range assignmentsColumns {
+            values := make([]interface{}, len(rows))
+            for j, row := range rows {
+                values[j] = row[i]
+            }
+
+            columns[name] = values
+        }
+
+        e := json.NewEncoder(f)
+
+        err = e.Encode(columns)
+        log.PanicIf(err)
+    } else {
+        log.Panicf("assignments format [%s] not valid", format)
+    }
+
+    fmt.Printf("Wrote (%d) assignments to: %s\n", len(rows), filepath)
+
+    return nil
+}
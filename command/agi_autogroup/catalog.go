@@ -4,6 +4,7 @@ import (
     "fmt"
     "path"
     "sort"
+    "time"
 
     "github.com/dsoprea/go-geographic-attractor"
     "github.com/dsoprea/go-geographic-index"
@@ -15,8 +16,9 @@ import (
 )
 
 type catalogItem struct {
-    groupKey   geoautogroup.GroupKey
-    linkWidget sitebuilder.LinkWidget
+    groupKey    geoautogroup.GroupKey
+    linkWidget  sitebuilder.LinkWidget
+    coverWidget sitebuilder.ImageWidget
 }
 
 // sortableLinks is a sortable slice of catalog items. We use it to sort the
@@ -47,19 +49,80 @@ func (sl sortableLinks) Less(i, j int) bool {
     return first.groupKey.CameraModel < second.groupKey.CameraModel
 }
 
+type catalogOptions struct {
+    // NoEmbedImages shows the images directly rather than thumbnails.
+    NoEmbedImages bool
+
+    // ThumbnailsPath is where the thumbnails are cached.
+    ThumbnailsPath string
+
+    // MapTilesPath is a local "<z>/<x>/<y>.png" tile directory for the map or
+    // empty.
+    MapTilesPath string
+
+    // MapOutlineFilepath is a GeoJSON file to draw on the map when there are no
+    // tiles or empty.
+    MapOutlineFilepath string
+
+    // FileMappings are the copied images. Images that weren't copied are
+    // referenced in place.
+    FileMappings map[string]imageFileMapping
+}
+
+// getCatalogTimePhrase formats the timestamp in local time for the catalog.
+func getCatalogTimePhrase(t time.Time) string {
+    localTime := t.Local()
+    tzName, _ := localTime.Zone()
+
+    return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d %s", localTime.Year(), localTime.Month(), localTime.Day(), localTime.Hour(), localTime.Minute(), localTime.Second(), tzName)
+}
+
+// getCatalogImageFilepath returns the copy of the image if there is one or
+// the source image otherwise.
+func getCatalogImageFilepath(gr *geoindex.GeographicRecord, fileMappings map[string]imageFileMapping) string {
+    if ifm, found := fileMappings[gr.Filepath]; found == true {
+        return ifm.OutputFilepath
+    }
+
+    return gr.Filepath
+}
+
+// getCatalogDisplayFilepath returns the thumbnail of the image or the image
+// itself if `tc` is nil or the thumbnail can't be created.
+func getCatalogDisplayFilepath(tc *geoautogroup.ThumbnailCache, imageFilepath string) (displayFilepath string, isThumbnail bool) {
+    if tc == nil {
+        return imageFilepath, false
+    }
+
+    thumbnailFilepath, err := tc.Get(imageFilepath)
+    if err != nil {
+        // Images that we can't decode are shown as-is.
+        mainLogger.Warningf(nil, "Could not create thumbnail for [%s]: %s", imageFilepath, err)
+
+        return imageFilepath, false
+    }
+
+    return thumbnailFilepath, true
+}
+
 // writeDestHtmlCatalog will write an HTML catalog to the disk. Note that the
 // catalog is organized by original groups whereas the the physical folders on
-// the disk may or may not be combined based on the folder-name template.
-func writeDestHtmlCatalog(groupArguments groupParameters, fg *geoautogroup.FindGroups, collected []map[string]interface{}, copyPath string, noEmbedImages bool, fileMappings map[string]imageFileMapping) (err error) {
+// the disk may or may not be combined based on the folder-name template. The
+// index shows the first image, time-range, city, camera, and count of every
+// group and links to a map of the groups.
+func writeDestHtmlCatalog(outputGroups []*outputGroup, catalogPath string, co catalogOptions) (err error) {
     defer func() {
         if state := recover(); state != nil {
             err = log.Wrap(state.(error))
         }
     }()
 
-    // TODO(dustin): !! Finish implementing `noEmbedImages`.
+    var tc *geoautogroup.ThumbnailCache
+    if co.NoEmbedImages == false {
+        tc = geoautogroup.NewThumbnailCache(co.ThumbnailsPath, catalogImageWidth)
+    }
 
-    sc := sitebuilder.NewSiteContext(copyPath)
+    sc := sitebuilder.NewSiteContext(catalogPath)
     md := markdowndialect.NewMarkdownDialect()
 
     sb := sitebuilder.NewSiteBuilder(destHtmlCatalogDefaultName, md, sc)
@@ -68,31 +131,38 @@ func writeDestHtmlCatalog(groupArguments groupParameters, fg *geoautogroup.FindG
 
     rootNode := sb.Root()
 
-    nearestCityIndex := fg.NearestCityIndex()
+    // The map shows the covers, too. They are relative to the catalog and keyed
+    // by group key-phrase.
+    coverSources := make(map[string]string)
 
     catalogItems := make([]catalogItem, 0)
-    for _, item := range collected {
-        groupKey := item["group_key"].(geoautogroup.GroupKey)
-        groupedItems := item["records"].([]*geoindex.GeographicRecord)
+    for _, og := range outputGroups {
+        groupKey := og.GroupKey
+        groupedItems := og.Records
+        cityRecord := og.CityRecord
 
-        cityRecord := nearestCityIndex[groupKey.NearestCityKey]
+        childPageTitle := fmt.Sprintf("%s (%s) %s", getCatalogTimePhrase(groupKey.TimeKey), cityRecord.CityAndProvinceState(), groupKey.CameraModel)
 
-        localTimeKey := groupKey.TimeKey.Local()
+        childPageId, err := writeDestHtmlCatalogGroup(rootNode, groupKey, cityRecord, childPageTitle, groupedItems, co.FileMappings, catalogPath, tc)
+        log.PanicIf(err)
 
-        tzName, _ := localTimeKey.Zone()
-        timePhrase := fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d %s", localTimeKey.Year(), localTimeKey.Month(), localTimeKey.Day(), localTimeKey.Hour(), localTimeKey.Minute(), localTimeKey.Second(), tzName)
-        childPageTitle := fmt.Sprintf("%s (%s) %s", timePhrase, cityRecord.CityAndProvinceState(), groupKey.CameraModel)
+        description := fmt.Sprintf("%s to %s, %s, %s (%d)", getCatalogTimePhrase(og.First), getCatalogTimePhrase(og.Last), cityRecord.CityAndProvinceState(), groupKey.CameraModel, len(groupedItems))
 
-        navbarTitle := fmt.Sprintf("%s (%d)", childPageTitle, len(groupedItems))
+        catalogLw := sitebuilder.NewLinkWidget(description, sitebuilder.NewSitePageLocalResourceLocator(sb, childPageId))
 
-        childPageId, err := writeDestHtmlCatalogGroup(rootNode, groupKey, cityRecord, childPageTitle, groupedItems, fileMappings)
-        log.PanicIf(err)
+        // The cover is the first image of the group.
 
-        catalogLw := sitebuilder.NewLinkWidget(navbarTitle, sitebuilder.NewSitePageLocalResourceLocator(sb, childPageId))
+        coverFilepath, _ := getCatalogDisplayFilepath(tc, getCatalogImageFilepath(groupedItems[0], co.FileMappings))
+        coverSource := getRelativePath(catalogPath, coverFilepath)
+
+        coverSources[groupKey.KeyPhrase()] = coverSource
+
+        coverIw := sitebuilder.NewImageWidget(path.Base(coverFilepath), sitebuilder.NewLocalResourceLocator(coverSource), catalogCoverWidth, catalogImageHeight)
 
         ci := catalogItem{
-            groupKey:   groupKey,
-            linkWidget: catalogLw,
+            groupKey:    groupKey,
+            linkWidget:  catalogLw,
+            coverWidget: coverIw,
         }
 
         catalogItems = append(catalogItems, ci)
@@ -101,30 +171,43 @@ func writeDestHtmlCatalog(groupArguments groupParameters, fg *geoautogroup.FindG
     stl := sortableLinks(catalogItems)
     sort.Sort(stl)
 
-    catalogLinks := make([]sitebuilder.LinkWidget, len(stl))
-    for i, ci := range stl {
-        catalogLinks[i] = ci.linkWidget
-    }
-
     rootPb := rootNode.Builder()
 
-    // Add navbar with page links.
-
-    nw := sitebuilder.NewNavbarWidget(catalogLinks)
+    mapLw := sitebuilder.NewLinkWidget("Map", sitebuilder.NewLocalResourceLocator(catalogMapFilename))
 
-    // TODO(dustin): !! Replace this with a list of descriptions and the first image.
-    err = rootPb.AddVerticalNavbar(nw, "Groups")
+    err = rootPb.AddContentLink(mapLw)
     log.PanicIf(err)
 
+    for _, ci := range stl {
+        err = rootPb.AddContentImage(ci.coverWidget)
+        log.PanicIf(err)
+
+        err = rootPb.AddContentLink(ci.linkWidget)
+        log.PanicIf(err)
+    }
+
     // Render and write.
 
     err = sb.WriteToPath()
     log.PanicIf(err)
 
+    err = writeCatalogMap(outputGroups, catalogPath, co, coverSources)
+    log.PanicIf(err)
+
     return nil
 }
 
-func writeDestHtmlCatalogGroup(rootNode *sitebuilder.SiteNode, groupKey geoautogroup.GroupKey, cr geoattractor.CityRecord, pageTitle string, groupedItems []*geoindex.GeographicRecord, fileMappings map[string]imageFileMapping) (childPageId string, err error) {
+// getCatalogGroupPageFilepath returns the file-path of the catalog page for the
+// given group. The site-builder writes each page as "<page ID>.html" at the top
+// of the catalog.
+func getCatalogGroupPageFilepath(catalogPath string, groupKey geoautogroup.GroupKey) string {
+    return path.Join(catalogPath, groupKey.KeyPhrase()+".html")
+}
+
+// writeDestHtmlCatalogGroup adds the page for one group. If `tc` is not nil,
+// the page shows thumbnails that link to the images. Otherwise, the page shows
+// the images directly.
+func writeDestHtmlCatalogGroup(rootNode *sitebuilder.SiteNode, groupKey geoautogroup.GroupKey, cr geoattractor.CityRecord, pageTitle string, groupedItems []*geoindex.GeographicRecord, fileMappings map[string]imageFileMapping, catalogPath string, tc *geoautogroup.ThumbnailCache) (childPageId string, err error) {
     defer func() {
         if state := recover(); state != nil {
             err = log.Wrap(state.(error))
@@ -142,21 +225,33 @@ func writeDestHtmlCatalogGroup(rootNode *sitebuilder.SiteNode, groupKey geoautog
     // Add images.
 
     for _, gr := range groupedItems {
-        imageLocations, found := fileMappings[gr.Filepath]
-        if found == false {
-            log.Panicf("Could not find copied file-path for [%s] out of (%d) mappings.", gr.Filepath, len(fileMappings))
-        }
+        imageFilepath := getCatalogImageFilepath(gr, fileMappings)
+        imageLrl := sitebuilder.NewLocalResourceLocator(getRelativePath(catalogPath, imageFilepath))
+
+        filename := path.Base(imageFilepath)
 
-        lrl := sitebuilder.NewLocalResourceLocator(imageLocations.RelativeFilepathFromCatalog)
+        displayFilepath, isThumbnail := getCatalogDisplayFilepath(tc, imageFilepath)
+        if isThumbnail == false {
+            // TODO(dustin): !! Insert descriptions for each image.
+            iw := sitebuilder.NewImageWidget(filename, imageLrl, catalogImageWidth, catalogImageHeight)
 
-        filename := path.Base(imageLocations.RelativeFilepathFromCatalog)
+            err = childPb.AddContentImage(iw)
+            log.PanicIf(err)
 
-        // TODO(dustin): !! Insert descriptions for each image.
-        // TODO(dustin): !! We should also take a nil-able link that we will link the image against if present.
-        iw := sitebuilder.NewImageWidget(filename, lrl, catalogImageWidth, catalogImageHeight)
+            continue
+        }
+
+        thumbnailLrl := sitebuilder.NewLocalResourceLocator(getRelativePath(catalogPath, displayFilepath))
+
+        iw := sitebuilder.NewImageWidget(filename, thumbnailLrl, catalogImageWidth, catalogImageHeight)
 
         err = childPb.AddContentImage(iw)
         log.PanicIf(err)
+
+        lw := sitebuilder.NewLinkWidget(filename, imageLrl)
+
+        err = childPb.AddContentLink(lw)
+        log.PanicIf(err)
     }
 
     return childPageId, nil
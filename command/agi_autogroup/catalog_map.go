@@ -0,0 +1,535 @@
+package main
+
+import (
+    "os"
+    "path"
+
+    "encoding/json"
+    "html/template"
+    "io/ioutil"
+
+    "github.com/dsoprea/go-logging"
+)
+
+const (
+    catalogMapFilename = "map.html"
+)
+
+// catalogMapGroup is a group as it is given to the map page.
+type catalogMapGroup struct {
+    Name        string      `json:"name"`
+    Start       string      `json:"start"`
+    End         string      `json:"end"`
+    CameraModel string      `json:"camera_model"`
+    Count       int         `json:"count"`
+    Latitude    float64     `json:"latitude"`
+    Longitude   float64     `json:"longitude"`
+    Page        string      `json:"page"`
+    Cover       string      `json:"cover,omitempty"`
+    Images      [][]float64 `json:"images"`
+}
+
+// catalogMapData is everything that the map page draws.
+type catalogMapData struct {
+    Groups []catalogMapGroup `json:"groups"`
+
+    // TileUrlTemplate is the relative URL of the tiles with "{z}", "{x}", and
+    // "{y}" placeholders or empty if there aren't any.
+    TileUrlTemplate string `json:"tile_url_template,omitempty"`
+
+    // Outline is GeoJSON to draw as the background when there aren't any
+    // tiles.
+    Outline json.RawMessage `json:"outline"`
+}
+
+// writeCatalogMap writes a page that plots the located groups at their
+// centroids, along with their images, over either locally-stored tiles or a
+// vector outline (our own coarse one unless another is given). Everything is
+// embedded or referenced locally so that the page works without a network
+// connection. `coverSources` are the cover images of
+// the groups, relative to the catalog and keyed by group key-phrase.
+func writeCatalogMap(outputGroups []*outputGroup, catalogPath string, co catalogOptions, coverSources map[string]string) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    cmd := catalogMapData{
+        Groups: make([]catalogMapGroup, 0),
+    }
+
+    for _, og := range outputGroups {
+        if og.Located == false {
+            continue
+        }
+
+        images := make([][]float64, 0, len(og.Records))
+        for _, gr := range og.Records {
+            if gr.HasGeographic == true {
+                images = append(images, []float64{gr.Latitude, gr.Longitude})
+            }
+        }
+
+        keyPhrase := og.GroupKey.KeyPhrase()
+
+        cmg := catalogMapGroup{
+            Name:        og.CityRecord.CityAndProvinceState(),
+            Start:       getCatalogTimePhrase(og.First),
+            End:         getCatalogTimePhrase(og.Last),
+            CameraModel: og.GroupKey.CameraModel,
+            Count:       len(og.Records),
+            Latitude:    og.CentroidLatitude,
+            Longitude:   og.CentroidLongitude,
+            Page:        getRelativePath(catalogPath, getCatalogGroupPageFilepath(catalogPath, og.GroupKey)),
+            Cover:       coverSources[keyPhrase],
+            Images:      images,
+        }
+
+        cmd.Groups = append(cmd.Groups, cmg)
+    }
+
+    if co.MapTilesPath != "" {
+        cmd.TileUrlTemplate = getRelativePath(catalogPath, co.MapTilesPath) + "/{z}/{x}/{y}.png"
+    }
+
+    if co.MapOutlineFilepath != "" {
+        data, err := ioutil.ReadFile(co.MapOutlineFilepath)
+        log.PanicIf(err)
+
+        if json.Valid(data) == false {
+            log.Panicf("map outline is not valid JSON: [%s]", co.MapOutlineFilepath)
+        }
+
+        cmd.Outline = json.RawMessage(data)
+    } else {
+        cmd.Outline = json.RawMessage(catalogMapWorldOutline)
+    }
+
+    err = os.MkdirAll(catalogPath, 0755)
+    log.PanicIf(err)
+
+    f, err := os.Create(path.Join(catalogPath, catalogMapFilename))
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    replacements := map[string]interface{}{
+        "Title": destHtmlCatalogDefaultName,
+        "Data":  cmd,
+    }
+
+    err = catalogMapTemplate.Execute(f, replacements)
+    log.PanicIf(err)
+
+    return nil
+}
+
+var (
+    // catalogMapTemplate draws the map with a canvas so that there are no
+    // dependencies. Tiles (if given) and the outline are drawn in Web Mercator.
+    // Groups and images that are close together on the screen are clustered;
+    // clicking a cluster zooms into it and clicking a group shows its details.
+    catalogMapTemplate = template.Must(template.New("catalog map").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}: Map</title>
+<style>
+html, body { margin: 0; height: 100%; font-family: sans-serif; }
+#map { display: block; width: 100%; height: 100%; background: #dde8f0; cursor: grab; }
+#details { position: absolute; top: 10px; right: 10px; max-width: 280px; padding: 10px; background: #fff; border: 1px solid #999; display: none; }
+#details img { max-width: 100%; }
+#back { position: absolute; top: 10px; left: 10px; padding: 4px 8px; background: #fff; border: 1px solid #999; }
+</style>
+</head>
+<body>
+<canvas id="map"></canvas>
+<a id="back" href="index.html">Catalog</a>
+<div id="details"></div>
+<script>
+(function() {
+    var data = {{.Data}};
+
+    var TILE_SIZE = 256;
+    var MAX_ZOOM = 19;
+    var GROUP_CLUSTER_RADIUS = 40;
+    var IMAGE_CLUSTER_RADIUS = 12;
+
+    var canvas = document.getElementById("map");
+    var context = canvas.getContext("2d");
+    var details = document.getElementById("details");
+
+    // The view is the zoom and the world-pixel at the center of the canvas.
+    var zoom = 1;
+    var centerX = 0;
+    var centerY = 0;
+
+    var tiles = {};
+    var clickTargets = [];
+
+    function project(latitude, longitude, z) {
+        var scale = TILE_SIZE * Math.pow(2, z);
+        var sinLatitude = Math.sin(Math.max(-85, Math.min(85, latitude)) * Math.PI / 180);
+
+        return [
+            (longitude + 180) / 360 * scale,
+            (0.5 - Math.log((1 + sinLatitude) / (1 - sinLatitude)) / (4 * Math.PI)) * scale
+        ];
+    }
+
+    function toScreen(latitude, longitude) {
+        var p = project(latitude, longitude, zoom);
+        return [p[0] - centerX + canvas.width / 2, p[1] - centerY + canvas.height / 2];
+    }
+
+    function cluster(items, radius) {
+        var cells = {};
+        var clusters = [];
+
+        items.forEach(function(item) {
+            var key = Math.floor(item.x / radius) + "," + Math.floor(item.y / radius);
+
+            var c = cells[key];
+            if (c === undefined) {
+                c = {x: 0, y: 0, items: []};
+                cells[key] = c;
+                clusters.push(c);
+            }
+
+            c.items.push(item);
+        });
+
+        clusters.forEach(function(c) {
+            c.items.forEach(function(item) {
+                c.x += item.x / c.items.length;
+                c.y += item.y / c.items.length;
+            });
+        });
+
+        return clusters;
+    }
+
+    function drawTiles() {
+        var z = Math.max(0, Math.min(MAX_ZOOM, Math.round(zoom)));
+        var scale = Math.pow(2, zoom - z);
+        var size = TILE_SIZE * scale;
+        var count = Math.pow(2, z);
+
+        var left = centerX - canvas.width / 2;
+        var top = centerY - canvas.height / 2;
+
+        for (var tx = Math.floor(left / size); tx <= Math.floor((left + canvas.width) / size); tx++) {
+            for (var ty = Math.floor(top / size); ty <= Math.floor((top + canvas.height) / size); ty++) {
+                if (ty < 0 || ty >= count) {
+                    continue;
+                }
+
+                var wrappedX = ((tx % count) + count) % count;
+                var url = data.tile_url_template.replace("{z}", z).replace("{x}", wrappedX).replace("{y}", ty);
+
+                var image = tiles[url];
+                if (image === undefined) {
+                    image = new Image();
+                    image.onload = draw;
+                    image.src = url;
+                    tiles[url] = image;
+                }
+
+                if (image.complete && image.naturalWidth > 0) {
+                    context.drawImage(image, tx * size - left, ty * size - top, size, size);
+                }
+            }
+        }
+    }
+
+    function drawRing(ring) {
+        ring.forEach(function(coordinate, i) {
+            var p = toScreen(coordinate[1], coordinate[0]);
+            if (i === 0) {
+                context.moveTo(p[0], p[1]);
+            } else {
+                context.lineTo(p[0], p[1]);
+            }
+        });
+    }
+
+    function drawGeometry(geometry) {
+        if (geometry === null) {
+            return;
+        }
+
+        var polygons = [];
+        var lines = [];
+
+        if (geometry.type === "Polygon") {
+            polygons = [geometry.coordinates];
+        } else if (geometry.type === "MultiPolygon") {
+            polygons = geometry.coordinates;
+        } else if (geometry.type === "LineString") {
+            lines = [geometry.coordinates];
+        } else if (geometry.type === "MultiLineString") {
+            lines = geometry.coordinates;
+        } else if (geometry.type === "GeometryCollection") {
+            geometry.geometries.forEach(drawGeometry);
+        }
+
+        polygons.forEach(function(polygon) {
+            context.beginPath();
+            polygon.forEach(drawRing);
+            context.fill("evenodd");
+            context.stroke();
+        });
+
+        lines.forEach(function(line) {
+            context.beginPath();
+            drawRing(line);
+            context.stroke();
+        });
+    }
+
+    function drawOutline() {
+        context.fillStyle = "#f4f1e8";
+        context.strokeStyle = "#888";
+        context.lineWidth = 1;
+
+        var outline = data.outline;
+        if (outline.type === "FeatureCollection") {
+            outline.features.forEach(function(feature) {
+                drawGeometry(feature.geometry);
+            });
+        } else if (outline.type === "Feature") {
+            drawGeometry(outline.geometry);
+        } else {
+            drawGeometry(outline);
+        }
+    }
+
+    function drawGraticule() {
+        context.strokeStyle = "#b8c8d4";
+        context.lineWidth = 1;
+
+        for (var longitude = -180; longitude <= 180; longitude += 30) {
+            var top = toScreen(85, longitude);
+            var bottom = toScreen(-85, longitude);
+
+            context.beginPath();
+            context.moveTo(top[0], top[1]);
+            context.lineTo(bottom[0], bottom[1]);
+            context.stroke();
+        }
+
+        for (var latitude = -60; latitude <= 60; latitude += 30) {
+            var west = toScreen(latitude, -180);
+            var east = toScreen(latitude, 180);
+
+            context.beginPath();
+            context.moveTo(west[0], west[1]);
+            context.lineTo(east[0], east[1]);
+            context.stroke();
+        }
+    }
+
+    function drawCluster(c, radius, fill) {
+        context.fillStyle = fill;
+        context.strokeStyle = "#fff";
+        context.lineWidth = 2;
+
+        context.beginPath();
+        context.arc(c.x, c.y, radius, 0, 2 * Math.PI);
+        context.fill();
+        context.stroke();
+
+        if (c.items.length > 1) {
+            context.fillStyle = "#fff";
+            context.font = "11px sans-serif";
+            context.textAlign = "center";
+            context.textBaseline = "middle";
+            context.fillText(String(c.items.length), c.x, c.y);
+        }
+    }
+
+    function draw() {
+        canvas.width = canvas.clientWidth;
+        canvas.height = canvas.clientHeight;
+
+        context.clearRect(0, 0, canvas.width, canvas.height);
+
+        if (data.tile_url_template) {
+            drawTiles();
+        } else {
+            drawGraticule();
+            drawOutline();
+        }
+
+        var imageItems = [];
+        var groupItems = [];
+
+        data.groups.forEach(function(group) {
+            group.images.forEach(function(coordinate) {
+                var p = toScreen(coordinate[0], coordinate[1]);
+                imageItems.push({x: p[0], y: p[1]});
+            });
+
+            var p = toScreen(group.latitude, group.longitude);
+            groupItems.push({x: p[0], y: p[1], group: group});
+        });
+
+        cluster(imageItems, IMAGE_CLUSTER_RADIUS).forEach(function(c) {
+            drawCluster(c, c.items.length > 1 ? 5 : 3, "rgba(67, 99, 216, 0.6)");
+        });
+
+        clickTargets = cluster(groupItems, GROUP_CLUSTER_RADIUS);
+        clickTargets.forEach(function(c) {
+            drawCluster(c, c.items.length > 1 ? 12 : 7, "#e6194b");
+        });
+    }
+
+    function showGroup(group) {
+        var html = "<b>" + escapeHtml(group.name) + "</b><br>" +
+            escapeHtml(group.start) + " -<br>" + escapeHtml(group.end) + "<br>" +
+            escapeHtml(group.camera_model) + ", " + group.count + " images<br>";
+
+        if (group.cover) {
+            html += "<a href=\"" + escapeHtml(group.page) + "\"><img src=\"" + escapeHtml(group.cover) + "\"></a><br>";
+        }
+
+        html += "<a href=\"" + escapeHtml(group.page) + "\">Open</a>";
+
+        details.innerHTML = html;
+        details.style.display = "block";
+    }
+
+    function escapeHtml(s) {
+        return String(s).replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;").replace(/"/g, "&quot;");
+    }
+
+    function setZoom(newZoom, x, y) {
+        newZoom = Math.max(0, Math.min(MAX_ZOOM, newZoom));
+
+        // Keep the world-pixel under (x, y) where it is.
+        var factor = Math.pow(2, newZoom - zoom);
+        var worldX = centerX + x - canvas.width / 2;
+        var worldY = centerY + y - canvas.height / 2;
+
+        centerX = worldX * factor - (x - canvas.width / 2);
+        centerY = worldY * factor - (y - canvas.height / 2);
+        zoom = newZoom;
+
+        draw();
+    }
+
+    function fitBounds() {
+        canvas.width = canvas.clientWidth;
+        canvas.height = canvas.clientHeight;
+
+        var points = [];
+        data.groups.forEach(function(group) {
+            points.push(project(group.latitude, group.longitude, 0));
+        });
+
+        if (points.length === 0) {
+            centerX = TILE_SIZE / 2;
+            centerY = TILE_SIZE / 2;
+            zoom = Math.log(Math.min(canvas.width, canvas.height) / TILE_SIZE) / Math.LN2;
+            return;
+        }
+
+        var minX = Infinity, minY = Infinity, maxX = -Infinity, maxY = -Infinity;
+        points.forEach(function(p) {
+            minX = Math.min(minX, p[0]);
+            minY = Math.min(minY, p[1]);
+            maxX = Math.max(maxX, p[0]);
+            maxY = Math.max(maxY, p[1]);
+        });
+
+        var spanX = Math.max(maxX - minX, 0.001);
+        var spanY = Math.max(maxY - minY, 0.001);
+
+        zoom = Math.min(12, Math.log(Math.min(canvas.width / spanX, canvas.height / spanY) * 0.8) / Math.LN2);
+        zoom = Math.max(0, zoom);
+
+        var scale = Math.pow(2, zoom);
+        centerX = (minX + maxX) / 2 * scale;
+        centerY = (minY + maxY) / 2 * scale;
+    }
+
+    var dragging = null;
+
+    canvas.addEventListener("mousedown", function(e) {
+        dragging = {x: e.clientX, y: e.clientY, moved: false};
+        canvas.style.cursor = "grabbing";
+    });
+
+    window.addEventListener("mousemove", function(e) {
+        if (dragging === null) {
+            return;
+        }
+
+        var dx = e.clientX - dragging.x;
+        var dy = e.clientY - dragging.y;
+
+        if (Math.abs(dx) + Math.abs(dy) > 2) {
+            dragging.moved = true;
+        }
+
+        centerX -= dx;
+        centerY -= dy;
+        dragging.x = e.clientX;
+        dragging.y = e.clientY;
+
+        draw();
+    });
+
+    window.addEventListener("mouseup", function(e) {
+        if (dragging === null) {
+            return;
+        }
+
+        var moved = dragging.moved;
+        dragging = null;
+        canvas.style.cursor = "grab";
+
+        if (moved) {
+            return;
+        }
+
+        var rect = canvas.getBoundingClientRect();
+        var x = e.clientX - rect.left;
+        var y = e.clientY - rect.top;
+
+        for (var i = 0; i < clickTargets.length; i++) {
+            var c = clickTargets[i];
+            if (Math.pow(c.x - x, 2) + Math.pow(c.y - y, 2) > 15 * 15) {
+                continue;
+            }
+
+            if (c.items.length === 1) {
+                showGroup(c.items[0].group);
+            } else {
+                setZoom(zoom + 2, c.x, c.y);
+            }
+
+            return;
+        }
+
+        details.style.display = "none";
+    });
+
+    canvas.addEventListener("wheel", function(e) {
+        e.preventDefault();
+
+        var rect = canvas.getBoundingClientRect();
+        setZoom(zoom + (e.deltaY < 0 ? 0.5 : -0.5), e.clientX - rect.left, e.clientY - rect.top);
+    });
+
+    window.addEventListener("resize", draw);
+
+    fitBounds();
+    draw();
+})();
+</script>
+</body>
+</html>
+`))
+)
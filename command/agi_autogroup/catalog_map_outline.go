@@ -0,0 +1,38 @@
+package main
+
+// catalogMapWorldOutline is embedded into the catalog map when no outline or
+// tiles are given so that it still shows where the groups are without a network
+// connection. It is a very coarse, hand-simplified outline of the continents
+// and the larger islands (only accurate to a few degrees); use
+// --catalog-map-outline-filepath for anything better.
+const catalogMapWorldOutline = `{"type":"FeatureCollection","features":[
+{"type":"Feature","properties":{"name":"North America"},"geometry":{"type":"Polygon","coordinates":[[[-168,66],[-162,70],[-156,71.3],[-141,69.6],[-128,70],[-115,68.5],[-95,68],[-90,69],[-82,69.5],[-80,63],[-93,59],[-94,57],[-85,55],[-82,52.5],[-79,54],[-77,60],[-70,59],[-64,60],[-61,56],[-56,52],[-60,50],[-66,49.5],[-60,46.5],[-64,44.5],[-70,43.5],[-70,41.5],[-74,40.5],[-76,37],[-76,35],[-81,31.5],[-80,27],[-80.5,25.2],[-82.5,27.5],[-84,30],[-89,30.2],[-94,29.6],[-97,27.5],[-97.5,24],[-95,19],[-91,19],[-90.5,21],[-87,21.5],[-88,16],[-84,15.5],[-83.5,11],[-80,9],[-77.5,8.5],[-79,7.5],[-81,7.5],[-85.5,10],[-87.5,13],[-91.5,14],[-95,16],[-98,16],[-105,19.5],[-105.5,23],[-110,27.5],[-112.5,31],[-114.5,31.5],[-112,29],[-110,24],[-109.5,23],[-112,25],[-115,28],[-117,32.5],[-120.5,34.5],[-122.5,37.5],[-124,40.5],[-124,46.5],[-123,49],[-128,51],[-131,54.5],[-136,58],[-140,60],[-146,61],[-152,59],[-154,57.5],[-158,57],[-163,55],[-158,58.5],[-162,60],[-165,62],[-164,64],[-168,66]]]}},
+{"type":"Feature","properties":{"name":"Greenland"},"geometry":{"type":"Polygon","coordinates":[[[-73,78],[-60,82],[-35,83.5],[-20,82],[-18,77],[-22,72],[-25,69],[-32,68],[-40,65],[-43,60],[-48,61],[-53,66],[-55,70],[-60,76],[-73,78]]]}},
+{"type":"Feature","properties":{"name":"Baffin Island"},"geometry":{"type":"Polygon","coordinates":[[[-64.5,63],[-68,66.5],[-73,70],[-80,73.7],[-89,73],[-84,69.8],[-79,69.5],[-72,67.5],[-64.5,63]]]}},
+{"type":"Feature","properties":{"name":"Victoria Island"},"geometry":{"type":"Polygon","coordinates":[[[-118,69],[-103,68.5],[-101,70.2],[-105,73],[-117,72],[-118,69]]]}},
+{"type":"Feature","properties":{"name":"Ellesmere Island"},"geometry":{"type":"Polygon","coordinates":[[[-90,76.5],[-78,76.5],[-62,82],[-85,83],[-93,80],[-90,76.5]]]}},
+{"type":"Feature","properties":{"name":"Cuba"},"geometry":{"type":"Polygon","coordinates":[[[-85,21.9],[-82,23.2],[-77,22.2],[-74.2,20.2],[-77.7,19.9],[-80.6,21.7],[-85,21.9]]]}},
+{"type":"Feature","properties":{"name":"Hispaniola"},"geometry":{"type":"Polygon","coordinates":[[[-74.5,18.4],[-72.8,19.9],[-70,19.7],[-68.3,18.6],[-71.7,17.8],[-74.5,18.4]]]}},
+{"type":"Feature","properties":{"name":"South America"},"geometry":{"type":"Polygon","coordinates":[[[-77.5,8.5],[-75.5,10.5],[-72,12],[-68,10.5],[-62,10.5],[-60,8.5],[-57,6],[-52,5],[-50,1.5],[-48,-1],[-44,-2.5],[-39,-3.5],[-35,-5.5],[-35,-9],[-38.5,-13],[-39,-17.5],[-41,-22],[-44.5,-23.2],[-48.5,-26],[-48.8,-28.5],[-52,-32],[-54,-34.5],[-58,-34.5],[-57,-37],[-62,-39],[-65,-41],[-63.5,-42.5],[-65.5,-45],[-67.5,-46.5],[-66,-48],[-69,-51],[-68.5,-52.5],[-71,-54],[-74.5,-52],[-75.5,-48],[-74,-44],[-73.5,-40],[-73.5,-37],[-71.5,-32],[-71.5,-28],[-70.5,-23],[-70.2,-18.5],[-75,-15.5],[-76.5,-13.5],[-79.5,-8],[-81,-5.5],[-80.5,-2],[-80,1],[-78.5,2.5],[-77.5,4],[-77.5,8.5]]]}},
+{"type":"Feature","properties":{"name":"Eurasia"},"geometry":{"type":"Polygon","coordinates":[[[-9,43],[-9,39],[-9,37],[-6,36.2],[-2,36.8],[0,38.5],[0.5,40.5],[3.2,42],[3,43.3],[6,43],[8.5,44.3],[10.5,43],[12.5,41.5],[15.6,38],[17,39],[18.5,40.2],[16,41.9],[14,42.5],[12.3,44.5],[13.5,45.7],[15,44.5],[19.5,41.8],[20,39.5],[21.5,37],[22.5,36.5],[23,38],[24,38],[22.8,40.5],[26,40.8],[29,41.2],[26.2,39.5],[27.3,37],[30.5,36.3],[36,36.8],[35.8,34.5],[34.9,32.5],[34.3,31.3],[34.9,29.5],[35,28],[39,22],[42.5,16.5],[43.5,12.7],[45,13],[52,15.5],[55.5,17.5],[57.5,18.9],[59.8,22.5],[56.3,24.9],[56.2,26.2],[54,24],[51.5,24.5],[51,26.2],[50,26.7],[48.5,28.5],[50,30],[52,27.8],[56.5,27],[61.5,25.2],[66.5,25.4],[68.5,23.5],[70,21],[72.8,21],[73,17],[74.5,14.5],[76.5,9.5],[77.5,8],[78.2,9],[80,11.5],[80.2,15.5],[82.3,17],[86.5,20],[87,21.6],[89,21.8],[91.5,22.5],[92.5,20.5],[94.4,16],[97.5,16.5],[98.5,13],[98.5,10],[98.3,8],[100.3,5.5],[101.3,2.8],[103.5,1.3],[104.2,1.5],[103.4,4.5],[102.3,6.2],[100.5,7.4],[99.5,10],[100,13.4],[100.9,12.7],[102.5,12],[105,8.6],[106.7,10.4],[109.3,12],[108.8,15.5],[106,18.5],[107.5,21.5],[110.5,21.2],[113.5,22.2],[117,23.5],[120,26.5],[122,30],[121,32],[119.5,35],[122.5,37],[119,37.2],[118,38.5],[121.5,40.7],[122,40],[124.5,40],[126.5,37.8],[126.5,34.5],[129.3,35.2],[129.5,37],[128,38.5],[130,42.5],[135.5,43.5],[140,48],[140.5,52],[141.5,53.3],[137,54],[137.5,56.5],[143,59.3],[151,59],[155,59.5],[160,61.5],[163.5,62.5],[156.7,57.5],[156,51],[160,54.5],[163,56],[162.5,57.8],[170,60],[179.9,62.5],[179.9,68.9],[175,69.8],[170,70],[160,69.7],[150,71.5],[140,72.5],[130,71],[128,72.8],[113,73.5],[105,77.5],[100,76],[88,75.5],[80,73.5],[72.5,72.8],[68.5,68.5],[60,69],[55,68],[44,68.5],[40.5,67.5],[41,66.5],[36.5,64.5],[34.5,66],[33,69.3],[28,71],[20,70],[15,68],[11.5,64.5],[5,62],[5,59],[7,58],[10,59],[11.5,58],[12.8,55.7],[14.3,55.5],[16,56.2],[17,57.5],[19,59.9],[17.5,61],[17.3,62.5],[21.5,65],[24.5,65.7],[21.5,63],[21.5,60.5],[23,60],[29,60.5],[22,58.5],[24,57],[21,56.8],[21.2,55],[18.5,54.6],[14,54],[11,54],[9.9,57.5],[8.1,56.5],[8.6,53.8],[5,53.2],[3.6,51.5],[1.6,50.8],[-1.5,49.7],[-1.5,48.7],[-4.7,48.4],[-2.5,47.3],[-1.2,46],[-1.5,43.4],[-8,43.7],[-9,43]],[[47,45],[51,47],[53.5,46.5],[53,42],[54,40],[53,37],[49,37.5],[49.5,40.5],[47.5,43],[47,45]],[[28,41.2],[28.5,43.5],[30,45.5],[33.5,44.5],[36.5,45.3],[38,47],[39.5,45],[41.5,41.5],[36,41.7],[31,41.1],[28,41.2]]]}},
+{"type":"Feature","properties":{"name":"Great Britain"},"geometry":{"type":"Polygon","coordinates":[[[-5.7,50.1],[-3,50.6],[1.4,51.2],[1.7,52.7],[0.3,53.4],[-1.5,55],[-2,56],[-1.8,57.6],[-3,58.6],[-5,58.6],[-6.2,56.6],[-5.5,55.3],[-3,54.9],[-3.4,54],[-3,53.3],[-4.7,52.8],[-5.2,51.7],[-3.4,51.4],[-5.7,50.1]]]}},
+{"type":"Feature","properties":{"name":"Ireland"},"geometry":{"type":"Polygon","coordinates":[[[-6,52.2],[-6.2,53.9],[-5.7,54.7],[-7.5,55.3],[-8.5,54.3],[-10,54],[-9.5,52.6],[-10.3,51.8],[-8.5,51.6],[-6,52.2]]]}},
+{"type":"Feature","properties":{"name":"Iceland"},"geometry":{"type":"Polygon","coordinates":[[[-22.5,63.9],[-18.5,63.4],[-14.5,64.4],[-13.5,65.5],[-15,66.3],[-18,66.1],[-22.5,66.4],[-24,65.5],[-22.5,63.9]]]}},
+{"type":"Feature","properties":{"name":"Africa"},"geometry":{"type":"Polygon","coordinates":[[[-5.9,35.8],[-2,35.1],[3,36.9],[10,37.3],[11,35.5],[10.5,34],[15.2,32.3],[19.8,30.6],[20,32.2],[23,32.6],[29,30.8],[32.3,31.3],[34.2,31.3],[34.9,29.5],[32.6,29.9],[33.5,27.5],[35.5,24],[37.3,21],[38.5,18],[43.2,12.6],[44.5,10.4],[51.2,11.8],[51,10.5],[48.5,5],[43.5,-0.5],[40,-3],[39.5,-6.5],[40.5,-10.5],[40.5,-15],[35,-19.5],[35.5,-24],[32.8,-26],[32.5,-29],[30,-31.5],[27,-33.8],[22,-34.3],[18.5,-34.2],[18,-32],[15.5,-27],[14.5,-22.5],[11.8,-17.2],[13.5,-11],[12.2,-6],[9,-1],[9.6,3],[8.7,4.5],[5.8,4.3],[4.5,6.3],[1.5,6.1],[-2,4.7],[-7.5,4.4],[-11.5,6.8],[-13.5,9.5],[-15,11],[-17.5,14.7],[-16,19.5],[-17,21],[-15,24],[-13,27.8],[-9.8,29.8],[-9.5,32.5],[-6.8,34],[-5.9,35.8]]]}},
+{"type":"Feature","properties":{"name":"Madagascar"},"geometry":{"type":"Polygon","coordinates":[[[49.3,-12],[50.5,-15.5],[49.5,-17.5],[47,-25],[45,-25.5],[43.5,-22],[44.3,-16.5],[47.5,-14.5],[49.3,-12]]]}},
+{"type":"Feature","properties":{"name":"Sri Lanka"},"geometry":{"type":"Polygon","coordinates":[[[79.9,6.5],[80,9.8],[81.8,7.5],[81.1,6.1],[79.9,6.5]]]}},
+{"type":"Feature","properties":{"name":"Sumatra"},"geometry":{"type":"Polygon","coordinates":[[[95.3,5.6],[98,4],[100.5,2],[103.8,-1],[106,-3.3],[105.8,-5.8],[104.5,-5.9],[102,-4],[100.5,-1.3],[98.7,1.7],[95.3,5.6]]]}},
+{"type":"Feature","properties":{"name":"Java"},"geometry":{"type":"Polygon","coordinates":[[[105.2,-6.8],[108,-6.3],[111,-6.6],[114.5,-7.6],[114.4,-8.7],[110,-8.2],[106.5,-7.4],[105.2,-6.8]]]}},
+{"type":"Feature","properties":{"name":"Borneo"},"geometry":{"type":"Polygon","coordinates":[[[109,1.5],[110,-1],[111,-3],[114.5,-3.8],[116.5,-2.5],[118,1],[119,5],[117,7],[115.5,5],[113.5,3.3],[111,1.8],[109,1.5]]]}},
+{"type":"Feature","properties":{"name":"New Guinea"},"geometry":{"type":"Polygon","coordinates":[[[131,-1.5],[134,-0.9],[137,-1.5],[141,-2.6],[145,-4.3],[148,-8.1],[150.5,-10.5],[147,-10.2],[144,-7.7],[141,-9.2],[138.8,-8.2],[137.8,-5.3],[135,-4.4],[132.8,-4],[131,-1.5]]]}},
+{"type":"Feature","properties":{"name":"Luzon"},"geometry":{"type":"Polygon","coordinates":[[[120,16],[120.6,18.5],[122.2,18.5],[122.5,16],[124,13],[121.8,13.9],[120.6,14.2],[120,16]]]}},
+{"type":"Feature","properties":{"name":"Mindanao"},"geometry":{"type":"Polygon","coordinates":[[[122,7],[124,8.3],[125.5,9.8],[126.5,7.5],[125.5,5.7],[123.5,7.7],[122,7]]]}},
+{"type":"Feature","properties":{"name":"Taiwan"},"geometry":{"type":"Polygon","coordinates":[[[120.1,23],[121.5,25.3],[121.9,24.6],[120.8,21.9],[120.1,23]]]}},
+{"type":"Feature","properties":{"name":"Honshu"},"geometry":{"type":"Polygon","coordinates":[[[130,31.5],[131.5,31.5],[132,33.5],[135,33.5],[136.9,34.3],[139.8,35],[141,36],[141,38.3],[142,39.6],[141.4,41.4],[140,40.5],[139.8,38],[137,37],[136,36],[133,35.5],[131,34.4],[129.7,33.1],[130,31.5]]]}},
+{"type":"Feature","properties":{"name":"Hokkaido"},"geometry":{"type":"Polygon","coordinates":[[[140,42],[141.5,42.5],[143.2,42],[145.5,43.3],[144.5,44],[142,45.5],[141.5,44],[140,42]]]}},
+{"type":"Feature","properties":{"name":"Australia"},"geometry":{"type":"Polygon","coordinates":[[[113.5,-22],[114,-26],[115,-34],[118,-35],[123.5,-34],[126,-32.3],[131,-31.5],[134,-32.5],[135.7,-34.8],[138,-34],[138.5,-35.5],[140,-38],[143.5,-38.8],[146.5,-39],[150,-37.5],[150.8,-34.5],[153,-31],[153.5,-28],[153,-25],[150.8,-22.5],[149,-20.5],[146.3,-19],[145.3,-15],[143.5,-14],[142.5,-10.7],[141.6,-12.8],[141.5,-16.5],[140.5,-17.5],[139,-17],[136,-15],[137,-12.2],[135,-12.2],[132.5,-11.3],[130,-13],[129.5,-15],[127,-13.8],[125,-14.5],[122.2,-17],[121,-19.5],[117,-20.6],[113.5,-22]]]}},
+{"type":"Feature","properties":{"name":"Tasmania"},"geometry":{"type":"Polygon","coordinates":[[[144.6,-40.7],[148.3,-40.9],[148,-43.2],[146,-43.6],[144.6,-40.7]]]}},
+{"type":"Feature","properties":{"name":"New Zealand North Island"},"geometry":{"type":"Polygon","coordinates":[[[172.7,-34.4],[174.5,-36.5],[178.5,-37.7],[177,-39.3],[176,-41.3],[174.6,-41.3],[174.8,-39.8],[173.8,-39.2],[174.6,-37],[172.7,-34.4]]]}},
+{"type":"Feature","properties":{"name":"New Zealand South Island"},"geometry":{"type":"Polygon","coordinates":[[[172.6,-40.5],[174.3,-41.7],[173,-43.7],[171,-44.8],[169,-46.7],[166.5,-46],[167.5,-44.4],[170.5,-42.8],[172.6,-40.5]]]}},
+{"type":"Feature","properties":{"name":"Antarctica"},"geometry":{"type":"Polygon","coordinates":[[[-180,-84],[-180,-78],[-160,-78.3],[-150,-76.5],[-135,-74.5],[-120,-73.8],[-100,-73],[-80,-73],[-67,-68],[-58,-63.5],[-62,-70],[-60,-75],[-45,-78],[-30,-77],[-20,-74],[-10,-71],[0,-70],[20,-70],[40,-69],[55,-66],[70,-68],[90,-66.5],[110,-66],[135,-66],[150,-68.5],[165,-70.5],[170,-72],[165,-78],[180,-78],[180,-84],[-180,-84]]]}}
+]}`
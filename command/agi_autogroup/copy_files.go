@@ -13,13 +13,16 @@ import (
     "github.com/sbwhitecap/tqdm"
     "github.com/sbwhitecap/tqdm/iterators"
 
+    "github.com/dsoprea/go-geographic-attractor"
     "github.com/dsoprea/go-geographic-index"
     "github.com/dsoprea/go-logging"
 
     "github.com/dsoprea/go-geographic-autogroup-images"
 )
 
-func copyFiles(groupArguments groupParameters, fg *geoautogroup.FindGroups, finishedGroupKey geoautogroup.GroupKey, finishedGroup []*geoindex.GeographicRecord, copyRootPath string, imageOutputPathTemplate *template.Template, printProgressOutput bool, binnedImages map[string][]*geoindex.GeographicRecord, fileMappings map[string]imageFileMapping) (err error) {
+// getGroupFolderName returns the output folder (relative to the copy path) for
+// the given group along with the template replacements that it was built from.
+func getGroupFolderName(cityRecord geoattractor.CityRecord, finishedGroupKey geoautogroup.GroupKey, recordCount int, imageOutputPathTemplate *template.Template) (folderName string, replacements map[string]interface{}, err error) {
     defer func() {
         if state := recover(); state != nil {
             err = log.Wrap(state.(error))
@@ -28,9 +31,6 @@ func copyFiles(groupArguments groupParameters, fg *geoautogroup.FindGroups, fini
 
     timeKey := finishedGroupKey.TimeKey
 
-    nearestCityIndex := fg.NearestCityIndex()
-    cityRecord := nearestCityIndex[finishedGroupKey.NearestCityKey]
-
     camera_model := finishedGroupKey.CameraModel
 
     // This will often happen with screen-catpures and pictures downloaded
@@ -45,14 +45,15 @@ func copyFiles(groupArguments groupParameters, fg *geoautogroup.FindGroups, fini
 
     // If the city-record doesn't have a usable province-state string (where
     // `city_and_province_state` equals city), then attach the country name.
-    if location == cityRecord.City {
+    // Group names given by overrides don't have a country.
+    if location == cityRecord.City && cityRecord.Country != "" {
         location = fmt.Sprintf("%s, %s", cityRecord.City, cityRecord.Country)
     }
 
     localTimeKey := timeKey.Local()
 
     // TODO(dustin): !! We should create the directories with the local timezone, not UTC.
-    replacements := map[string]interface{}{
+    replacements = map[string]interface{}{
         "year":                    timeKey.Year(),
         "month_number":            fmt.Sprintf("%02d", localTimeKey.Month()),
         "month_name":              fmt.Sprintf("%s", localTimeKey.Month()),
@@ -63,7 +64,7 @@ func copyFiles(groupArguments groupParameters, fg *geoautogroup.FindGroups, fini
         "city_and_province_state": cityprovince,
         "location":                location,
         "country":                 cityRecord.Country,
-        "record_count":            len(finishedGroup),
+        "record_count":            recordCount,
         "camera_model":            camera_model,
         "path_sep":                string([]byte{os.PathSeparator}),
     }
@@ -72,7 +73,20 @@ func copyFiles(groupArguments groupParameters, fg *geoautogroup.FindGroups, fini
     err = imageOutputPathTemplate.Execute(b, replacements)
     log.PanicIf(err)
 
-    folderName := b.String()
+    return b.String(), replacements, nil
+}
+
+func copyFiles(outputArguments outputParameters, og *outputGroup, copyRootPath string, imageOutputPathTemplate *template.Template, printProgressOutput bool, binnedImages map[string][]*geoindex.GeographicRecord, fileMappings map[string]imageFileMapping) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    finishedGroup := og.Records
+
+    folderName, replacements, err := getGroupFolderName(og.CityRecord, og.GroupKey, len(finishedGroup), imageOutputPathTemplate)
+    log.PanicIf(err)
 
     destPath := path.Join(copyRootPath, folderName)
 
@@ -97,15 +111,13 @@ func copyFiles(groupArguments groupParameters, fg *geoautogroup.FindGroups, fini
 
         filename := path.Base(gr.Filepath)
 
-        finalFilename, err := copyFile(groupArguments, destPath, filename, gr, fileMappings)
+        finalFilename, err := copyFile(outputArguments, destPath, filename, gr, fileMappings)
         log.PanicIf(err)
 
         destFilepath := path.Join(destPath, finalFilename)
-        relFilepathFromCatalog := path.Join("..", "..", folderName, finalFilename)
 
         fileMappings[gr.Filepath] = imageFileMapping{
-            OutputFilepath:              destFilepath,
-            RelativeFilepathFromCatalog: relFilepathFromCatalog,
+            OutputFilepath: destFilepath,
         }
     }
 
@@ -145,7 +157,7 @@ func copyFiles(groupArguments groupParameters, fg *geoautogroup.FindGroups, fini
     return nil
 }
 
-func copyFile(groupArguments groupParameters, destPath, filename string, gr *geoindex.GeographicRecord, fileMappings map[string]imageFileMapping) (finalFilename string, err error) {
+func copyFile(outputArguments outputParameters, destPath, filename string, gr *geoindex.GeographicRecord, fileMappings map[string]imageFileMapping) (finalFilename string, err error) {
     defer func() {
         if state := recover(); state != nil {
             err = log.Wrap(state.(error))
@@ -172,7 +184,7 @@ func copyFile(groupArguments groupParameters, destPath, filename string, gr *geo
         }
 
         // An optimization.
-        if groupArguments.NoHashChecksOnExisting == true {
+        if outputArguments.NoHashChecksOnExisting == true {
             return filename, nil
         }
 
@@ -187,6 +199,12 @@ func copyFile(groupArguments groupParameters, destPath, filename string, gr *geo
             return filename, nil
         }
 
+        // It's a copy that we wrote the GPS EXIF into.
+        if equal, err := geoautogroup.JpegImageDataEqual(gr.Filepath, destFilepath); err == nil && equal == true {
+            mainLogger.Debugf(nil, "Image already exists (other than EXIF): [%s] => [%s]", gr.Filepath, destFilepath)
+            return filename, nil
+        }
+
         filename = fmt.Sprintf("%s (%d)%s", leftSide, i+1, destExt)
         destFilepath = path.Join(destPath, filename)
     }
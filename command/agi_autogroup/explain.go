@@ -0,0 +1,136 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "path"
+
+    "path/filepath"
+    "text/template"
+
+    "github.com/dsoprea/go-logging"
+
+    "github.com/dsoprea/go-geographic-autogroup-images"
+)
+
+type explainParameters struct {
+    groupParameters
+
+    Positional struct {
+        ImageFilepath string `positional-arg-name:"image-path" description:"The image to explain. It must be under one of the --image-path paths."`
+    } `positional-args:"yes" required:"yes"`
+}
+
+func handleExplain(explainArguments explainParameters) {
+    defer func() {
+        if state := recover(); state != nil {
+            err := log.Wrap(state.(error))
+            log.PrintError(err)
+            os.Exit(-1)
+        }
+    }()
+
+    groupArguments := explainArguments.groupParameters
+
+    // The image might be given relative or absolute, and the image paths
+    // might be as well, so trace both forms.
+
+    imageFilepath := filepath.Clean(explainArguments.Positional.ImageFilepath)
+
+    absImageFilepath, err := filepath.Abs(imageFilepath)
+    log.PanicIf(err)
+
+    groupArguments.TraceImages = []string{imageFilepath, absImageFilepath}
+    groupArguments.TraceImagePatterns = nil
+    groupArguments.TraceAllImages = false
+
+    isImage := func(candidate string) bool {
+        return candidate == imageFilepath || candidate == absImageFilepath
+    }
+
+    fg, ci := getFindGroups(groupArguments)
+
+    defer ci.Close()
+
+    gr := geoautogroup.NewGroupsReducer(fg)
+    gr.SetImageTracer(fg.ImageTracer())
+
+    collectedGroups, _ := gr.Reduce()
+
+    it := fg.ImageTracer()
+
+    fmt.Printf("Image: %s\n", explainArguments.Positional.ImageFilepath)
+    fmt.Printf("\n")
+
+    tracedFilepaths := it.Filepaths()
+    if len(tracedFilepaths) == 0 {
+        fmt.Printf("The image was not loaded. Make sure that it is under one of the --image-path paths and that it has a timestamp.\n")
+        return
+    }
+
+    fmt.Printf("Decisions\n")
+    fmt.Printf("=========\n")
+    fmt.Printf("\n")
+
+    for _, tracedFilepath := range tracedFilepaths {
+        for _, ite := range it.Events(tracedFilepath) {
+            fmt.Printf("- %s\n", ite)
+        }
+    }
+
+    fmt.Printf("\n")
+
+    fmt.Printf("Result\n")
+    fmt.Printf("======\n")
+    fmt.Printf("\n")
+
+    for _, ur := range fg.UnassignedRecords() {
+        if isImage(ur.Geographic.Filepath) == false {
+            continue
+        }
+
+        ure := ur.ReportEntry()
+
+        fmt.Printf("Unassigned: %s (%s)\n", ur.Reason.Description(), ur.Reason)
+        fmt.Printf("Matcher: %s\n", ure.LocationMatchStrategy)
+        fmt.Printf("Nearest location before: %s\n", describeLocationRecord(ur.Diagnostics.LocationBefore, ur.Geographic.Timestamp))
+        fmt.Printf("Nearest location after: %s\n", describeLocationRecord(ur.Diagnostics.LocationAfter, ur.Geographic.Timestamp))
+
+        if ure.NearestCity != "" {
+            fmt.Printf("Nearest city: %s (%.3f km)\n", ure.NearestCity, ure.NearestCityDistanceKm)
+        }
+
+        return
+    }
+
+    imageOutputPathTemplate := template.Must(template.New("group path template").Parse(groupArguments.ImageOutputPathTemplate))
+
+    copyRootPath := groupArguments.CopyPath
+    if copyRootPath == "" {
+        copyRootPath = "<copy-into-path>"
+    }
+
+    for _, groups := range collectedGroups {
+        for _, cg := range groups {
+            for _, imageGr := range cg.Records {
+                if isImage(imageGr.Filepath) == false {
+                    continue
+                }
+
+                cityRecord := fg.NearestCityIndex()[cg.GroupKey.NearestCityKey]
+
+                folderName, _, err := getGroupFolderName(fg.NearestCityIndex()[cg.GroupKey.NearestCityKey], cg.GroupKey, len(cg.Records), imageOutputPathTemplate)
+                log.PanicIf(err)
+
+                fmt.Printf("Group: %s\n", cg.GroupKey)
+                fmt.Printf("City: %s\n", cityRecord.CityAndProvinceState())
+                fmt.Printf("Images in group: (%d)\n", len(cg.Records))
+                fmt.Printf("Destination: %s\n", path.Join(copyRootPath, folderName, path.Base(imageGr.Filepath)))
+
+                return
+            }
+        }
+    }
+
+    fmt.Printf("The image was loaded but not found in any group.\n")
+}
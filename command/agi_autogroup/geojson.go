@@ -0,0 +1,139 @@
+package main
+
+import (
+    "os"
+    "time"
+
+    "encoding/json"
+
+    "github.com/dsoprea/go-logging"
+
+    "github.com/dsoprea/go-geographic-autogroup-images"
+)
+
+// Values of the "layer" property of the GeoJSON features.
+const (
+    geoJsonLayerGroup = "group"
+    geoJsonLayerImage = "image"
+    geoJsonLayerTrack = "track"
+)
+
+// writeGroupInfoAsGeoJson writes a FeatureCollection with a point feature per
+// group (at its centroid), a point feature per located image, and a line of the
+// location track that each group was matched against. Each feature has a
+// "layer" property to tell them apart.
+func writeGroupInfoAsGeoJson(outputGroups []*outputGroup, filepath string) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    features := make([]map[string]interface{}, 0)
+
+    for i, og := range outputGroups {
+        groupId := og.GroupKey.KeyPhrase()
+
+        var geometry map[string]interface{}
+        if og.Located == true {
+            geometry = map[string]interface{}{
+                "type":        "Point",
+                "coordinates": []float64{og.CentroidLongitude, og.CentroidLatitude},
+            }
+        }
+
+        groupFeature := map[string]interface{}{
+            "type":     "Feature",
+            "id":       i,
+            "geometry": geometry,
+            "properties": map[string]interface{}{
+                "layer":        geoJsonLayerGroup,
+                "group":        groupId,
+                "name":         og.Name(),
+                "country":      og.CityRecord.Country,
+                "camera_model": og.CameraModel(),
+                "image_count":  len(og.Records),
+                "start":        og.First.Format(time.RFC3339),
+                "end":          og.Last.Format(time.RFC3339),
+                "folder":       og.FolderName,
+                "trip":         og.Trip,
+            },
+        }
+
+        if og.Located == true {
+            groupFeature["bbox"] = og.Bbox[:]
+        }
+
+        features = append(features, groupFeature)
+
+        for _, gr := range og.Records {
+            if gr.HasGeographic == false {
+                continue
+            }
+
+            method := og.LocationMethods[gr.Filepath]
+
+            imageFeature := map[string]interface{}{
+                "type": "Feature",
+                "geometry": map[string]interface{}{
+                    "type":        "Point",
+                    "coordinates": []float64{gr.Longitude, gr.Latitude},
+                },
+                "properties": map[string]interface{}{
+                    "layer":           geoJsonLayerImage,
+                    "group":           groupId,
+                    "filepath":        gr.Filepath,
+                    "timestamp":       gr.Timestamp.Format(time.RFC3339),
+                    "location_method": method,
+                    "matched":         method != geoautogroup.LocationMethodNative,
+                },
+            }
+
+            features = append(features, imageFeature)
+        }
+
+        track := og.Track
+        if len(track) < 2 {
+            continue
+        }
+
+        coordinates := make([][]float64, len(track))
+        for j, locationGr := range track {
+            coordinates[j] = []float64{locationGr.Longitude, locationGr.Latitude}
+        }
+
+        trackFeature := map[string]interface{}{
+            "type": "Feature",
+            "geometry": map[string]interface{}{
+                "type":        "LineString",
+                "coordinates": coordinates,
+            },
+            "properties": map[string]interface{}{
+                "layer": geoJsonLayerTrack,
+                "group": groupId,
+                "start": track[0].Timestamp.Format(time.RFC3339),
+                "end":   track[len(track)-1].Timestamp.Format(time.RFC3339),
+            },
+        }
+
+        features = append(features, trackFeature)
+    }
+
+    featureCollection := map[string]interface{}{
+        "type":     "FeatureCollection",
+        "features": features,
+    }
+
+    f, err := os.Create(filepath)
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    e := json.NewEncoder(f)
+    e.SetIndent("", "  ")
+
+    err = e.Encode(featureCollection)
+    log.PanicIf(err)
+
+    return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+    "os"
+    "time"
+
+    "encoding/xml"
+    "path/filepath"
+
+    "github.com/dsoprea/go-logging"
+)
+
+const (
+    gpxCreator = "go-geographic-autogroup-images"
+)
+
+type gpxPoint struct {
+    Latitude    float64 `xml:"lat,attr"`
+    Longitude   float64 `xml:"lon,attr"`
+    Time        string  `xml:"time"`
+    Name        string  `xml:"name,omitempty"`
+    Description string  `xml:"desc,omitempty"`
+    Type        string  `xml:"type,omitempty"`
+}
+
+type gpxTrackSegment struct {
+    Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxTrack struct {
+    Name        string            `xml:"name"`
+    Description string            `xml:"desc,omitempty"`
+    Segments    []gpxTrackSegment `xml:"trkseg"`
+}
+
+type gpxDocument struct {
+    XMLName   xml.Name   `xml:"gpx"`
+    Version   string     `xml:"version,attr"`
+    Creator   string     `xml:"creator,attr"`
+    Namespace string     `xml:"xmlns,attr"`
+    Waypoints []gpxPoint `xml:"wpt"`
+    Tracks    []gpxTrack `xml:"trk"`
+}
+
+// writeGroupInfoAsGpx writes a GPX 1.1 file with a waypoint for every located
+// image (named for the file and described by its group key) and a track for
+// every group with the location records that it was matched against. The
+// waypoint type is the location-method.
+func writeGroupInfoAsGpx(outputGroups []*outputGroup, gpxFilepath string) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    gd := gpxDocument{
+        Version:   "1.1",
+        Creator:   gpxCreator,
+        Namespace: "http://www.topografix.com/GPX/1/1",
+        Waypoints: make([]gpxPoint, 0),
+        Tracks:    make([]gpxTrack, 0),
+    }
+
+    for _, og := range outputGroups {
+        groupId := og.GroupKey.KeyPhrase()
+
+        for _, gr := range og.Records {
+            if gr.HasGeographic == false {
+                continue
+            }
+
+            waypoint := gpxPoint{
+                Latitude:    gr.Latitude,
+                Longitude:   gr.Longitude,
+                Time:        gr.Timestamp.UTC().Format(time.RFC3339),
+                Name:        filepath.Base(gr.Filepath),
+                Description: groupId,
+                Type:        og.LocationMethods[gr.Filepath],
+            }
+
+            gd.Waypoints = append(gd.Waypoints, waypoint)
+        }
+
+        track := og.Track
+        if len(track) < 2 {
+            continue
+        }
+
+        points := make([]gpxPoint, len(track))
+        for i, locationGr := range track {
+            points[i] = gpxPoint{
+                Latitude:  locationGr.Latitude,
+                Longitude: locationGr.Longitude,
+                Time:      locationGr.Timestamp.UTC().Format(time.RFC3339),
+            }
+        }
+
+        gt := gpxTrack{
+            Name:        og.Name(),
+            Description: groupId,
+            Segments: []gpxTrackSegment{
+                {Points: points},
+            },
+        }
+
+        gd.Tracks = append(gd.Tracks, gt)
+    }
+
+    f, err := os.Create(gpxFilepath)
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    _, err = f.Write([]byte(xml.Header))
+    log.PanicIf(err)
+
+    e := xml.NewEncoder(f)
+    e.Indent("", "  ")
+
+    err = e.Encode(gd)
+    log.PanicIf(err)
+
+    return nil
+}
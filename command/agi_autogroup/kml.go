@@ -0,0 +1,237 @@
+package main
+
+import (
+    "fmt"
+    "html"
+    "os"
+    "sort"
+    "strconv"
+    "time"
+
+    "encoding/xml"
+    "image/color"
+    "path/filepath"
+
+    "github.com/dsoprea/go-logging"
+    "github.com/twpayne/go-kml"
+
+    "github.com/dsoprea/go-geographic-autogroup-images"
+)
+
+const (
+    kmlThumbnailWidth = 240
+)
+
+var (
+    // kmlCameraColors are assigned to the camera-models in the order that they
+    // are first seen.
+    kmlCameraColors = []color.RGBA{
+        {R: 0xe6, G: 0x19, B: 0x4b, A: 0xff},
+        {R: 0x3c, G: 0xb4, B: 0x4b, A: 0xff},
+        {R: 0x43, G: 0x63, B: 0xd8, A: 0xff},
+        {R: 0xf5, G: 0x82, B: 0x31, A: 0xff},
+        {R: 0x91, G: 0x1e, B: 0xb4, A: 0xff},
+        {R: 0x46, G: 0xf0, B: 0xf0, A: 0xff},
+        {R: 0xf0, G: 0x32, B: 0xe6, A: 0xff},
+        {R: 0x9a, G: 0x63, B: 0x24, A: 0xff},
+    }
+)
+
+// kmlOptions are the parameters of the KML output.
+type kmlOptions struct {
+    // MinimumImageCount excludes groups with fewer images.
+    MinimumImageCount int
+
+    // CatalogPath is where the HTML catalog was written or empty if there
+    // isn't one.
+    CatalogPath string
+
+    // FileMappings are the copied images. If an image wasn't copied, the
+    // source image is used.
+    FileMappings map[string]imageFileMapping
+
+    // ThumbnailsPath is where the thumbnails are cached. This is shared with
+    // the catalog so that the thumbnails are only created once.
+    ThumbnailsPath string
+}
+
+// describeKmlGroup returns the HTML description of the group's placemark. The
+// cover is the thumbnail of the first image rather than the image itself so
+// that opening a balloon doesn't load a full-size picture.
+func describeKmlGroup(og *outputGroup, kmlFilepath string, ko kmlOptions, tc *geoautogroup.ThumbnailCache) string {
+    description := fmt.Sprintf("%d pictures<br />%s - %s<br />%s", len(og.Records), og.First.Local().Format(time.RFC1123), og.Last.Local().Format(time.RFC1123), html.EscapeString(og.CameraModel()))
+
+    if len(og.Records) > 0 {
+        coverFilepath, _ := getCatalogDisplayFilepath(tc, getCatalogImageFilepath(og.Records[0], ko.FileMappings))
+        description += fmt.Sprintf("<br /><img src=\"%s\" width=\"%d\" />", html.EscapeString(getRelativePath(filepath.Dir(kmlFilepath), coverFilepath)), kmlThumbnailWidth)
+    }
+
+    if ko.CatalogPath != "" {
+        pageFilepath := getCatalogGroupPageFilepath(ko.CatalogPath, og.GroupKey)
+        description += fmt.Sprintf("<br /><a href=\"%s\">Catalog</a>", html.EscapeString(getRelativePath(filepath.Dir(kmlFilepath), pageFilepath)))
+    }
+
+    return description
+}
+
+// writeGroupInfoAsKml writes a placemark for every group that has a location,
+// in folders by year and trip. Every group has a time-span so that it can be
+// played back and, if we have location data for its period, a track.
+// Placemarks are colored by camera-model.
+func writeGroupInfoAsKml(outputGroups []*outputGroup, kmlFilepath string, ko kmlOptions) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    // Without a catalog, the thumbnails are kept next to the KML.
+    thumbnailsPath := ko.ThumbnailsPath
+    if thumbnailsPath == "" {
+        thumbnailsPath = filepath.Join(filepath.Dir(kmlFilepath), catalogThumbnailsDirectoryName)
+    }
+
+    tc := geoautogroup.NewThumbnailCache(thumbnailsPath, catalogImageWidth)
+
+    styleIds := make(map[string]string)
+    styles := make([]kml.Element, 0)
+
+    // Bin the groups by year and trip while preserving their order.
+
+    years := make([]int, 0)
+    trips := make(map[int][]string)
+    groupsByTrip := make(map[string][]*outputGroup)
+
+    for _, og := range outputGroups {
+        if og.Located == false || len(og.Records) < ko.MinimumImageCount {
+            continue
+        }
+
+        cameraModel := og.CameraModel()
+        if _, found := styleIds[cameraModel]; found == false {
+            styleId := fmt.Sprintf("camera-%d", len(styleIds))
+            styleIds[cameraModel] = styleId
+
+            c := kmlCameraColors[len(styles)%len(kmlCameraColors)]
+
+            style := kml.SharedStyle(
+                styleId,
+                kml.IconStyle(
+                    kml.Color(c),
+                ),
+                kml.LineStyle(
+                    kml.Color(c),
+                    kml.Width(3),
+                ),
+            )
+
+            styles = append(styles, style)
+        }
+
+        year := og.First.Local().Year()
+        if _, found := trips[year]; found == false {
+            years = append(years, year)
+            trips[year] = make([]string, 0)
+        }
+
+        if _, found := groupsByTrip[og.Trip]; found == false {
+            trips[year] = append(trips[year], og.Trip)
+        }
+
+        groupsByTrip[og.Trip] = append(groupsByTrip[og.Trip], og)
+    }
+
+    sort.Ints(years)
+
+    yearFolders := make([]kml.Element, 0)
+    for _, year := range years {
+        tripFolders := make([]kml.Element, 0)
+        for _, trip := range trips[year] {
+            placemarks := []kml.Element{
+                kml.Name(trip),
+            }
+
+            for _, og := range groupsByTrip[trip] {
+                styleUrl := kml.StyleURL("#" + styleIds[og.CameraModel()])
+
+                timeSpan := kml.TimeSpan(
+                    kml.Begin(og.First),
+                    kml.End(og.Last),
+                )
+
+                coordinate := kml.Coordinate{
+                    Lon: og.CentroidLongitude,
+                    Lat: og.CentroidLatitude,
+                }
+
+                groupPoint := kml.Placemark(
+                    kml.Name(og.Name()),
+                    kml.Description(describeKmlGroup(og, kmlFilepath, ko, tc)),
+                    styleUrl,
+                    timeSpan,
+                    kml.Point(
+                        kml.Coordinates(coordinate),
+                    ),
+                )
+
+                placemarks = append(placemarks, groupPoint)
+
+                track := og.Track
+                if len(track) < 2 {
+                    continue
+                }
+
+                trackChildren := make([]kml.Element, 0, len(track)*2)
+                for _, locationGr := range track {
+                    trackChildren = append(trackChildren, kml.When(locationGr.Timestamp))
+                }
+
+                for _, locationGr := range track {
+                    coordinate := kml.Coordinate{
+                        Lon: locationGr.Longitude,
+                        Lat: locationGr.Latitude,
+                    }
+
+                    trackChildren = append(trackChildren, kml.GxCoord(coordinate))
+                }
+
+                trackPlacemark := kml.Placemark(
+                    kml.Name(fmt.Sprintf("%s (track)", og.Name())),
+                    styleUrl,
+                    kml.GxTrack(trackChildren...),
+                )
+
+                placemarks = append(placemarks, trackPlacemark)
+            }
+
+            tripFolders = append(tripFolders, kml.Folder(placemarks...))
+        }
+
+        yearFolder := kml.Folder(
+            append([]kml.Element{kml.Name(strconv.Itoa(year))}, tripFolders...)...,
+        )
+
+        yearFolders = append(yearFolders, yearFolder)
+    }
+
+    k := kml.GxKML(
+        kml.Document(
+            append(styles, yearFolders...)...,
+        ),
+    )
+
+    // Render the XML.
+
+    f, err := os.Create(kmlFilepath)
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    e := xml.NewEncoder(f)
+    e.Indent("", "  ")
+
+    err = e.Encode(k)
+    log.PanicIf(err)
+
+    return nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "time"
+
+    "encoding/csv"
+    "encoding/json"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-parse"
+
+    "github.com/dsoprea/go-geographic-autogroup-images"
+)
+
+// locationsDatabaseParameters are the parameters common to all of the
+// location-database subcommands.
+type locationsDatabaseParameters struct {
+    LocationsDatabaseFilepath string `long:"geographic-db-filepath" description:"File-path of locations database" required:"true"`
+}
+
+type locationsInfoParameters struct {
+    locationsDatabaseParameters
+}
+
+type locationsDumpParameters struct {
+    locationsDatabaseParameters
+
+    Format         string `long:"format" description:"Output format" choice:"csv" choice:"geojson" default:"csv"`
+    OutputFilepath string `long:"output-filepath" description:"File to write to. Defaults to STDOUT."`
+}
+
+type locationsVerifyParameters struct {
+    locationsDatabaseParameters
+}
+
+type locationsGapsParameters struct {
+    locationsDatabaseParameters
+
+    MinimumDurationRaw string `long:"minimum" description:"Only list gaps longer than this. Example: 8h" default:"8h"`
+}
+
+type locationsPruneParameters struct {
+    locationsDatabaseParameters
+
+    StartTimestampRaw string `long:"start" description:"Start of the range to remove (RFC3339)" required:"true"`
+    EndTimestampRaw   string `long:"end" description:"End of the range to remove (RFC3339)" required:"true"`
+}
+
+type locationsParameters struct {
+    Info   locationsInfoParameters   `command:"info" description:"Print the series in the location database"`
+    Dump   locationsDumpParameters   `command:"dump" description:"Write all location points as CSV or GeoJSON"`
+    Verify locationsVerifyParameters `command:"verify" description:"Check the checksums of all series without loading the data sources"`
+    Gaps   locationsGapsParameters   `command:"gaps" description:"List periods with no location points"`
+    Prune  locationsPruneParameters  `command:"prune" description:"Remove all location points within a time range"`
+}
+
+func handleLocationsInfo(arguments locationsInfoParameters) {
+    infos, err := geoautogroup.GetLocationDatabaseInfo(arguments.LocationsDatabaseFilepath)
+    log.PanicIf(err)
+
+    var headTime, tailTime time.Time
+    recordCount := 0
+
+    for i, ldsi := range infos {
+        fmt.Printf("Series (%d)\n", i)
+        fmt.Printf("  Source: [%s]\n", ldsi.SourceFilepath)
+        fmt.Printf("  Source SHA1: [%s]\n", ldsi.SourceSha1)
+        fmt.Printf("  Time range: [%s] - [%s]\n", ldsi.HeadTime.Format(time.RFC3339), ldsi.TailTime.Format(time.RFC3339))
+        fmt.Printf("  Records: (%d)\n", ldsi.RecordCount)
+
+        if ldsi.PrunedRanges > 0 {
+            fmt.Printf("  Pruned ranges: (%d)\n", ldsi.PrunedRanges)
+        }
+
+        if ldsi.DecodeError != "" {
+            fmt.Printf("  DECODE FAILED: %s\n", ldsi.DecodeError)
+        } else if ldsi.ChecksumOk == false {
+            fmt.Printf("  CHECKSUM FAILED\n")
+        }
+
+        fmt.Printf("\n")
+
+        if ldsi.RecordCount == 0 {
+            continue
+        }
+
+        if headTime.IsZero() == true || ldsi.HeadTime.Before(headTime) == true {
+            headTime = ldsi.HeadTime
+        }
+
+        if tailTime.IsZero() == true || ldsi.TailTime.After(tailTime) == true {
+            tailTime = ldsi.TailTime
+        }
+
+        recordCount += ldsi.RecordCount
+    }
+
+    fmt.Printf("Series: (%d)\n", len(infos))
+    fmt.Printf("Time range: [%s] - [%s]\n", headTime.Format(time.RFC3339), tailTime.Format(time.RFC3339))
+    fmt.Printf("Records: (%d)\n", recordCount)
+}
+
+func handleLocationsDump(arguments locationsDumpParameters) {
+    ts, err := geoautogroup.ReadLocationDatabase(arguments.LocationsDatabaseFilepath)
+    log.PanicIf(err)
+
+    var w io.Writer = os.Stdout
+    if arguments.OutputFilepath != "" {
+        f, err := os.Create(arguments.OutputFilepath)
+        log.PanicIf(err)
+
+        defer f.Close()
+
+        w = f
+    }
+
+    if arguments.Format == "geojson" {
+        features := make([]map[string]interface{}, 0)
+        for _, te := range ts {
+            for _, item := range te.Items {
+                gr := item.(*geoindex.GeographicRecord)
+
+                feature := map[string]interface{}{
+                    "type": "Feature",
+                    "geometry": map[string]interface{}{
+                        "type":        "Point",
+                        "coordinates": []float64{gr.Longitude, gr.Latitude},
+                    },
+                    "properties": map[string]interface{}{
+                        "timestamp": gr.Timestamp.Format(time.RFC3339),
+                        "source":    gr.Filepath,
+                    },
+                }
+
+                features = append(features, feature)
+            }
+        }
+
+        featureCollection := map[string]interface{}{
+            "type":     "FeatureCollection",
+            "features": features,
+        }
+
+        e := json.NewEncoder(w)
+        e.SetIndent("", "  ")
+
+        err := e.Encode(featureCollection)
+        log.PanicIf(err)
+
+        return
+    }
+
+    c := csv.NewWriter(w)
+
+    err = c.Write([]string{"timestamp", "latitude", "longitude", "source"})
+    log.PanicIf(err)
+
+    for _, te := range ts {
+        for _, item := range te.Items {
+            gr := item.(*geoindex.GeographicRecord)
+
+            row := []string{
+                gr.Timestamp.Format(time.RFC3339),
+                fmt.Sprintf("%.6f", gr.Latitude),
+                fmt.Sprintf("%.6f", gr.Longitude),
+                gr.Filepath,
+            }
+
+            err := c.Write(row)
+            log.PanicIf(err)
+        }
+    }
+
+    c.Flush()
+
+    err = c.Error()
+    log.PanicIf(err)
+}
+
+func handleLocationsVerify(arguments locationsVerifyParameters) {
+    infos, err := geoautogroup.GetLocationDatabaseInfo(arguments.LocationsDatabaseFilepath)
+    log.PanicIf(err)
+
+    failed := 0
+    for i, ldsi := range infos {
+        if ldsi.Ok() == true {
+            continue
+        }
+
+        if ldsi.DecodeError != "" {
+            fmt.Printf("Series (%d) could not be decoded: %s\n", i, ldsi.DecodeError)
+        } else {
+            fmt.Printf("Checksum failed: [%s]\n", ldsi.SourceFilepath)
+        }
+
+        failed++
+    }
+
+    if failed > 0 {
+        fmt.Printf("(%d) of (%d) series failed verification.\n", failed, len(infos))
+        os.Exit(3)
+    }
+
+    fmt.Printf("All (%d) series verified.\n", len(infos))
+}
+
+func handleLocationsGaps(arguments locationsGapsParameters) {
+    minimumDuration, _, err := timeparse.ParseDuration(arguments.MinimumDurationRaw)
+    log.PanicIf(err)
+
+    ts, err := geoautogroup.ReadLocationDatabase(arguments.LocationsDatabaseFilepath)
+    log.PanicIf(err)
+
+    gaps := geoautogroup.FindLocationGaps(ts, minimumDuration)
+    for _, lg := range gaps {
+        fmt.Printf("%s  %s  %s\n", lg.Before.Timestamp.Format(time.RFC3339), lg.After.Timestamp.Format(time.RFC3339), lg.Duration())
+    }
+
+    fmt.Printf("\n")
+    fmt.Printf("(%d) gaps longer than [%s].\n", len(gaps), minimumDuration)
+}
+
+func handleLocationsPrune(arguments locationsPruneParameters) {
+    start, err := time.Parse(time.RFC3339, arguments.StartTimestampRaw)
+    log.PanicIf(err)
+
+    end, err := time.Parse(time.RFC3339, arguments.EndTimestampRaw)
+    log.PanicIf(err)
+
+    removed, err := geoautogroup.PruneLocationDatabase(arguments.LocationsDatabaseFilepath, start, end)
+    log.PanicIf(err)
+
+    fmt.Printf("Removed (%d) records.\n", removed)
+}
+
+func handleLocations(subcommandName string, locationsArguments locationsParameters) {
+    defer func() {
+        if state := recover(); state != nil {
+            err := log.Wrap(state.(error))
+            log.PrintError(err)
+            os.Exit(-1)
+        }
+    }()
+
+    switch subcommandName {
+    case "info":
+        handleLocationsInfo(locationsArguments.Info)
+    case "dump":
+        handleLocationsDump(locationsArguments.Dump)
+    case "verify":
+        handleLocationsVerify(locationsArguments.Verify)
+    case "gaps":
+        handleLocationsGaps(locationsArguments.Gaps)
+    case "prune":
+        handleLocationsPrune(locationsArguments.Prune)
+    default:
+        fmt.Printf("Subcommand not handled: [%s]\n", subcommandName)
+        os.Exit(2)
+    }
+}
@@ -8,17 +8,16 @@ import (
     "time"
 
     "encoding/json"
-    "encoding/xml"
     "io/ioutil"
+    "path/filepath"
     "text/template"
 
     "github.com/jessevdk/go-flags"
-    "github.com/twpayne/go-kml"
 
-    "github.com/dsoprea/go-geographic-attractor"
     "github.com/dsoprea/go-geographic-attractor/index"
     "github.com/dsoprea/go-geographic-index"
     "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-index"
     "github.com/dsoprea/go-time-parse"
 
     "github.com/dsoprea/go-geographic-autogroup-images"
@@ -35,6 +34,14 @@ const (
 
     catalogImageWidth  = 600
     catalogImageHeight = 0
+
+    // catalogCoverWidth is the width of the group covers on the catalog index.
+    catalogCoverWidth = 240
+
+    // catalogThumbnailsDirectoryName is the directory that the catalog
+    // thumbnails are cached in. Under the copy-path, this is shared by the
+    // catalogs of every run.
+    catalogThumbnailsDirectoryName = "thumbnails"
 )
 
 type tallyItem struct {
@@ -75,11 +82,39 @@ type indexParameters struct {
     DataPaths                 []string `long:"data-path" description:"Path to scan for geographic data (GPX files and image files; can be provided more than once)"`
     LocationsDatabaseFilepath string   `long:"geographic-db-filepath" description:"File-path of locations database. Will be created if does not exist (requires --data-path to be provided). Will be updated if --data-path was given and represents different data."`
     ImagePaths                []string `long:"image-path" description:"Path to scan for images to group (can be provided more than once)" required:"true"`
-    ListfileFilepaths         []string `long:"listfile-location-data-filepath" description:"Zero or more files to read a list of location IDs and timestamps to directly insert to the location index"`
+    ListfileFilepaths         []string `long:"listfile-location-data-filepath" description:"Zero or more list-files of locations and timestamps to insert into the location index. Rows take priority over location data for the same period."`
+    ImageLoadConcurrency      int      `long:"image-load-concurrency" description:"Number of images to parse in parallel. Defaults to the number of CPUs." default:"0"`
+    ImageCacheFilepath        string   `long:"image-cache-filepath" description:"File-path of the image metadata cache. Will be created if does not exist. Images that have not changed since the last run will not be parsed again."`
+    OverridesFilepath         string   `long:"overrides-filepath" description:"YAML file assigning locations, timestamps, or group names to images matching paths or globs"`
+    UseDirectoryOverrides     bool     `long:"directory-overrides" description:"Also read per-directory override files (named '.autogroup-overrides.yaml') from the image directories"`
 }
 
 type sourceCatalogParameters struct {
-    NoEmbedImages bool `long:"no-embed-images" description:"By default thumbnails are embedded directly into the catalog. Use the source image directly, instead."`
+    NoEmbedImages bool `long:"no-embed-images" description:"By default the catalog shows thumbnails (cached by content under 'catalog/thumbnails' in the copy-path) that link to the images. Show the images directly, instead."`
+}
+
+// outputParameters are the parameters of everything that is written from the
+// finished groups. These are shared by the "group" and "render" subcommands.
+type outputParameters struct {
+    KmlFilepath               string `long:"kml-filepath" description:"Write KML to the given file. Enabled by default and named 'groups.kml' in the --copy-into-path argument if provided. Can be disabled using 'none'."`
+    KmlMinimumGroupImageCount int    `long:"kml-minimum" description:"Exclude groups with less than N images from the KML" default:"20"`
+    TripGapRaw                string `long:"trip-gap" description:"Consecutive groups no further apart than this are considered to be part of the same trip" default:"24h"`
+    GeoJsonFilepath           string `long:"geojson-filepath" description:"Write GeoJSON with a layer of groups, a layer of images, and a layer of location tracks to the given file. Enabled by default and named 'groups.geojson' in the --copy-into-path argument if provided. Can be disabled using 'none'."`
+    GpxFilepath               string `long:"gpx-filepath" description:"Write GPX with a waypoint for every located image and a track of the location data that every group was matched against to the given file. Enabled by default and named 'groups.gpx' in the --copy-into-path argument if provided. Can be disabled using 'none'."`
+    AssignmentsFilepath       string `long:"assignments-filepath" description:"Write a flat table with one row per grouped image (source and destination, timestamps, location and how it was obtained, city, group, trip, and merge notes) to the given file. Enabled by default and named 'assignments.csv' (or 'assignments.json') in the --copy-into-path argument if provided. Can be disabled using 'none'."`
+    AssignmentsFormat         string `long:"assignments-format" description:"Format of the assignments table. 'columnar-json' is an object of column arrays, for large libraries." choice:"csv" choice:"columnar-json" default:"csv"`
+    CopyPath                  string `long:"copy-into-path" description:"Copy grouped images into this path"`
+    CatalogPath               string `long:"catalog-path" description:"Write the HTML catalog into this path. Images that aren't copied are referenced in place, so this can be used without --copy-into-path to review the groups before copying. By default, the catalog is written into the --copy-into-path argument if provided."`
+    CatalogMapTilesPath       string `long:"catalog-map-tiles-path" description:"Directory of map tiles laid out as '<z>/<x>/<y>.png' to draw under the catalog map. The tiles are referenced locally so the map works without a network connection."`
+    CatalogMapOutlineFilepath string `long:"catalog-map-outline-filepath" description:"GeoJSON file of country or coastline outlines (e.g. Natural Earth's) to embed into the catalog map when there are no tiles. By default, a coarse outline of the continents is embedded."`
+    ImageOutputPathTemplate   string `long:"output-template" description:"Group output path name template within the output path. Can use Go template tokens." default:"{{.year}}-{{.month_number}}-{{.day_number}} {{.location}}{{.path_sep}}{{.camera_model}}/{{.hour}}.{{.minute}}"`
+    NoPrintProgressOutput     bool   `long:"no-dots" description:"Don't print dot progress output if copying"`
+    NoHashChecksOnExisting    bool   `long:"no-hash-checks" description:"If the file already exists in copy-path skip without calculating hash"`
+    WriteXmpSidecars          bool   `long:"write-xmp-sidecars" description:"Write an XMP sidecar with the location, nearest city, group, and trip of every grouped image. Written next to the copies if copying or next to the sources (never overwriting an existing sidecar) with --modify-sources."`
+    WriteExifGps              bool   `long:"write-exif-gps" description:"Write matched locations into the EXIF of JPEGs that didn't have their own. Written into the copies if copying or into the sources with --modify-sources. Copies that only differ by their EXIF are still recognized when copying into the same path again."`
+    ModifySources             bool   `long:"modify-sources" description:"Allow --write-xmp-sidecars and --write-exif-gps to write next to or into the source images when not copying"`
+
+    sourceCatalogParameters
 }
 
 type groupParameters struct {
@@ -87,31 +122,99 @@ type groupParameters struct {
     indexParameters
 
     LocationsAreSparse         bool     `long:"sparse-data" description:"Location data is sparse. Sparse datasets will not record points if there has been no movement."`
-    KmlFilepath                string   `long:"kml-filepath" description:"Write KML to the given file. Enabled by default and named 'groups.kml' in the --copy-into-path argument if provided. Can be disabled using 'none'."`
-    KmlMinimumGroupImageCount  int      `long:"kml-minimum" description:"Exclude groups with less than N images from the KML" default:"20"`
     JsonFilepath               string   `long:"json-filepath" description:"Write JSON to the given file. Enabled by default and named 'groups.json' in the --copy-into-path argument if provided. Can be disabled using 'none'."`
     UnassignedFilepath         string   `long:"unassigned-filepath" description:"File to write unassigned files to. Enabled by default and named 'unassigned.txt' in --copy-into-path argument if provided."`
+    UnassignedReportFilepath   string   `long:"unassigned-report-filepath" description:"Write the reason and diagnostics (nearest location records, nearest city, matcher) for every unassigned image to the given file."`
+    UnassignedReportFormat     string   `long:"unassigned-report-format" description:"Format of the unassigned report" choice:"json" choice:"csv" default:"json"`
+    CoverageReportFilepath     string   `long:"coverage-report-filepath" description:"Write a report of every period where there are images but the location data is missing or too old."`
     PrintStats                 bool     `long:"stats" description:"Print statistics"`
-    CopyPath                   string   `long:"copy-into-path" description:"Copy grouped images into this path"`
-    ImageOutputPathTemplate    string   `long:"output-template" description:"Group output path name template within the output path. Can use Go template tokens." default:"{{.year}}-{{.month_number}}-{{.day_number}} {{.location}}{{.path_sep}}{{.camera_model}}/{{.hour}}.{{.minute}}"`
-    NoPrintProgressOutput      bool     `long:"no-dots" description:"Don't print dot progress output if copying"`
-    NoHashChecksOnExisting     bool     `long:"no-hash-checks" description:"If the file already exists in copy-path skip without calculating hash"`
     ImageTimestampSkewRaw      string   `long:"image-timestamp-skew" description:"A duration to be combined with the given polarity and added to the timestamps of the images to shift them to the local timezone. By default, all images are interpreted as UTC (a requirement of EXIF). Example: 5h"`
     ImageTimestampSkewPolarity bool     `long:"image-timestamp-skew-polarity" description:"If skew is being used. false if it should be negative and true if positive"`
     TraceImages                []string `long:"trace-image" description:"Zero or more absolute file-paths of images to record additional processing comments for"`
+    TraceImagePatterns         []string `long:"trace-image-pattern" description:"Zero or more glob patterns of images to record additional processing comments for. Patterns without a path separator are matched against the filename."`
+    TraceAllImages             bool     `long:"trace-all" description:"Record additional processing comments for every image"`
+    TraceFilepath              string   `long:"trace-filepath" description:"Write the processing comments of the traced images to the given file as JSON Lines rather than printing them"`
     CameraModels               []string `long:"camera-model" description:"Zero or more camera-models to specifically include to the exclusion of all others"`
+    AdjacentImageWindowRaw     string   `long:"adjacent-image-window" description:"Images that can't be matched to location data borrow the location of a located image (from any camera) taken within this duration. Example: 5m"`
+    GroupUnlocated             bool     `long:"group-unlocated" description:"Group images that can't be matched to a location by time and camera-model alone rather than leaving them unassigned"`
+    UnlocatedLabel             string   `long:"unlocated-label" description:"Location name to use for images grouped with --group-unlocated" default:"Unknown location"`
 
-    sourceCatalogParameters
+    outputParameters
 }
 
 type subcommands struct {
-    Group groupParameters `command:"group" description:"Grouping operations"`
+    Group     groupParameters     `command:"group" description:"Grouping operations"`
+    Locations locationsParameters `command:"locations" description:"Location database inspection and maintenance"`
+    Patch     patchParameters     `command:"patch" description:"Write a list-file for the images that could not be matched with locations"`
+    Explain   explainParameters   `command:"explain" description:"Run the grouping and print every decision that was made for one image"`
+    Render    renderParameters    `command:"render" description:"Write the copies, catalog, and exports for the groups in a saved groups.json without grouping again"`
 }
 
 var (
     rootArguments = new(subcommands)
 )
 
+// getLocationTs loads the location index and applies any list-files.
+func getLocationTs(ci *geoattractorindex.CityIndex, attractorArguments attractorParameters, indexArguments indexParameters, printStats, beVerbose bool) (locationTs timeindex.TimeSlice) {
+    locationIndex, _, _, err := geoautogroup.GetLocationTimeIndex(indexArguments.DataPaths, indexArguments.LocationsDatabaseFilepath, beVerbose)
+    log.PanicIf(err)
+
+    if printStats == true {
+        fmt.Printf("(%d) records loaded in location index.\n", len(locationIndex.Series()))
+    }
+
+    if indexArguments.ListfileFilepaths != nil {
+        var cnr *geoautogroup.CityNameResolver
+        if attractorArguments.CitiesFilepath != "" {
+            cnr = geoautogroup.NewCityNameResolver(attractorArguments.CitiesFilepath)
+        }
+
+        entries := make([]geoautogroup.LocationListfileEntry, 0)
+        for _, filepath := range indexArguments.ListfileFilepaths {
+            f, err := os.Open(filepath)
+            log.PanicIf(err)
+
+            defer f.Close()
+
+            listfileEntries, err := geoautogroup.ReadLocationListfile(ci, cnr, filepath, f)
+            log.PanicIf(err)
+
+            if printStats == true {
+                fmt.Printf("Read (%d) rows from list-file [%s].\n", len(listfileEntries), filepath)
+            }
+
+            entries = append(entries, listfileEntries...)
+        }
+
+        var suppressedCount int
+        locationIndex, suppressedCount, err = geoautogroup.ApplyLocationListfileEntries(locationIndex.Series(), entries)
+        log.PanicIf(err)
+
+        if printStats == true {
+            fmt.Printf("(%d) location records were superseded by list-file rows.\n", suppressedCount)
+        }
+    }
+
+    return locationIndex.Series()
+}
+
+// getImageTimestampSkew parses the skew arguments. The skew is zero if not
+// given.
+func getImageTimestampSkew(imageTimestampSkewRaw string, imageTimestampSkewPolarity bool) (imageTimestampSkew time.Duration) {
+    if imageTimestampSkewRaw == "" {
+        return 0
+    }
+
+    imageTimestampSkew, _, err := timeparse.ParseDuration(imageTimestampSkewRaw)
+    log.PanicIf(err)
+
+    if imageTimestampSkewPolarity == false {
+        imageTimestampSkew *= -1
+    }
+
+    return imageTimestampSkew
+}
+
 func getFindGroups(groupArguments groupParameters) (fg *geoautogroup.FindGroups, ci *geoattractorindex.CityIndex) {
     defer func() {
         if state := recover(); state != nil {
@@ -124,6 +227,12 @@ func getFindGroups(groupArguments groupParameters) (fg *geoautogroup.FindGroups,
         }
     }()
 
+    // Check this before we spend time loading anything.
+    if groupArguments.GroupUnlocated == true {
+        err := geoautogroup.ValidateGroupName(groupArguments.UnlocatedLabel)
+        log.PanicIf(err)
+    }
+
     attractorParameters := groupArguments.attractorParameters
 
     beVerbose := groupArguments.NoPrintProgressOutput == false
@@ -139,50 +248,46 @@ func getFindGroups(groupArguments groupParameters) (fg *geoautogroup.FindGroups,
 
     log.PanicIf(err)
 
-    locationIndex, _, _, err := geoautogroup.GetLocationTimeIndex(groupArguments.indexParameters.DataPaths, groupArguments.indexParameters.LocationsDatabaseFilepath, beVerbose)
-    log.PanicIf(err)
+    locationTs := getLocationTs(ci, attractorParameters, groupArguments.indexParameters, groupArguments.PrintStats, beVerbose)
 
-    locationTs := locationIndex.Series()
+    imageTimestampSkew := getImageTimestampSkew(groupArguments.ImageTimestampSkewRaw, groupArguments.ImageTimestampSkewPolarity)
 
-    if groupArguments.PrintStats == true {
-        fmt.Printf("(%d) records loaded in location index.\n", len(locationTs))
+    var cameraModels []string
+    if len(groupArguments.CameraModels) > 0 {
+        cameraModels = groupArguments.CameraModels
     }
 
-    if groupArguments.ListfileFilepaths != nil {
-        for _, filepath := range groupArguments.ListfileFilepaths {
-            f, err := os.Open(filepath)
-            log.PanicIf(err)
-
-            defer f.Close()
-
-            listfileRecordCount, err := geoautogroup.LoadLocationListFile(ci, filepath, f, locationIndex)
-            log.PanicIf(err)
-
-            if groupArguments.PrintStats == true {
-                fmt.Printf("Read (%d) records from list-file [%s].\n", listfileRecordCount, filepath)
-            }
-        }
+    var imc *geoautogroup.ImageMetadataCache
+    if groupArguments.indexParameters.ImageCacheFilepath != "" {
+        imc, err = geoautogroup.OpenImageMetadataCache(groupArguments.indexParameters.ImageCacheFilepath, imageTimestampSkew)
+        log.PanicIf(err)
     }
 
-    var imageTimestampSkew time.Duration
-    if groupArguments.ImageTimestampSkewRaw != "" {
-        var err error
-        imageTimestampSkew, _, err = timeparse.ParseDuration(groupArguments.ImageTimestampSkewRaw)
+    var it *geoautogroup.ImageTracer
+    if len(groupArguments.TraceImages) > 0 || len(groupArguments.TraceImagePatterns) > 0 || groupArguments.TraceAllImages == true {
+        it, err = geoautogroup.NewImageTracer(groupArguments.TraceImages, groupArguments.TraceImagePatterns, groupArguments.TraceAllImages)
         log.PanicIf(err)
-
-        if groupArguments.ImageTimestampSkewPolarity == false {
-            imageTimestampSkew *= -1
-        }
     }
 
-    var cameraModels []string
-    if len(groupArguments.CameraModels) > 0 {
-        cameraModels = groupArguments.CameraModels
+    var ios *geoautogroup.ImageOverrides
+    if groupArguments.indexParameters.OverridesFilepath != "" || groupArguments.indexParameters.UseDirectoryOverrides == true {
+        ios, err = geoautogroup.NewImageOverrides(groupArguments.indexParameters.OverridesFilepath, groupArguments.indexParameters.UseDirectoryOverrides)
+        log.PanicIf(err)
     }
 
-    imageIndex, err := geoautogroup.GetImageTimeIndex(groupArguments.indexParameters.ImagePaths, imageTimestampSkew, cameraModels, beVerbose)
+    imageIndex, err := geoautogroup.GetImageTimeIndex(groupArguments.indexParameters.ImagePaths, imageTimestampSkew, cameraModels, groupArguments.indexParameters.ImageLoadConcurrency, imc, ios, it, beVerbose)
     log.PanicIf(err)
 
+    if imc != nil {
+        err := imc.Save()
+        log.PanicIf(err)
+
+        if groupArguments.PrintStats == true {
+            hits, misses := imc.Stats()
+            fmt.Printf("Image cache: (%d) hits, (%d) misses.\n", hits, misses)
+        }
+    }
+
     imageTs := imageIndex.Series()
 
     if groupArguments.PrintStats == true {
@@ -191,6 +296,23 @@ func getFindGroups(groupArguments groupParameters) (fg *geoautogroup.FindGroups,
 
     fg = geoautogroup.NewFindGroups(locationTs, imageTs, ci)
 
+    if ios != nil {
+        fg.SetImageOverrides(ios)
+    }
+
+    fg.SetImageTracer(it)
+
+    if groupArguments.AdjacentImageWindowRaw != "" {
+        adjacentImageWindow, _, err := timeparse.ParseDuration(groupArguments.AdjacentImageWindowRaw)
+        log.PanicIf(err)
+
+        fg.SetAdjacentImageWindow(adjacentImageWindow)
+    }
+
+    if groupArguments.GroupUnlocated == true {
+        fg.SetUnknownLocationFallback(groupArguments.UnlocatedLabel)
+    }
+
     if groupArguments.LocationsAreSparse == true {
         fg.SetLocationMatchStrategy(geoautogroup.LocationMatchStrategySparseData)
     }
@@ -199,8 +321,28 @@ func getFindGroups(groupArguments groupParameters) (fg *geoautogroup.FindGroups,
 }
 
 type imageFileMapping struct {
-    OutputFilepath              string
-    RelativeFilepathFromCatalog string
+    OutputFilepath string
+}
+
+// getRelativePath returns the path to the given file relative to the given
+// directory, with forward-slashes so that it can be used in a URL, or an
+// absolute path if it can't be made relative.
+func getRelativePath(fromPath, targetFilepath string) string {
+    absFromPath, err := filepath.Abs(fromPath)
+    if err != nil {
+        return targetFilepath
+    }
+
+    absTargetFilepath, err := filepath.Abs(targetFilepath)
+    if err != nil {
+        return targetFilepath
+    }
+
+    if relPath, err := filepath.Rel(absFromPath, absTargetFilepath); err == nil {
+        return filepath.ToSlash(relPath)
+    }
+
+    return absTargetFilepath
 }
 
 func handleGroup(groupArguments groupParameters) {
@@ -214,10 +356,6 @@ func handleGroup(groupArguments groupParameters) {
 
     sessionTimestampPhrase := geoautogroup.GetCondensedDatetime(time.Now())
 
-    if len(groupArguments.TraceImages) > 0 {
-        geoautogroup.InitImageTrace(groupArguments.TraceImages)
-    }
-
     fg, ci := getFindGroups(groupArguments)
 
     defer ci.Close()
@@ -225,6 +363,7 @@ func handleGroup(groupArguments groupParameters) {
     // Run the grouping operation.
 
     gr := geoautogroup.NewGroupsReducer(fg)
+    gr.SetImageTracer(fg.ImageTracer())
 
     // Merge smaller cities with smaller datasets into the groups for larger
     // cities.
@@ -243,75 +382,30 @@ func handleGroup(groupArguments groupParameters) {
         }
     }
 
-    // Copy images.
-
-    if groupArguments.CopyPath != "" {
-        fmt.Printf("Copying images:\n")
-        fmt.Printf("\n")
-    }
-
-    kmlTallies := make(map[geoattractor.CityRecord][2]int)
-    collected := make([]map[string]interface{}, 0)
-
     imageOutputPathTemplate := template.Must(template.New("group path template").Parse(groupArguments.ImageOutputPathTemplate))
 
-    printProgressOutput := (groupArguments.NoPrintProgressOutput == false)
-
-    // Depending on the folder-template, the groups may, and probably will, be
-    // merged-together to some degree on disk (our groups might differ in
-    // minutes but may be stored on disk by date). So, keep track of them based
-    // on the on-disk folder rather than by their in-memory names (found via
-    // `String()`).
-    binnedImages := make(map[string][]*geoindex.GeographicRecord)
-
-    fileMappings := make(map[string]imageFileMapping)
-    i := 0
+    outputGroups := make([]*outputGroup, 0)
     for _, groups := range collectedGroups {
         for _, cg := range groups {
-            finishedGroupKey := cg.GroupKey
-            finishedGroup := cg.Records
+            cityRecord := fg.NearestCityIndex()[cg.GroupKey.NearestCityKey]
 
-            if groupArguments.CopyPath != "" {
-                err := copyFiles(groupArguments, fg, finishedGroupKey, finishedGroup, groupArguments.CopyPath, imageOutputPathTemplate, printProgressOutput, binnedImages, fileMappings)
-                log.PanicIf(err)
-            }
-
-            if collected != nil {
-                item := map[string]interface{}{
-                    "group_key": finishedGroupKey,
-                    "records":   finishedGroup,
-                }
-
-                collected = append(collected, item)
-            }
-
-            nearestCityIndex := fg.NearestCityIndex()
-            cityRecord := nearestCityIndex[finishedGroupKey.NearestCityKey]
+            folderName, _, err := getGroupFolderName(cityRecord, cg.GroupKey, len(cg.Records), imageOutputPathTemplate)
+            log.PanicIf(err)
 
-            if existing, found := kmlTallies[cityRecord]; found == true {
-                kmlTallies[cityRecord] = [2]int{
-                    existing[0] + 1,
-                    existing[1] + len(finishedGroup),
-                }
-            } else {
-                kmlTallies[cityRecord] = [2]int{
-                    1,
-                    len(finishedGroup),
-                }
-            }
+            og, err := newOutputGroup(fg, gr, cg.GroupKey, cg.Records, folderName)
+            log.PanicIf(err)
 
-            i++
+            outputGroups = append(outputGroups, og)
         }
     }
 
     if groupArguments.PrintStats == true {
-        fmt.Printf("\n")
         fmt.Printf("Attractor index stats: %s\n", ci.Stats())
+        fmt.Printf("\n")
     }
 
     urbanCenters := fg.UrbanCentersEncountered()
     if len(urbanCenters) > 0 {
-        fmt.Printf("\n")
         fmt.Printf("Urban Areas Visited\n")
         fmt.Printf("===================\n")
 
@@ -328,55 +422,12 @@ func handleGroup(groupArguments groupParameters) {
             cr := urbanCenters[id]
             fmt.Printf("%8s  %s  (%.6f,%.6f)\n", cr.Id, cr.CityAndProvinceState(), cr.Latitude, cr.Longitude)
         }
-    }
-
-    if collected != nil {
-        // Automatically write a destination catalog if we're doing a copy.
-        if groupArguments.CopyPath != "" {
-            destCatalogPath := path.Join(groupArguments.CopyPath, "catalog", sessionTimestampPhrase)
-
-            fmt.Printf("\n")
-            fmt.Printf("Writing catalog to: %s\n", destCatalogPath)
 
-            err := writeDestHtmlCatalog(groupArguments, fg, collected, destCatalogPath, groupArguments.NoEmbedImages, fileMappings)
-            log.PanicIf(err)
-        }
+        fmt.Printf("\n")
     }
 
-    if len(binnedImages) > 0 {
-        err := writeCopyPathInfo(groupArguments, sessionTimestampPhrase, groupArguments.CopyPath, binnedImages)
-        log.PanicIf(err)
-
-        tallies := make(Tallies, 0)
-        for folderName, entries := range binnedImages {
-            count := len(entries)
-            if count < largestGroupMinimumSize {
-                continue
-            }
-
-            ti := tallyItem{
-                name:  folderName,
-                count: count,
-            }
-
-            tallies = append(tallies, ti)
-        }
-
-        if len(tallies) > 0 {
-            // This sorts in reverse.
-            sort.Sort(tallies)
-
-            fmt.Printf("\n")
-            fmt.Printf("Largest Groups\n")
-            fmt.Printf("==============\n")
-
-            for _, ti := range tallies {
-                fmt.Printf("%s: (%d)\n", ti.name, ti.count)
-            }
-
-            fmt.Printf("\n")
-        }
-    }
+    err := renderOutputs(groupArguments.outputParameters, outputGroups, sessionTimestampPhrase)
+    log.PanicIf(err)
 
     // TODO(dustin): !! Make sure that files that returned nil,nil from the image processor in go-geographic-index is logged as unassigned. Otherwise, we'll have no chance of debugging image issues.
 
@@ -390,54 +441,21 @@ func handleGroup(groupArguments groupParameters) {
     }
 
     if jsonFilepath != "none" {
-        // Write all of the final data as a JSON structure.
-
-        encodedGroups := make([]map[string]interface{}, len(collected))
-        for i, groupInfo := range collected {
-            groupKey := groupInfo["group_key"].(geoautogroup.GroupKey)
-            originalRecords := groupInfo["records"].([]*geoindex.GeographicRecord)
-
-            updatedRecords := make([]map[string]interface{}, len(originalRecords))
-            locationSourceRecords := make(map[string]map[string]interface{})
-            for i, gr := range originalRecords {
-                encoded := gr.Encode()
-
-                // Relocate relationships to reduce duplication and clutter.
-
-                encodedRelationships := encoded["relationships"].(map[string][]map[string]interface{})
-
-                if len(encodedRelationships) == 0 {
-                    delete(encodedRelationships, "relationships")
-                } else {
-                    updatedRelationships := make(map[string][]string)
-                    for type_, encodedGrList := range encodedRelationships {
-                        filepaths := make([]string, len(encodedGrList))
-                        for i, encodedGr := range encodedGrList {
-                            filepath := encodedGr["filepath"].(string)
-                            filepaths[i] = filepath
-                            locationSourceRecords[filepath] = encodedGr
-                        }
-
-                        updatedRelationships[type_] = filepaths
-                    }
-
-                    encoded["relationships"] = updatedRelationships
-                }
+        err := writeGroupInfoAsJson(fg, gr, outputGroups, jsonFilepath)
+        log.PanicIf(err)
+    }
 
-                updatedRecords[i] = encoded
-            }
+    if groupArguments.CoverageReportFilepath != "" {
+        gaps, err := fg.LocationCoverageGaps()
+        log.PanicIf(err)
 
-            item := map[string]interface{}{
-                "group_key":        groupKey,
-                "records":          updatedRecords,
-                "location_sources": locationSourceRecords,
-            }
+        err = writeCoverageReport(gaps, groupArguments.CoverageReportFilepath)
+        log.PanicIf(err)
 
-            encodedGroups[i] = item
+        if len(gaps) > 0 {
+            fmt.Printf("(%d) periods are missing location data. See: %s\n", len(gaps), groupArguments.CoverageReportFilepath)
+            fmt.Printf("\n")
         }
-
-        err := writeGroupInfoAsJson(fg, encodedGroups, jsonFilepath)
-        log.PanicIf(err)
     }
 
     unassignedRecords := fg.UnassignedRecords()
@@ -463,11 +481,16 @@ func handleGroup(groupArguments groupParameters) {
             defer f.Close()
 
             for _, ur := range unassignedRecords {
-                fmt.Fprintf(f, "%s\t%s\n", ur.Geographic.Filepath, ur.Reason)
+                fmt.Fprintf(f, "%s\t%s\n", ur.Geographic.Filepath, ur.Reason.Description())
             }
         }
 
-        groupedUnassigned := make(map[string]int)
+        if groupArguments.UnassignedReportFilepath != "" {
+            err := writeUnassignedReport(unassignedRecords, groupArguments.UnassignedReportFilepath, groupArguments.UnassignedReportFormat)
+            log.PanicIf(err)
+        }
+
+        groupedUnassigned := make(map[geoautogroup.SkipReason]int)
 
         for _, ur := range unassignedRecords {
             if existing, found := groupedUnassigned[ur.Reason]; found == true {
@@ -482,73 +505,69 @@ func handleGroup(groupArguments groupParameters) {
         fmt.Printf("\n")
 
         for reason, count := range groupedUnassigned {
-            fmt.Printf("%s: (%d)\n", reason, count)
+            fmt.Printf("%s (%s): (%d)\n", reason.Description(), reason, count)
         }
 
         fmt.Printf("\n")
     }
 
-    kmlFilepath := groupArguments.KmlFilepath
-    if kmlFilepath == "" {
-        if groupArguments.CopyPath != "" {
-            kmlFilepath = path.Join(groupArguments.CopyPath, "groups.kml")
-        } else {
-            kmlFilepath = "none"
-        }
-    }
-
-    if kmlFilepath != "none" {
-        err := writeGroupInfoAsKml(kmlTallies, kmlFilepath)
-        log.PanicIf(err)
-    }
+    if it := fg.ImageTracer(); it != nil {
+        if groupArguments.TraceFilepath != "" {
+            f, err := os.Create(groupArguments.TraceFilepath)
+            log.PanicIf(err)
 
-    if geoautogroup.IsImageTraceIndexInited() {
-        fmt.Printf("Image Traces\n")
-        fmt.Printf("============\n")
-        fmt.Printf("\n")
+            defer f.Close()
 
-        for filepath, comments := range geoautogroup.ImageTraceIndex() {
-            fmt.Printf("%s\n", filepath)
+            err = it.WriteJsonLines(f)
+            log.PanicIf(err)
+        } else {
+            fmt.Printf("Image Traces\n")
+            fmt.Printf("============\n")
             fmt.Printf("\n")
 
-            for _, comment := range comments {
-                fmt.Printf("- %s\n", comment)
-            }
+            for _, filepath := range it.Filepaths() {
+                fmt.Printf("%s\n", filepath)
+                fmt.Printf("\n")
 
-            fmt.Printf("\n")
+                for _, ite := range it.Events(filepath) {
+                    fmt.Printf("- %s\n", ite)
+                }
+
+                fmt.Printf("\n")
+            }
         }
     }
 }
 
-func writeGroupInfoAsJson(fg *geoautogroup.FindGroups, collected []map[string]interface{}, filepath string) (err error) {
+// writeGroupInfoAsJson writes the groups as a `GroupsDocument`.
+func writeGroupInfoAsJson(fg *geoautogroup.FindGroups, gr *geoautogroup.GroupsReducer, outputGroups []*outputGroup, filepath string) (err error) {
     defer func() {
         if state := recover(); state != nil {
             err = log.Wrap(state.(error))
         }
     }()
 
-    f, err := os.Create(filepath)
-    log.PanicIf(err)
-
-    defer f.Close()
+    gd := geoautogroup.NewGroupsDocument(fg)
 
-    nearestCityIndex := fg.NearestCityIndex()
+    for _, og := range outputGroups {
+        gdg, err := fg.NewGroupsDocumentGroup(gr, og.GroupKey, og.Records, og.Trip)
+        log.PanicIf(err)
 
-    content := map[string]interface{}{
-        "groups":     collected,
-        "city_index": nearestCityIndex,
+        gd.Groups = append(gd.Groups, gdg)
     }
 
-    e := json.NewEncoder(f)
-    e.SetIndent("", "  ")
+    f, err := os.Create(filepath)
+    log.PanicIf(err)
 
-    err = e.Encode(content)
+    defer f.Close()
+
+    err = gd.Write(f)
     log.PanicIf(err)
 
     return nil
 }
 
-func writeCopyPathInfo(groupArguments groupParameters, sessionTimestampPhrase, destRootPath string, binnedImages map[string][]*geoindex.GeographicRecord) (err error) {
+func writeCopyPathInfo(outputArguments outputParameters, sessionTimestampPhrase, destRootPath string, binnedImages map[string][]*geoindex.GeographicRecord) (err error) {
     defer func() {
         if state := recover(); state != nil {
             err = log.Wrap(state.(error))
@@ -557,7 +576,7 @@ func writeCopyPathInfo(groupArguments groupParameters, sessionTimestampPhrase, d
 
     destPathTallies := make(map[string]int)
     for folderName, _ := range binnedImages {
-        destPath := path.Join(groupArguments.CopyPath, folderName)
+        destPath := path.Join(outputArguments.CopyPath, folderName)
 
         entries, err := ioutil.ReadDir(destPath)
         log.PanicIf(err)
@@ -584,64 +603,6 @@ func writeCopyPathInfo(groupArguments groupParameters, sessionTimestampPhrase, d
     return nil
 }
 
-func writeGroupInfoAsKml(tallies map[geoattractor.CityRecord][2]int, filepath string) (err error) {
-    defer func() {
-        if state := recover(); state != nil {
-            err = log.Wrap(state.(error))
-        }
-    }()
-
-    elements := make([]kml.Element, 0)
-    for cr, tallies := range tallies {
-        var description string
-        if tallies[0] > 1 {
-            description = fmt.Sprintf("%d groups<br />%d pictures", tallies[0], tallies[1])
-        } else {
-            description = fmt.Sprintf("%d pictures", tallies[1])
-        }
-
-        coordinate := kml.Coordinate{
-            cr.Longitude,
-            cr.Latitude,
-            0,
-        }
-
-        name := cr.CityAndProvinceState()
-
-        groupPoint := kml.Placemark(
-            kml.Name(name),
-            kml.Description(description),
-
-            kml.Point(
-                kml.Coordinates(coordinate),
-            ),
-        )
-
-        elements = append(elements, groupPoint)
-    }
-
-    k := kml.KML(
-        kml.Document(
-            elements...,
-        ),
-    )
-
-    // Render the XML.
-
-    f, err := os.Create(filepath)
-    log.PanicIf(err)
-
-    defer f.Close()
-
-    e := xml.NewEncoder(f)
-    e.Indent("", "  ")
-
-    err = e.Encode(k)
-    log.PanicIf(err)
-
-    return nil
-}
-
 func main() {
     defer func() {
         if state := recover(); state != nil {
@@ -661,6 +622,14 @@ func main() {
     switch p.Active.Name {
     case "group":
         handleGroup(rootArguments.Group)
+    case "locations":
+        handleLocations(p.Active.Active.Name, rootArguments.Locations)
+    case "patch":
+        handlePatch(rootArguments.Patch)
+    case "explain":
+        handleExplain(rootArguments.Explain)
+    case "render":
+        handleRender(rootArguments.Render)
     default:
         fmt.Printf("Subcommand not handled: [%s]\n", p.Active.Name)
         os.Exit(2)
@@ -0,0 +1,100 @@
+package main
+
+import (
+    "fmt"
+
+    "github.com/dsoprea/go-logging"
+
+    "github.com/dsoprea/go-geographic-autogroup-images"
+)
+
+// writeImageMetadata writes the locations that we computed back out so that
+// other tools can see them: XMP sidecars (with the location, city, group, and
+// trip) and the GPS IFD of copied JPEGs. If we copied, only the copies are
+// touched. Otherwise, the sources are only touched if that was explicitly
+// requested and existing sidecars are never overwritten.
+func writeImageMetadata(outputArguments outputParameters, outputGroups []*outputGroup, fileMappings map[string]imageFileMapping) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    if outputArguments.WriteXmpSidecars == false && outputArguments.WriteExifGps == false {
+        return nil
+    }
+
+    isCopying := outputArguments.CopyPath != ""
+    if isCopying == false && outputArguments.ModifySources == false {
+        log.Panicf("writing image metadata without --copy-into-path requires --modify-sources")
+    }
+
+    sidecarCount := 0
+    exifCount := 0
+
+    for _, og := range outputGroups {
+        for _, gr := range og.Records {
+            targetFilepath := gr.Filepath
+            if isCopying == true {
+                ifm, found := fileMappings[gr.Filepath]
+                if found == false {
+                    // Not copied. Don't touch the source.
+                    continue
+                }
+
+                targetFilepath = ifm.OutputFilepath
+            }
+
+            if outputArguments.WriteXmpSidecars == true {
+                is := geoautogroup.ImageSidecar{
+                    HasLocation: gr.HasGeographic,
+                    Latitude:    gr.Latitude,
+                    Longitude:   gr.Longitude,
+                    City:        og.CityRecord.CityAndProvinceState(),
+                    Country:     og.CityRecord.Country,
+                    Group:       og.Name(),
+                    Trip:        og.Trip,
+                }
+
+                // Copies are ours to overwrite. Sidecars next to the sources
+                // might have come from somewhere else.
+                sidecarFilepath, written, err := is.WriteXmpSidecar(targetFilepath, isCopying)
+                log.PanicIf(err)
+
+                if written == true {
+                    sidecarCount++
+                } else {
+                    fmt.Printf("WARNING: Sidecar already exists. Skipping: [%s]\n", sidecarFilepath)
+                }
+            }
+
+            // Only write coordinates that the image doesn't already have.
+            method := og.LocationMethods[gr.Filepath]
+            if outputArguments.WriteExifGps == false || method == geoautogroup.LocationMethodNative || method == geoautogroup.LocationMethodNone {
+                continue
+            }
+
+            err := geoautogroup.WriteJpegGpsExif(targetFilepath, gr.Latitude, gr.Longitude)
+            if err == geoautogroup.ErrNotJpeg {
+                continue
+            } else if err != nil {
+                fmt.Printf("WARNING: Could not write EXIF GPS. Skipping: [%s] %s\n", targetFilepath, err)
+                continue
+            }
+
+            exifCount++
+        }
+    }
+
+    fmt.Printf("\n")
+
+    if outputArguments.WriteXmpSidecars == true {
+        fmt.Printf("Wrote (%d) XMP sidecars.\n", sidecarCount)
+    }
+
+    if outputArguments.WriteExifGps == true {
+        fmt.Printf("Wrote EXIF GPS into (%d) images.\n", exifCount)
+    }
+
+    return nil
+}
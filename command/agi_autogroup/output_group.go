@@ -0,0 +1,253 @@
+package main
+
+import (
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/dsoprea/go-geographic-attractor"
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+
+    "github.com/dsoprea/go-geographic-autogroup-images"
+)
+
+// outputGroup is a finished group along with everything that the exporters
+// need to know about it.
+type outputGroup struct {
+    GroupKey   geoautogroup.GroupKey
+    CityRecord geoattractor.CityRecord
+    Records    []*geoindex.GeographicRecord
+
+    // FolderName is the folder that the images are copied into relative to the
+    // copy path.
+    FolderName string
+
+    // First and Last are the timestamps of the earliest and latest images.
+    First time.Time
+    Last  time.Time
+
+    // Located is false if none of the images have a location.
+    Located bool
+
+    CentroidLatitude  float64
+    CentroidLongitude float64
+
+    // Bbox is the bounding-box of the located images as [west, south, east,
+    // north].
+    Bbox [4]float64
+
+    // LocationMethods are keyed by image file-path.
+    LocationMethods map[string]string
+
+    // MergeNotes describe how the images were merged into this group, keyed
+    // by image file-path.
+    MergeNotes map[string][]string
+
+    // Track is the location records that the images in the group were matched
+    // against, in chronological order.
+    Track []*geoindex.GeographicRecord
+
+    // Trip is the name of the trip that the group is a part of. This is set by
+    // `assignTrips`.
+    Trip string
+}
+
+// newOutputGroup describes a group that was just found. `gr` provides the
+// merge notes.
+func newOutputGroup(fg *geoautogroup.FindGroups, gr *geoautogroup.GroupsReducer, groupKey geoautogroup.GroupKey, records []*geoindex.GeographicRecord, folderName string) (og *outputGroup, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    og = &outputGroup{
+        GroupKey:        groupKey,
+        CityRecord:      fg.NearestCityIndex()[groupKey.NearestCityKey],
+        Records:         records,
+        FolderName:      folderName,
+        LocationMethods: make(map[string]string),
+        MergeNotes:      make(map[string][]string),
+    }
+
+    for _, imageGr := range records {
+        method, err := fg.LocationMethod(imageGr)
+        log.PanicIf(err)
+
+        og.LocationMethods[imageGr.Filepath] = method
+
+        if mergeNotes := gr.MergeNotes(imageGr.Filepath); len(mergeNotes) > 0 {
+            og.MergeNotes[imageGr.Filepath] = mergeNotes
+        }
+    }
+
+    og.summarize()
+    og.Track = fg.LocationRecordsBetween(og.First, og.Last)
+
+    return og, nil
+}
+
+// newOutputGroupFromDocument describes a group that was read from a groups
+// document.
+func newOutputGroupFromDocument(gdg geoautogroup.GroupsDocumentGroup, cityRecord geoattractor.CityRecord, folderName string) (og *outputGroup) {
+    og = &outputGroup{
+        GroupKey:        gdg.GroupKey,
+        CityRecord:      cityRecord,
+        Records:         make([]*geoindex.GeographicRecord, len(gdg.Records)),
+        FolderName:      folderName,
+        LocationMethods: make(map[string]string),
+        MergeNotes:      make(map[string][]string),
+        Trip:            gdg.Trip,
+    }
+
+    for i, gdr := range gdg.Records {
+        og.Records[i] = gdr.GeographicRecord()
+        og.LocationMethods[gdr.Filepath] = gdr.LocationMethod
+
+        if len(gdr.MergeNotes) > 0 {
+            og.MergeNotes[gdr.Filepath] = gdr.MergeNotes
+        }
+    }
+
+    og.summarize()
+    og.Track = gdg.Track()
+
+    return og
+}
+
+// summarize calculates the time-range, centroid, and bounding-box of the
+// records.
+func (og *outputGroup) summarize() {
+    var latitudeSum, longitudeSum float64
+    locatedCount := 0
+
+    for _, gr := range og.Records {
+        if og.First.IsZero() == true || gr.Timestamp.Before(og.First) == true {
+            og.First = gr.Timestamp
+        }
+
+        if og.Last.IsZero() == true || gr.Timestamp.After(og.Last) == true {
+            og.Last = gr.Timestamp
+        }
+
+        if gr.HasGeographic == false {
+            continue
+        }
+
+        if locatedCount == 0 {
+            og.Bbox = [4]float64{gr.Longitude, gr.Latitude, gr.Longitude, gr.Latitude}
+        } else {
+            if gr.Longitude < og.Bbox[0] {
+                og.Bbox[0] = gr.Longitude
+            }
+
+            if gr.Latitude < og.Bbox[1] {
+                og.Bbox[1] = gr.Latitude
+            }
+
+            if gr.Longitude > og.Bbox[2] {
+                og.Bbox[2] = gr.Longitude
+            }
+
+            if gr.Latitude > og.Bbox[3] {
+                og.Bbox[3] = gr.Latitude
+            }
+        }
+
+        latitudeSum += gr.Latitude
+        longitudeSum += gr.Longitude
+        locatedCount++
+    }
+
+    if locatedCount > 0 {
+        og.Located = true
+        og.CentroidLatitude = latitudeSum / float64(locatedCount)
+        og.CentroidLongitude = longitudeSum / float64(locatedCount)
+    }
+}
+
+// Name is the display-name of the group.
+func (og *outputGroup) Name() string {
+    return og.CityRecord.CityAndProvinceState()
+}
+
+// CameraModel returns the camera-model or a placeholder if there wasn't one.
+func (og *outputGroup) CameraModel() string {
+    if og.GroupKey.CameraModel == "" {
+        return "no_camera_model"
+    }
+
+    return og.GroupKey.CameraModel
+}
+
+type outputGroupsByTime []*outputGroup
+
+func (ogs outputGroupsByTime) Len() int {
+    return len(ogs)
+}
+
+func (ogs outputGroupsByTime) Less(i, j int) bool {
+    if ogs[i].First.Equal(ogs[j].First) == true {
+        return ogs[i].GroupKey.CameraModel < ogs[j].GroupKey.CameraModel
+    }
+
+    return ogs[i].First.Before(ogs[j].First)
+}
+
+func (ogs outputGroupsByTime) Swap(i, j int) {
+    ogs[i], ogs[j] = ogs[j], ogs[i]
+}
+
+// assignTrips sorts the groups chronologically and names the runs of groups
+// that are no more than `tripGap` apart as trips. A trip is named by its date
+// range and the location of its first group.
+func assignTrips(outputGroups []*outputGroup, tripGap time.Duration) {
+    sort.Sort(outputGroupsByTime(outputGroups))
+
+    nameTrip := func(trip []*outputGroup) {
+        start := trip[0].First.Local()
+
+        // The groups are ordered by their first image, not their last.
+        end := trip[0].Last
+        for _, og := range trip {
+            if og.Last.After(end) == true {
+                end = og.Last
+            }
+        }
+
+        end = end.Local()
+
+        startPhrase := start.Format("2006-01-02")
+        endPhrase := end.Format("2006-01-02")
+
+        var name string
+        if startPhrase == endPhrase {
+            name = fmt.Sprintf("%s %s", startPhrase, trip[0].Name())
+        } else {
+            name = fmt.Sprintf("%s to %s %s", startPhrase, endPhrase, trip[0].Name())
+        }
+
+        for _, og := range trip {
+            og.Trip = name
+        }
+    }
+
+    var trip []*outputGroup
+    var tripEnd time.Time
+    for _, og := range outputGroups {
+        if len(trip) > 0 && og.First.Sub(tripEnd) > tripGap {
+            nameTrip(trip)
+            trip = nil
+        }
+
+        trip = append(trip, og)
+        if og.Last.After(tripEnd) == true {
+            tripEnd = og.Last
+        }
+    }
+
+    if len(trip) > 0 {
+        nameTrip(trip)
+    }
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "os"
+    "path"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/dsoprea/go-geographic-attractor"
+    "github.com/dsoprea/go-geographic-attractor/index"
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-parse"
+
+    "github.com/dsoprea/go-geographic-autogroup-images"
+)
+
+type patchParameters struct {
+    attractorParameters
+
+    DataPaths                  []string `long:"data-path" description:"Path to scan for geographic data (can be provided more than once)"`
+    LocationsDatabaseFilepath  string   `long:"geographic-db-filepath" description:"File-path of locations database"`
+    ListfileFilepaths          []string `long:"listfile-location-data-filepath" description:"Zero or more list-files that were already used in the run"`
+    UnassignedFilepath         string   `long:"unassigned-filepath" description:"The unassigned-images list (--unassigned-filepath) or the JSON unassigned report (--unassigned-report-filepath, named '*.json') written by the grouping run. The CSV report isn't supported." required:"true"`
+    OutputFilepath             string   `long:"output-filepath" description:"File to write the new list-file to" required:"true"`
+    ClusterGapRaw              string   `long:"cluster-gap" description:"Images more than this far apart are proposed separately. Example: 2h" default:"2h"`
+    AcceptNearest              bool     `long:"accept-nearest" description:"Don't prompt. Use whichever known location is closest in time to each cluster."`
+    ImageTimestampSkewRaw      string   `long:"image-timestamp-skew" description:"Must match what was used in the grouping run. Example: 5h"`
+    ImageTimestampSkewPolarity bool     `long:"image-timestamp-skew-polarity" description:"false if the skew is negative and true if positive"`
+}
+
+// patchCandidate is one city that a cluster could be assigned to.
+type patchCandidate struct {
+    label      string
+    sourceName string
+    cr         geoattractor.CityRecord
+}
+
+func getPatchCandidates(ci *geoattractorindex.CityIndex, pc geoautogroup.PatchCluster) (candidates []patchCandidate, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    candidates = make([]patchCandidate, 0)
+
+    add := func(label string, gr *geoindex.GeographicRecord, relativeTo time.Time) {
+        if gr == nil {
+            return
+        }
+
+        sourceName, _, cr, err := ci.Nearest(gr.Latitude, gr.Longitude, false)
+        if err != nil {
+            if log.Is(err, geoattractorindex.ErrNoNearestCity) == true {
+                return
+            }
+
+            log.Panic(err)
+        }
+
+        // Don't offer the same city twice.
+        for _, existing := range candidates {
+            if existing.cr.Id == cr.Id {
+                return
+            }
+        }
+
+        pcd := patchCandidate{
+            label:      fmt.Sprintf("%s (%s)", label, describeLocationRecord(gr, relativeTo)),
+            sourceName: sourceName,
+            cr:         cr,
+        }
+
+        candidates = append(candidates, pcd)
+    }
+
+    // The nearest goes first so that it is the default.
+    nearest := pc.Nearest()
+    if nearest == pc.Before {
+        add("last known location before", pc.Before, pc.First().Timestamp)
+        add("first known location after", pc.After, pc.Last().Timestamp)
+    } else {
+        add("first known location after", pc.After, pc.Last().Timestamp)
+        add("last known location before", pc.Before, pc.First().Timestamp)
+    }
+
+    return candidates, nil
+}
+
+// promptPatchCandidate asks which candidate to use. It returns -1 to skip the
+// cluster and -2 to stop.
+func promptPatchCandidate(r *bufio.Reader, pc geoautogroup.PatchCluster, candidates []patchCandidate) (choice int, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    fmt.Printf("\n")
+    fmt.Printf("(%d) images from [%s] to [%s]\n", len(pc.Images), pc.First().Timestamp.Format(time.RFC3339), pc.Last().Timestamp.Format(time.RFC3339))
+    fmt.Printf("  First: [%s]\n", pc.First().Filepath)
+    fmt.Printf("  Last: [%s]\n", pc.Last().Filepath)
+    fmt.Printf("\n")
+
+    for i, pcd := range candidates {
+        fmt.Printf("  %d) %s: %s\n", i+1, pcd.cr.CityAndProvinceState(), pcd.label)
+    }
+
+    fmt.Printf("  s) skip\n")
+    fmt.Printf("  q) stop and write what we have\n")
+
+    for {
+        fmt.Printf("\n")
+        fmt.Printf("Choice [1]: ")
+
+        response, err := r.ReadString('\n')
+        if err != nil && err != io.EOF {
+            log.Panic(err)
+        }
+
+        eof := err == io.EOF
+
+        response = strings.TrimSpace(response)
+        if response == "" {
+            if eof == true {
+                return -2, nil
+            } else if len(candidates) > 0 {
+                return 0, nil
+            }
+
+            return -1, nil
+        } else if response == "s" {
+            return -1, nil
+        } else if response == "q" {
+            return -2, nil
+        }
+
+        n, err := strconv.Atoi(response)
+        if err == nil && n >= 1 && n <= len(candidates) {
+            return n - 1, nil
+        }
+
+        fmt.Printf("Choice not valid: [%s]\n", response)
+
+        if eof == true {
+            return -2, nil
+        }
+    }
+}
+
+// writePatchRows writes the point rows that locate every image in the cluster.
+// A range row isn't used since the cluster may have long gaps that the points
+// of a range would fill with locations that the images were never at.
+func writePatchRows(w io.Writer, pc geoautogroup.PatchCluster, pcd patchCandidate) (rowsCount int) {
+    comment := fmt.Sprintf("%s: %d images, %s to %s", pcd.cr.CityAndProvinceState(), len(pc.Images), path.Base(pc.First().Filepath), path.Base(pc.Last().Filepath))
+    comment = strings.Replace(comment, "\"", "'", -1)
+
+    for _, timestamp := range pc.PointTimestamps() {
+        fmt.Fprintf(w, "%s,%s:%s,%s,,,\"%s\"\n", geoautogroup.ListfileKindId, pcd.sourceName, pcd.cr.Id, timestamp.Format(time.RFC3339), comment)
+        rowsCount++
+    }
+
+    return rowsCount
+}
+
+// readUnassignedEntries reads either the unassigned-images list or the JSON
+// unassigned report.
+func readUnassignedEntries(filepath string) (entries []geoautogroup.UnassignedListEntry, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    f, err := os.Open(filepath)
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    if strings.ToLower(path.Ext(filepath)) == ".json" {
+        entries, err = geoautogroup.ReadUnassignedReport(f)
+    } else {
+        entries, err = geoautogroup.ReadUnassignedList(f)
+    }
+
+    log.PanicIf(err)
+
+    return entries, nil
+}
+
+func handlePatch(patchArguments patchParameters) {
+    defer func() {
+        if state := recover(); state != nil {
+            err := log.Wrap(state.(error))
+            log.PrintError(err)
+            os.Exit(-1)
+        }
+    }()
+
+    clusterGap, _, err := timeparse.ParseDuration(patchArguments.ClusterGapRaw)
+    log.PanicIf(err)
+
+    entries, err := readUnassignedEntries(patchArguments.UnassignedFilepath)
+    log.PanicIf(err)
+
+    filepaths := make([]string, 0)
+    for _, ule := range entries {
+        if geoautogroup.IsPatchableReason(ule.Reason) == false {
+            continue
+        }
+
+        filepaths = append(filepaths, ule.Filepath)
+    }
+
+    fmt.Printf("(%d) of (%d) unassigned images need location data.\n", len(filepaths), len(entries))
+
+    if len(filepaths) == 0 {
+        return
+    }
+
+    attractorArguments := patchArguments.attractorParameters
+
+    ci, err := geoautogroup.GetCityIndex(
+        attractorArguments.CityDatabaseFilepath,
+        attractorArguments.CountriesFilepath,
+        attractorArguments.CitiesFilepath,
+        attractorArguments.CountryFilter,
+        true,
+    )
+
+    log.PanicIf(err)
+
+    defer ci.Close()
+
+    indexArguments := indexParameters{
+        DataPaths:                 patchArguments.DataPaths,
+        LocationsDatabaseFilepath: patchArguments.LocationsDatabaseFilepath,
+        ListfileFilepaths:         patchArguments.ListfileFilepaths,
+    }
+
+    locationTs := getLocationTs(ci, attractorArguments, indexArguments, false, true)
+
+    imageTimestampSkew := getImageTimestampSkew(patchArguments.ImageTimestampSkewRaw, patchArguments.ImageTimestampSkewPolarity)
+
+    imageIndex := geoindex.NewTimeIndex()
+    il := geoautogroup.NewImageLoader(imageTimestampSkew, 0)
+
+    err = il.Load(filepaths, imageIndex, nil)
+    log.PanicIf(err)
+
+    clusters := geoautogroup.FindPatchClusters(locationTs, imageIndex.Series(), clusterGap)
+
+    fmt.Printf("(%d) clusters found.\n", len(clusters))
+
+    g, err := os.Create(patchArguments.OutputFilepath)
+    log.PanicIf(err)
+
+    defer g.Close()
+
+    fmt.Fprintf(g, "%s,2\n", geoautogroup.ListfileVersionKeyword)
+    fmt.Fprintf(g, "# Generated from [%s].\n", patchArguments.UnassignedFilepath)
+    fmt.Fprintf(g, "# kind,location,start,end,priority,comment\n")
+
+    r := bufio.NewReader(os.Stdin)
+
+    written := 0
+    skipped := 0
+    for _, pc := range clusters {
+        candidates, err := getPatchCandidates(ci, pc)
+        log.PanicIf(err)
+
+        if len(candidates) == 0 {
+            fmt.Fprintf(g, "# No known locations for (%d) images from [%s] to [%s].\n", len(pc.Images), pc.First().Timestamp.Format(time.RFC3339), pc.Last().Timestamp.Format(time.RFC3339))
+            skipped++
+
+            continue
+        }
+
+        choice := 0
+        if patchArguments.AcceptNearest == false {
+            choice, err = promptPatchCandidate(r, pc, candidates)
+            log.PanicIf(err)
+
+            if choice == -2 {
+                break
+            } else if choice == -1 {
+                skipped++
+                continue
+            }
+        }
+
+        written += writePatchRows(g, pc, candidates[choice])
+    }
+
+    fmt.Printf("\n")
+    fmt.Printf("Wrote (%d) rows to [%s]. (%d) clusters skipped.\n", written, patchArguments.OutputFilepath, skipped)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "path"
+    "sort"
+    "time"
+
+    "text/template"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-parse"
+
+    "github.com/dsoprea/go-geographic-autogroup-images"
+)
+
+type renderParameters struct {
+    FromFilepath string `long:"from" description:"File-path of a 'groups.json' written by a previous grouping" required:"true"`
+
+    outputParameters
+}
+
+// renderOutputs writes everything that is derived from the finished groups:
+// the copies, the image metadata, the catalog, and the GeoJSON, assignments,
+// GPX, and KML exports. Trips are assigned here since the trip-gap is an
+// output option.
+func renderOutputs(outputArguments outputParameters, outputGroups []*outputGroup, sessionTimestampPhrase string) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    tripGap, _, err := timeparse.ParseDuration(outputArguments.TripGapRaw)
+    log.PanicIf(err)
+
+    assignTrips(outputGroups, tripGap)
+
+    // Copy images.
+
+    imageOutputPathTemplate := template.Must(template.New("group path template").Parse(outputArguments.ImageOutputPathTemplate))
+
+    printProgressOutput := (outputArguments.NoPrintProgressOutput == false)
+
+    // Depending on the folder-template, the groups may, and probably will, be
+    // merged-together to some degree on disk (our groups might differ in
+    // minutes but may be stored on disk by date). So, keep track of them based
+    // on the on-disk folder rather than by their in-memory names (found via
+    // `String()`).
+    binnedImages := make(map[string][]*geoindex.GeographicRecord)
+
+    fileMappings := make(map[string]imageFileMapping)
+
+    if outputArguments.CopyPath != "" {
+        fmt.Printf("Copying images:\n")
+        fmt.Printf("\n")
+
+        for _, og := range outputGroups {
+            err := copyFiles(outputArguments, og, outputArguments.CopyPath, imageOutputPathTemplate, printProgressOutput, binnedImages, fileMappings)
+            log.PanicIf(err)
+        }
+    }
+
+    err = writeImageMetadata(outputArguments, outputGroups, fileMappings)
+    log.PanicIf(err)
+
+    // Automatically write a destination catalog if we're doing a copy.
+
+    destCatalogPath := ""
+    thumbnailsPath := ""
+    if outputArguments.CatalogPath != "" {
+        destCatalogPath = outputArguments.CatalogPath
+        thumbnailsPath = path.Join(outputArguments.CatalogPath, catalogThumbnailsDirectoryName)
+    } else if outputArguments.CopyPath != "" {
+        destCatalogPath = path.Join(outputArguments.CopyPath, "catalog", sessionTimestampPhrase)
+        thumbnailsPath = path.Join(outputArguments.CopyPath, "catalog", catalogThumbnailsDirectoryName)
+    }
+
+    if destCatalogPath != "" {
+        fmt.Printf("\n")
+        fmt.Printf("Writing catalog to: %s\n", destCatalogPath)
+
+        co := catalogOptions{
+            NoEmbedImages:      outputArguments.NoEmbedImages,
+            ThumbnailsPath:     thumbnailsPath,
+            MapTilesPath:       outputArguments.CatalogMapTilesPath,
+            MapOutlineFilepath: outputArguments.CatalogMapOutlineFilepath,
+            FileMappings:       fileMappings,
+        }
+
+        err := writeDestHtmlCatalog(outputGroups, destCatalogPath, co)
+        log.PanicIf(err)
+    }
+
+    if len(binnedImages) > 0 {
+        err := writeCopyPathInfo(outputArguments, sessionTimestampPhrase, outputArguments.CopyPath, binnedImages)
+        log.PanicIf(err)
+
+        tallies := make(Tallies, 0)
+        for folderName, entries := range binnedImages {
+            count := len(entries)
+            if count < largestGroupMinimumSize {
+                continue
+            }
+
+            ti := tallyItem{
+                name:  folderName,
+                count: count,
+            }
+
+            tallies = append(tallies, ti)
+        }
+
+        if len(tallies) > 0 {
+            // This sorts in reverse.
+            sort.Sort(tallies)
+
+            fmt.Printf("\n")
+            fmt.Printf("Largest Groups\n")
+            fmt.Printf("==============\n")
+
+            for _, ti := range tallies {
+                fmt.Printf("%s: (%d)\n", ti.name, ti.count)
+            }
+
+            fmt.Printf("\n")
+        }
+    }
+
+    geoJsonFilepath := outputArguments.GeoJsonFilepath
+    if geoJsonFilepath == "" {
+        if outputArguments.CopyPath != "" {
+            geoJsonFilepath = path.Join(outputArguments.CopyPath, "groups.geojson")
+        } else {
+            geoJsonFilepath = "none"
+        }
+    }
+
+    if geoJsonFilepath != "none" {
+        err := writeGroupInfoAsGeoJson(outputGroups, geoJsonFilepath)
+        log.PanicIf(err)
+    }
+
+    assignmentsFilepath := outputArguments.AssignmentsFilepath
+    if assignmentsFilepath == "" {
+        if outputArguments.CopyPath != "" {
+            filename := "assignments.csv"
+            if outputArguments.AssignmentsFormat == assignmentsFormatColumnar {
+                filename = "assignments.json"
+            }
+
+            assignmentsFilepath = path.Join(outputArguments.CopyPath, filename)
+        } else {
+            assignmentsFilepath = "none"
+        }
+    }
+
+    if assignmentsFilepath != "none" {
+        err := writeAssignments(outputGroups, fileMappings, assignmentsFilepath, outputArguments.AssignmentsFormat)
+        log.PanicIf(err)
+    }
+
+    gpxFilepath := outputArguments.GpxFilepath
+    if gpxFilepath == "" {
+        if outputArguments.CopyPath != "" {
+            gpxFilepath = path.Join(outputArguments.CopyPath, "groups.gpx")
+        } else {
+            gpxFilepath = "none"
+        }
+    }
+
+    if gpxFilepath != "none" {
+        err := writeGroupInfoAsGpx(outputGroups, gpxFilepath)
+        log.PanicIf(err)
+    }
+
+    kmlFilepath := outputArguments.KmlFilepath
+    if kmlFilepath == "" {
+        if outputArguments.CopyPath != "" {
+            kmlFilepath = path.Join(outputArguments.CopyPath, "groups.kml")
+        } else {
+            kmlFilepath = "none"
+        }
+    }
+
+    if kmlFilepath != "none" {
+        ko := kmlOptions{
+            MinimumImageCount: outputArguments.KmlMinimumGroupImageCount,
+            CatalogPath:       destCatalogPath,
+            FileMappings:      fileMappings,
+            ThumbnailsPath:    thumbnailsPath,
+        }
+
+        err := writeGroupInfoAsKml(outputGroups, kmlFilepath, ko)
+        log.PanicIf(err)
+    }
+
+    return nil
+}
+
+// handleRender writes the outputs for the groups in a saved "groups.json"
+// without loading any indices or grouping again. The tracks are only the
+// location records that were actually matched since the location index isn't
+// loaded.
+func handleRender(renderArguments renderParameters) {
+    defer func() {
+        if state := recover(); state != nil {
+            err := log.Wrap(state.(error))
+            log.PrintError(err)
+            os.Exit(-1)
+        }
+    }()
+
+    sessionTimestampPhrase := geoautogroup.GetCondensedDatetime(time.Now())
+
+    f, err := os.Open(renderArguments.FromFilepath)
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    gd, err := geoautogroup.ReadGroupsDocument(f)
+    log.PanicIf(err)
+
+    imageOutputPathTemplate := template.Must(template.New("group path template").Parse(renderArguments.ImageOutputPathTemplate))
+
+    nearestCityIndex := gd.NearestCityIndex()
+
+    outputGroups := make([]*outputGroup, len(gd.Groups))
+    for i, gdg := range gd.Groups {
+        cityRecord := nearestCityIndex[gdg.GroupKey.NearestCityKey]
+
+        folderName, _, err := getGroupFolderName(cityRecord, gdg.GroupKey, len(gdg.Records), imageOutputPathTemplate)
+        log.PanicIf(err)
+
+        outputGroups[i] = newOutputGroupFromDocument(gdg, cityRecord, folderName)
+    }
+
+    fmt.Printf("Read (%d) groups from: %s\n", len(outputGroups), renderArguments.FromFilepath)
+    fmt.Printf("\n")
+
+    err = renderOutputs(renderArguments.outputParameters, outputGroups, sessionTimestampPhrase)
+    log.PanicIf(err)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "path"
+    "time"
+
+    "encoding/csv"
+    "encoding/json"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+
+    "github.com/dsoprea/go-geographic-autogroup-images"
+)
+
+// describeLocationRecord returns a one-line description of a location record
+// relative to the given time.
+func describeLocationRecord(gr *geoindex.GeographicRecord, relativeTo time.Time) string {
+    if gr == nil {
+        return "(none)"
+    }
+
+    delta := gr.Timestamp.Sub(relativeTo)
+    if delta < 0 {
+        delta = -delta
+    }
+
+    return fmt.Sprintf("%s (%.6f, %.6f) from [%s], %s away", gr.Timestamp.Format(time.RFC3339), gr.Latitude, gr.Longitude, path.Base(gr.Filepath), delta)
+}
+
+// writeCoverageReport writes every period where we had images but no usable
+// location data.
+func writeCoverageReport(gaps []geoautogroup.LocationCoverageGap, filepath string) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    f, err := os.Create(filepath)
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    for i, lcg := range gaps {
+        firstTimestamp := lcg.FirstImage.Timestamp
+        lastTimestamp := lcg.LastImage.Timestamp
+
+        fmt.Fprintf(f, "Period (%d): %s - %s\n", i+1, firstTimestamp.Format(time.RFC3339), lastTimestamp.Format(time.RFC3339))
+        fmt.Fprintf(f, "  Images: (%d)\n", lcg.ImageCount)
+        fmt.Fprintf(f, "  First image: %s\n", lcg.FirstImage.Filepath)
+        fmt.Fprintf(f, "  Last image: %s\n", lcg.LastImage.Filepath)
+        fmt.Fprintf(f, "  Nearest location before: %s\n", describeLocationRecord(lcg.Before, firstTimestamp))
+        fmt.Fprintf(f, "  Nearest location after: %s\n", describeLocationRecord(lcg.After, lastTimestamp))
+        fmt.Fprintf(f, "\n")
+    }
+
+    return nil
+}
+
+// writeUnassignedReport writes the diagnostics for every image that we
+// couldn't group as either JSON or CSV.
+func writeUnassignedReport(unassignedRecords []geoautogroup.UnassignedRecord, filepath, format string) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    entries := make([]geoautogroup.UnassignedReportEntry, len(unassignedRecords))
+    for i, ur := range unassignedRecords {
+        entries[i] = ur.ReportEntry()
+    }
+
+    f, err := os.Create(filepath)
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    if format == "json" {
+        e := json.NewEncoder(f)
+        e.SetIndent("", "  ")
+
+        err = e.Encode(entries)
+        log.PanicIf(err)
+    } else if format == "csv" {
+        w := csv.NewWriter(f)
+
+        err = w.Write(geoautogroup.UnassignedReportColumns)
+        log.PanicIf(err)
+
+        for _, ure := range entries {
+            err = w.Write(ure.Row())
+            log.PanicIf(err)
+        }
+
+        w.Flush()
+
+        err = w.Error()
+        log.PanicIf(err)
+    } else {
+        log.Panicf("unassigned-report format [%s] not valid", format)
+    }
+
+    return nil
+}
@@ -0,0 +1,161 @@
+package geoautogroup
+
+import (
+    "fmt"
+    "path"
+    "time"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-index"
+)
+
+// LocationCoverageGap is a period in which we have images that need location
+// data but where the location data is either missing or too old to be used.
+type LocationCoverageGap struct {
+    // ImageCount is the number of images in the period.
+    ImageCount int
+
+    // FirstImage is the earliest image in the period.
+    FirstImage *geoindex.GeographicRecord
+
+    // LastImage is the latest image in the period.
+    LastImage *geoindex.GeographicRecord
+
+    // Before is the nearest location record before the first image. This is
+    // nil if there is no location data before the period.
+    Before *geoindex.GeographicRecord
+
+    // After is the nearest location record after the last image. This is nil if
+    // there is no location data after the period.
+    After *geoindex.GeographicRecord
+}
+
+func (lcg LocationCoverageGap) String() string {
+    return fmt.Sprintf("LocationCoverageGap<IMAGES=(%d) FIRST=[%s] LAST=[%s]>", lcg.ImageCount, path.Base(lcg.FirstImage.Filepath), path.Base(lcg.LastImage.Filepath))
+}
+
+// isImageCovered returns true if the image either has its own location or
+// would be matched to a location record that isn't too old. This mirrors the
+// decisions made in `getCurrentPositionImages`. Locations that were matched,
+// borrowed from an adjacent image, or given by an override are not the image's
+// own, so those images are checked against the location data like any other.
+func (fg *FindGroups) isImageCovered(imageTe timeindex.TimeEntry, imageGr *geoindex.GeographicRecord) (covered bool, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    method, err := fg.LocationMethod(imageGr)
+    log.PanicIf(err)
+
+    if method == LocationMethodNative {
+        return true, nil
+    }
+
+    matchedTe, err := fg.locationMatcherFn(imageTe)
+    if err != nil {
+        if log.Is(err, ErrNoNearLocationRecord) == true {
+            return false, nil
+        }
+
+        log.Panic(err)
+    }
+
+    locationGr := matchedTe.Items[0].(*geoindex.GeographicRecord)
+
+    timeDelta := imageGr.Timestamp.Sub(locationGr.Timestamp)
+    if timeDelta > LocationMatchTimeWarnIntervalThreshold {
+        return false, nil
+    }
+
+    return true, nil
+}
+
+// NearestLocationRecords returns the last location record at or before the
+// first timestamp and the first location record at or after the second. Either
+// will be nil if there is no such record.
+func NearestLocationRecords(locationTs timeindex.TimeSlice, first, last time.Time) (before, after *geoindex.GeographicRecord) {
+    position := timeindex.SearchTimes(locationTs, first)
+    if position < len(locationTs) && locationTs[position].Time.Equal(first) == true {
+        before = locationTs[position].Items[0].(*geoindex.GeographicRecord)
+    } else if position > 0 {
+        te := locationTs[position-1]
+        before = te.Items[len(te.Items)-1].(*geoindex.GeographicRecord)
+    }
+
+    position = timeindex.SearchTimes(locationTs, last)
+    if position < len(locationTs) {
+        after = locationTs[position].Items[0].(*geoindex.GeographicRecord)
+    }
+
+    return before, after
+}
+
+// LocationCoverageGaps walks the images and the location data together and
+// returns every period where there are images that can not be matched to a
+// location because the location data is missing or too old. Adjacent images
+// that can't be matched are reported as one period unless there are location
+// records between them. This does not modify any state and can be called before
+// or after grouping.
+func (fg *FindGroups) LocationCoverageGaps() (gaps []LocationCoverageGap, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    gaps = make([]LocationCoverageGap, 0)
+
+    var current *LocationCoverageGap
+    var firstTe, lastTe timeindex.TimeEntry
+
+    flush := func() {
+        if current == nil {
+            return
+        }
+
+        current.Before, current.After = NearestLocationRecords(fg.locationTs, firstTe.Time, lastTe.Time)
+
+        gaps = append(gaps, *current)
+        current = nil
+    }
+
+    for _, imageTe := range fg.imageTs {
+        for _, item := range imageTe.Items {
+            imageGr := item.(*geoindex.GeographicRecord)
+
+            covered, err := fg.isImageCovered(imageTe, imageGr)
+            log.PanicIf(err)
+
+            if covered == true {
+                flush()
+                continue
+            }
+
+            // If there's location data between the last uncovered image and
+            // this one, they're in different periods.
+            if current != nil && timeindex.SearchTimes(fg.locationTs, lastTe.Time) != timeindex.SearchTimes(fg.locationTs, imageTe.Time) {
+                flush()
+            }
+
+            if current == nil {
+                current = &LocationCoverageGap{
+                    FirstImage: imageGr,
+                }
+
+                firstTe = imageTe
+            }
+
+            current.ImageCount++
+            current.LastImage = imageGr
+
+            lastTe = imageTe
+        }
+    }
+
+    flush()
+
+    return gaps, nil
+}
@@ -0,0 +1,209 @@
+package geoautogroup
+
+import (
+    "testing"
+    "time"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-index"
+)
+
+func getTestCoverageLocationTs() timeindex.TimeSlice {
+    locationTi := geoindex.NewTimeIndex()
+
+    gr := geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc, true, chicagoCoordinates[0], chicagoCoordinates[1], nil)
+    locationTi.AddWithRecord(gr)
+
+    gr = geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc.Add(time.Hour*20), true, detroitCoordinates[0], detroitCoordinates[1], nil)
+    locationTi.AddWithRecord(gr)
+
+    return locationTi.Series()
+}
+
+func TestFindGroups_LocationCoverageGaps_WarnThreshold(t *testing.T) {
+    locationTs := getTestCoverageLocationTs()
+
+    im := geoindex.ImageMetadata{
+        CameraModel: "some model",
+    }
+
+    imageTi := geoindex.NewTimeIndex()
+
+    add := func(filepath string, offset time.Duration, hasGeographic bool) {
+        gr := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, filepath, epochUtc.Add(offset), hasGeographic, chicagoCoordinates[0], chicagoCoordinates[1], im)
+        imageTi.AddWithRecord(gr)
+    }
+
+    // Covered by the first location record.
+    add("image1.jpg", time.Hour, false)
+
+    // Exactly at the warn threshold. Still covered.
+    add("image2.jpg", LocationMatchTimeWarnIntervalThreshold, false)
+
+    // Just past the warn threshold. This starts the first gap.
+    add("image3.jpg", LocationMatchTimeWarnIntervalThreshold+time.Second, false)
+    add("image4.jpg", time.Hour*9, false)
+
+    // Has its own location, which ends the first gap.
+    add("image5.jpg", time.Hour*9+time.Minute*30, true)
+
+    // Well past the threshold. This is the second gap.
+    add("image6.jpg", time.Hour*13, false)
+
+    // Covered by the second location record.
+    add("image7.jpg", time.Hour*20+time.Minute, false)
+
+    fg := NewFindGroups(locationTs, imageTi.Series(), nil)
+    fg.SetLocationMatchStrategy(LocationMatchStrategySparseData)
+
+    gaps, err := fg.LocationCoverageGaps()
+    log.PanicIf(err)
+
+    if len(gaps) != 2 {
+        t.Fatalf("Expected two gaps: %v", gaps)
+    }
+
+    first := gaps[0]
+    if first.ImageCount != 2 || first.FirstImage.Filepath != "image3.jpg" || first.LastImage.Filepath != "image4.jpg" {
+        t.Fatalf("First gap not correct: %s", first)
+    } else if first.Before == nil || first.Before.Timestamp.Equal(epochUtc) == false {
+        t.Fatalf("First gap's preceding location not correct: %v", first.Before)
+    } else if first.After == nil || first.After.Timestamp.Equal(epochUtc.Add(time.Hour*20)) == false {
+        t.Fatalf("First gap's following location not correct: %v", first.After)
+    }
+
+    second := gaps[1]
+    if second.ImageCount != 1 || second.FirstImage.Filepath != "image6.jpg" || second.LastImage.Filepath != "image6.jpg" {
+        t.Fatalf("Second gap not correct: %s", second)
+    }
+}
+
+func TestFindGroups_LocationCoverageGaps_BestGuess(t *testing.T) {
+    locationTs := getTestCoverageLocationTs()
+
+    im := geoindex.ImageMetadata{
+        CameraModel: "some model",
+    }
+
+    imageTi := geoindex.NewTimeIndex()
+
+    // Within the rounding window.
+    gr := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image1.jpg", epochUtc.Add(LocationMatchRoundingWindowDuration), false, 0, 0, im)
+    imageTi.AddWithRecord(gr)
+
+    // Outside of it.
+    gr = geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image2.jpg", epochUtc.Add(LocationMatchRoundingWindowDuration+time.Second), false, 0, 0, im)
+    imageTi.AddWithRecord(gr)
+
+    fg := NewFindGroups(locationTs, imageTi.Series(), nil)
+
+    gaps, err := fg.LocationCoverageGaps()
+    log.PanicIf(err)
+
+    if len(gaps) != 1 {
+        t.Fatalf("Expected one gap: %v", gaps)
+    } else if gaps[0].ImageCount != 1 || gaps[0].FirstImage.Filepath != "image2.jpg" {
+        t.Fatalf("Gap not correct: %s", gaps[0])
+    }
+}
+
+func TestNearestLocationRecords(t *testing.T) {
+    locationTs := getTestCoverageLocationTs()
+
+    // Exact matches on both ends.
+    before, after := NearestLocationRecords(locationTs, epochUtc, epochUtc.Add(time.Hour*20))
+    if before == nil || before.Timestamp.Equal(epochUtc) == false {
+        t.Fatalf("Exact preceding record not found: %v", before)
+    } else if after == nil || after.Timestamp.Equal(epochUtc.Add(time.Hour*20)) == false {
+        t.Fatalf("Exact following record not found: %v", after)
+    }
+
+    // Outside of the location data on both ends.
+    before, after = NearestLocationRecords(locationTs, epochUtc.Add(-time.Hour), epochUtc.Add(time.Hour*21))
+    if before != nil {
+        t.Fatalf("Expected no preceding record: %v", before)
+    } else if after != nil {
+        t.Fatalf("Expected no following record: %v", after)
+    }
+}
+
+func TestFindGroups_LocationCoverageGaps_SplitByLocationData(t *testing.T) {
+    oneDay := time.Hour * 24
+
+    locationTi := geoindex.NewTimeIndex()
+
+    gr := geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc.Add(oneDay*60), true, chicagoCoordinates[0], chicagoCoordinates[1], nil)
+    locationTi.AddWithRecord(gr)
+
+    im := geoindex.ImageMetadata{
+        CameraModel: "some model",
+    }
+
+    imageTi := geoindex.NewTimeIndex()
+
+    // Neither image is near the location data, but the location data is
+    // between them.
+    gr = geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image1.jpg", epochUtc, false, 0, 0, im)
+    imageTi.AddWithRecord(gr)
+
+    gr = geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image2.jpg", epochUtc.Add(oneDay*150), false, 0, 0, im)
+    imageTi.AddWithRecord(gr)
+
+    fg := NewFindGroups(locationTi.Series(), imageTi.Series(), nil)
+
+    gaps, err := fg.LocationCoverageGaps()
+    log.PanicIf(err)
+
+    if len(gaps) != 2 {
+        t.Fatalf("Expected two gaps: %v", gaps)
+    }
+
+    first := gaps[0]
+    if first.ImageCount != 1 || first.FirstImage.Filepath != "image1.jpg" {
+        t.Fatalf("First gap not correct: %s", first)
+    } else if first.Before != nil {
+        t.Fatalf("First gap should have no preceding location: %v", first.Before)
+    } else if first.After == nil || first.After.Timestamp.Equal(epochUtc.Add(oneDay*60)) == false {
+        t.Fatalf("First gap's following location not correct: %v", first.After)
+    }
+
+    second := gaps[1]
+    if second.ImageCount != 1 || second.FirstImage.Filepath != "image2.jpg" {
+        t.Fatalf("Second gap not correct: %s", second)
+    } else if second.Before == nil || second.Before.Timestamp.Equal(epochUtc.Add(oneDay*60)) == false {
+        t.Fatalf("Second gap's preceding location not correct: %v", second.Before)
+    } else if second.After != nil {
+        t.Fatalf("Second gap should have no following location: %v", second.After)
+    }
+}
+
+func TestFindGroups_LocationCoverageGaps_BorrowedLocation(t *testing.T) {
+    locationTs := getTestCoverageLocationTs()
+
+    im := geoindex.ImageMetadata{
+        CameraModel: "some model",
+    }
+
+    imageTi := geoindex.NewTimeIndex()
+
+    adjacentGr := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image1.jpg", epochUtc.Add(time.Hour*12), true, nycCoordinates[0], nycCoordinates[1], im)
+    imageTi.AddWithRecord(adjacentGr)
+
+    // This is what an image looks like after grouping has borrowed a location
+    // for it. It still isn't covered by the location data.
+    borrowedGr := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image2.jpg", epochUtc.Add(time.Hour*12+time.Minute), true, nycCoordinates[0], nycCoordinates[1], im)
+    borrowedGr.AddRelated(adjacentGr, GeographicRelationshipSourceAdjacentImage)
+    imageTi.AddWithRecord(borrowedGr)
+
+    fg := NewFindGroups(locationTs, imageTi.Series(), nil)
+
+    gaps, err := fg.LocationCoverageGaps()
+    log.PanicIf(err)
+
+    if len(gaps) != 1 {
+        t.Fatalf("Expected one gap: %v", gaps)
+    } else if gaps[0].ImageCount != 1 || gaps[0].FirstImage.Filepath != "image2.jpg" {
+        t.Fatalf("Gap not correct: %s", gaps[0])
+    }
+}
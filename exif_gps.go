@@ -0,0 +1,199 @@
+package geoautogroup
+
+import (
+    "bytes"
+    "errors"
+    "math"
+    "os"
+    "strings"
+
+    "io/ioutil"
+    "path/filepath"
+
+    "github.com/dsoprea/go-exif"
+    "github.com/dsoprea/go-jpeg-image-structure"
+    "github.com/dsoprea/go-logging"
+)
+
+const (
+    // gpsSecondsDenominator is the precision that we store the seconds of
+    // the coordinates at.
+    gpsSecondsDenominator = 1000
+)
+
+var (
+    ErrNotJpeg = errors.New("not a JPEG")
+)
+
+// getGpsRationals converts a coordinate to the degrees, minutes, and seconds
+// rationals of the GPS IFD. We round once, to the precision of the seconds, and
+// then split so that rounding up carries into the minutes and degrees rather
+// than producing sixty seconds.
+func getGpsRationals(value float64) []exif.Rational {
+    totalSeconds := uint64(math.Round(math.Abs(value) * 3600 * gpsSecondsDenominator))
+
+    degrees := totalSeconds / (3600 * gpsSecondsDenominator)
+    totalSeconds -= degrees * 3600 * gpsSecondsDenominator
+
+    minutes := totalSeconds / (60 * gpsSecondsDenominator)
+    seconds := totalSeconds - minutes*60*gpsSecondsDenominator
+
+    return []exif.Rational{
+        {Numerator: uint32(degrees), Denominator: 1},
+        {Numerator: uint32(minutes), Denominator: 1},
+        {Numerator: uint32(seconds), Denominator: gpsSecondsDenominator},
+    }
+}
+
+// isJpegFilepath indicates whether the file-path has a JPEG extension.
+func isJpegFilepath(imageFilepath string) bool {
+    extension := strings.ToLower(filepath.Ext(imageFilepath))
+    return extension == ".jpg" || extension == ".jpeg"
+}
+
+// WriteJpegGpsExif sets the GPS IFD of the given JPEG to the given
+// coordinates, replacing any that are already there. The new file is written
+// next to the original and then renamed over it (keeping its mode) so that the
+// original is never left half-written. Still, this should only be called on
+// copies unless the user explicitly asked for the originals to be modified.
+// Returns `ErrNotJpeg` for other kinds of images.
+func WriteJpegGpsExif(imageFilepath string, latitude, longitude float64) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    if isJpegFilepath(imageFilepath) == false {
+        return ErrNotJpeg
+    }
+
+    fi, err := os.Stat(imageFilepath)
+    log.PanicIf(err)
+
+    jmp := jpegstructure.NewJpegMediaParser()
+
+    intfc, err := jmp.ParseFile(imageFilepath)
+    log.PanicIf(err)
+
+    sl := intfc.(*jpegstructure.SegmentList)
+
+    rootIb, err := sl.ConstructExifBuilder()
+    log.PanicIf(err)
+
+    gpsIb, err := exif.GetOrCreateIbFromRootIb(rootIb, "IFD/GPSInfo")
+    log.PanicIf(err)
+
+    latitudeRef := "N"
+    if latitude < 0 {
+        latitudeRef = "S"
+    }
+
+    longitudeRef := "E"
+    if longitude < 0 {
+        longitudeRef = "W"
+    }
+
+    err = gpsIb.SetStandardWithName("GPSVersionID", []byte{2, 2, 0, 0})
+    log.PanicIf(err)
+
+    err = gpsIb.SetStandardWithName("GPSLatitudeRef", latitudeRef)
+    log.PanicIf(err)
+
+    err = gpsIb.SetStandardWithName("GPSLatitude", getGpsRationals(latitude))
+    log.PanicIf(err)
+
+    err = gpsIb.SetStandardWithName("GPSLongitudeRef", longitudeRef)
+    log.PanicIf(err)
+
+    err = gpsIb.SetStandardWithName("GPSLongitude", getGpsRationals(longitude))
+    log.PanicIf(err)
+
+    err = sl.SetExif(rootIb)
+    log.PanicIf(err)
+
+    f, err := ioutil.TempFile(filepath.Dir(imageFilepath), ".exif")
+    log.PanicIf(err)
+
+    tempFilepath := f.Name()
+
+    err = sl.Write(f)
+    if err == nil {
+        err = f.Chmod(fi.Mode())
+    }
+
+    f.Close()
+
+    if err != nil {
+        os.Remove(tempFilepath)
+        log.Panic(err)
+    }
+
+    err = os.Rename(tempFilepath, imageFilepath)
+    if err != nil {
+        os.Remove(tempFilepath)
+        log.Panic(err)
+    }
+
+    return nil
+}
+
+// getJpegImageSegments returns the segments of the JPEG other than EXIF.
+func getJpegImageSegments(imageFilepath string) (segments []*jpegstructure.Segment, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    jmp := jpegstructure.NewJpegMediaParser()
+
+    intfc, err := jmp.ParseFile(imageFilepath)
+    log.PanicIf(err)
+
+    sl := intfc.(*jpegstructure.SegmentList)
+
+    segments = make([]*jpegstructure.Segment, 0)
+    for _, s := range sl.Segments() {
+        if s.IsExif() == true {
+            continue
+        }
+
+        segments = append(segments, s)
+    }
+
+    return segments, nil
+}
+
+// JpegImageDataEqual indicates whether the two JPEGs are identical other than
+// their EXIF. This recognizes a copy that we wrote the GPS IFD into. Returns
+// `ErrNotJpeg` if either isn't a JPEG.
+func JpegImageDataEqual(imageFilepath1, imageFilepath2 string) (equal bool, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    if isJpegFilepath(imageFilepath1) == false || isJpegFilepath(imageFilepath2) == false {
+        return false, ErrNotJpeg
+    }
+
+    segments1, err := getJpegImageSegments(imageFilepath1)
+    log.PanicIf(err)
+
+    segments2, err := getJpegImageSegments(imageFilepath2)
+    log.PanicIf(err)
+
+    if len(segments1) != len(segments2) {
+        return false, nil
+    }
+
+    for i, s := range segments1 {
+        if s.MarkerId != segments2[i].MarkerId || bytes.Equal(s.Data, segments2[i].Data) == false {
+            return false, nil
+        }
+    }
+
+    return true, nil
+}
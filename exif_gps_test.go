@@ -0,0 +1,112 @@
+package geoautogroup
+
+import (
+    "math"
+    "os"
+    "path"
+    "testing"
+
+    "io/ioutil"
+
+    "github.com/dsoprea/go-exif"
+    "github.com/dsoprea/go-logging"
+)
+
+func TestWriteJpegGpsExif(t *testing.T) {
+    tempPath, err := ioutil.TempDir("", "")
+    log.PanicIf(err)
+
+    defer os.RemoveAll(tempPath)
+
+    originalFilepath := path.Join(testAssetsPath, "test_sources_path1", "20180331121807_IMG_1060.JPG")
+
+    data, err := ioutil.ReadFile(originalFilepath)
+    log.PanicIf(err)
+
+    imageFilepath := path.Join(tempPath, "image.jpg")
+
+    err = ioutil.WriteFile(imageFilepath, data, 0600)
+    log.PanicIf(err)
+
+    err = WriteJpegGpsExif(imageFilepath, -33.86785, 151.20732)
+    log.PanicIf(err)
+
+    fi, err := os.Stat(imageFilepath)
+    log.PanicIf(err)
+
+    if fi.Mode().Perm() != 0600 {
+        t.Fatalf("Mode not kept: %v", fi.Mode())
+    }
+
+    // Read the GPS IFD back.
+
+    rawExif, err := exif.SearchFileAndExtractExif(imageFilepath)
+    log.PanicIf(err)
+
+    im := exif.NewIfdMappingWithStandard()
+    ti := exif.NewTagIndex()
+
+    _, index, err := exif.Collect(im, ti, rawExif)
+    log.PanicIf(err)
+
+    gpsIfd, err := index.RootIfd.ChildWithIfdPath(exif.IfdPathStandardGps)
+    log.PanicIf(err)
+
+    gi, err := gpsIfd.GpsInfo()
+    log.PanicIf(err)
+
+    if math.Abs(gi.Latitude.Decimal()-(-33.86785)) > 0.00001 {
+        t.Fatalf("Latitude not correct: (%.6f)", gi.Latitude.Decimal())
+    } else if math.Abs(gi.Longitude.Decimal()-151.20732) > 0.00001 {
+        t.Fatalf("Longitude not correct: (%.6f)", gi.Longitude.Decimal())
+    }
+
+    // Only the EXIF changed, so this is still the same image.
+
+    equal, err := JpegImageDataEqual(originalFilepath, imageFilepath)
+    log.PanicIf(err)
+
+    if equal != true {
+        t.Fatalf("Image data expected to be equal after writing EXIF.")
+    }
+
+    otherFilepath := path.Join(testAssetsPath, "test_sources_path1", "DSC07191.JPG")
+
+    equal, err = JpegImageDataEqual(originalFilepath, otherFilepath)
+    log.PanicIf(err)
+
+    if equal != false {
+        t.Fatalf("Image data of different images expected to not be equal.")
+    }
+}
+
+func TestWriteJpegGpsExif_NotJpeg(t *testing.T) {
+    err := WriteJpegGpsExif("image.png", 0, 0)
+    if err != ErrNotJpeg {
+        t.Fatalf("Expected ErrNotJpeg: %v", err)
+    }
+}
+
+func TestGetGpsRationals_CarriesRounding(t *testing.T) {
+    // 59.9999 seconds past 41°51' rounds up to the next minute.
+    value := 41.0 + 51.0/60 + 59.9999/3600
+
+    rationals := getGpsRationals(value)
+
+    if rationals[0].Numerator != 41 || rationals[0].Denominator != 1 {
+        t.Fatalf("Degrees not correct: %v", rationals[0])
+    } else if rationals[1].Numerator != 52 || rationals[1].Denominator != 1 {
+        t.Fatalf("Minutes not correct: %v", rationals[1])
+    } else if rationals[2].Numerator != 0 {
+        t.Fatalf("Seconds not correct: %v", rationals[2])
+    }
+
+    // The same at the end of a degree.
+    value = -(41.0 + 59.0/60 + 59.9999/3600)
+
+    rationals = getGpsRationals(value)
+
+    if rationals[0].Numerator != 42 || rationals[1].Numerator != 0 || rationals[2].Numerator != 0 {
+        t.Fatalf("Rounding not carried into the degrees: %v", rationals)
+    }
+}
@@ -0,0 +1,75 @@
+package geoautogroup
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/dsoprea/go-geographic-attractor"
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-time-index"
+)
+
+const (
+    // UnknownLocationCitySourceName is the source-name used in the
+    // nearest-city keys of the groups of images without a location.
+    UnknownLocationCitySourceName = "Unknown"
+
+    // DefaultUnknownLocationLabel is the default name of the groups of images
+    // without a location.
+    DefaultUnknownLocationLabel = "Unknown location"
+)
+
+// UnknownLocationCityKey returns the nearest-city key used to group images
+// without a location.
+func UnknownLocationCityKey(label string) string {
+    return fmt.Sprintf("%s,%s", UnknownLocationCitySourceName, label)
+}
+
+// IsUnknownLocationCityKey indicates whether the nearest-city key is for a
+// group of images without a location.
+func IsUnknownLocationCityKey(nearestCityKey string) bool {
+    return strings.HasPrefix(nearestCityKey, UnknownLocationCitySourceName+",")
+}
+
+// SetUnknownLocationFallback has images that can't be matched to a location
+// grouped by time and camera-model alone under the given label rather than
+// left unassigned. An empty label disables the fallback (the default).
+func (fg *FindGroups) SetUnknownLocationFallback(label string) {
+    fg.unknownLocationLabel = label
+}
+
+// unlocated is called for images that we couldn't find a location or city
+// for. If the fallback is enabled, the image is returned for grouping.
+// Otherwise, it is recorded as unassigned.
+func (fg *FindGroups) unlocated(imageTe timeindex.TimeEntry, imageGr *geoindex.GeographicRecord, reason SkipReason) (cir currentImageRecord, grouped bool) {
+    if fg.unknownLocationLabel == "" {
+        fg.addUnassigned(imageGr, reason)
+        return currentImageRecord{}, false
+    }
+
+    nearestCityKey := UnknownLocationCityKey(fg.unknownLocationLabel)
+    if _, found := fg.nearestCityIndex[nearestCityKey]; found == false {
+        fg.nearestCityIndex[nearestCityKey] = geoattractor.CityRecord{
+            Id:   fg.unknownLocationLabel,
+            City: fg.unknownLocationLabel,
+        }
+    }
+
+    comment := fmt.Sprintf("Grouped under [%s] by time alone: %s", fg.unknownLocationLabel, reason.Description())
+    imageGr.AddComment(comment)
+
+    fields := map[string]interface{}{
+        "label":  fg.unknownLocationLabel,
+        "reason": string(reason),
+    }
+
+    fg.tracer.Debug(imageGr.Filepath, TraceStageFallback, comment, fields)
+
+    cir = currentImageRecord{
+        ImageUnixTime:    imageTe.Time,
+        GeographicRecord: imageGr,
+        NearestCityKey:   nearestCityKey,
+    }
+
+    return cir, true
+}
@@ -32,12 +32,11 @@ const (
     // being attached to the images.
     LocationMatchTimeWarnIntervalThreshold = time.Hour * 8
     LocationMatchTimeSkipIntervalThreshold = time.Hour * 10
-)
 
-const (
-    SkipReasonNoNearLocationRecord = "no matching/near location record"
-    SkipReasonNoNearCity           = "no near city"
-    SkipReasonLocationTooFar       = "matched location record too old"
+    // LocationMatchRoundingWindowDuration is the largest time duration we're
+    // allowed to search for matching location records within for a given
+    // image when the location data isn't sparse.
+    LocationMatchRoundingWindowDuration = time.Minute * 10
 )
 
 const (
@@ -48,6 +47,7 @@ const (
 // Relationship types that we might record in a `geoindex.GeographicRecord`.
 const (
     GeographicRelationshipSourceLocationRecord = "source_location_record"
+    GeographicRelationshipSourceAdjacentImage  = "source_adjacent_image"
 )
 
 var (
@@ -55,8 +55,9 @@ var (
 )
 
 type UnassignedRecord struct {
-    Geographic *geoindex.GeographicRecord
-    Reason     string
+    Geographic  *geoindex.GeographicRecord
+    Reason      SkipReason
+    Diagnostics UnassignedDiagnostics
 }
 
 type GroupKey struct {
@@ -89,7 +90,15 @@ type FindGroups struct {
     currentGroupKey      map[string]GroupKey
     currentGroup         map[string][]*geoindex.GeographicRecord
 
-    locationMatcherFn LocationMatcherFn
+    locationMatcherFn     LocationMatcherFn
+    locationMatchStrategy string
+    overrides             *ImageOverrides
+    unknownLocationLabel  string
+
+    adjacentImageWindow time.Duration
+    locatedImageTs      timeindex.TimeSlice
+
+    tracer *ImageTracer
 
     bufferedGroups *iterativeGroupBuffers
 }
@@ -115,6 +124,7 @@ func NewFindGroups(locationTs timeindex.TimeSlice, imageTs timeindex.TimeSlice,
     }
 
     fg.locationMatcherFn = fg.findLocationByTimeBestGuess
+    fg.locationMatchStrategy = LocationMatchStrategyBestGuess
 
     return fg
 }
@@ -135,6 +145,27 @@ func (fg *FindGroups) SetLocationMatchStrategy(strategy string) {
     } else {
         log.Panicf("location-match strategy [%s] not valid", strategy)
     }
+
+    fg.locationMatchStrategy = strategy
+}
+
+// SetImageOverrides has images whose override assigns a group name grouped
+// under that name rather than by location. The other parts of the overrides
+// are expected to have already been applied when the images were loaded.
+func (fg *FindGroups) SetImageOverrides(ios *ImageOverrides) {
+    fg.overrides = ios
+}
+
+// SetImageTracer has trace events recorded for the images that the tracer
+// selects.
+func (fg *FindGroups) SetImageTracer(it *ImageTracer) {
+    fg.tracer = it
+    fg.bufferedGroups.tracer = it
+}
+
+// ImageTracer returns the tracer or nil if there isn't one.
+func (fg *FindGroups) ImageTracer() *ImageTracer {
+    return fg.tracer
 }
 
 // NearestCityIndex returns all of the cities that we've grouped the images by
@@ -147,15 +178,19 @@ func (fg *FindGroups) UnassignedRecords() []UnassignedRecord {
     return fg.unassignedRecords
 }
 
-func (fg *FindGroups) addUnassigned(gr *geoindex.GeographicRecord, reason string) {
+func (fg *FindGroups) addUnassigned(gr *geoindex.GeographicRecord, reason SkipReason) {
+    ud, err := fg.getUnassignedDiagnostics(gr)
+    log.PanicIf(err)
+
     ur := UnassignedRecord{
-        Geographic: gr,
-        Reason:     reason,
+        Geographic:  gr,
+        Reason:      reason,
+        Diagnostics: ud,
     }
 
     fg.unassignedRecords = append(fg.unassignedRecords, ur)
 
-    findGroupsLogger.Warningf(nil, "Skipping %s: %s", gr, reason)
+    findGroupsLogger.Warningf(nil, "Skipping %s: %s", gr, reason.Description())
 }
 
 // findLocationByTime returns the nearest location record to the timestamp in
@@ -171,9 +206,7 @@ func (fg *FindGroups) findLocationByTimeBestGuess(imageTe timeindex.TimeEntry) (
         }
     }()
 
-    // DefaultRoundingWindowDuration is the largest time duration we're allowed
-    // to search for matching location records within for a given image.
-    roundingWindowDuration := time.Minute * 10
+    roundingWindowDuration := LocationMatchRoundingWindowDuration
 
     locationIndexTs := fg.locationTs
 
@@ -347,6 +380,71 @@ type currentImageRecord struct {
     NearestCityKey   string
 }
 
+// assignLocationRecord matches the image to a location record and assigns its
+// location to the image. If there is no usable location record, the image is
+// not modified and the reason is returned.
+func (fg *FindGroups) assignLocationRecord(imageTe timeindex.TimeEntry, imageGr *geoindex.GeographicRecord) (skipReason SkipReason, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    // TODO(dustin): Note that we match for a location based on the timestamp in the index but that we group based on the timestamp in the image. The original was an earlier design but going with the last will likely always be at least identical accuracy and the design is a little more intuitive. Refactor the location-matching to use the image time.
+    matchedTe, err := fg.locationMatcherFn(imageTe)
+    if err != nil {
+        if log.Is(err, ErrNoNearLocationRecord) == true {
+            return SkipReasonNoNearLocationRecord, nil
+        }
+
+        log.Panic(err)
+    }
+
+    locationItem := matchedTe.Items[0]
+    locationGr := locationItem.(*geoindex.GeographicRecord)
+
+    timeDelta := imageGr.Timestamp.Sub(locationGr.Timestamp)
+
+    fields := map[string]interface{}{
+        "location_filepath":  locationGr.Filepath,
+        "location_timestamp": locationGr.Timestamp,
+        "time_delta":         timeDelta.String(),
+        "strategy":           fg.locationMatchStrategy,
+    }
+
+    fg.tracer.Debug(imageGr.Filepath, TraceStageLocation, fmt.Sprintf("Matched location: %s [%v] -> %s [%v] TIME-DELTA=[%v]", imageGr, imageGr.Timestamp, locationGr, locationGr.Timestamp, timeDelta), fields)
+
+    if timeDelta > LocationMatchTimeWarnIntervalThreshold {
+        if timeDelta < LocationMatchTimeSkipIntervalThreshold {
+            fg.tracer.Warning(imageGr.Filepath, TraceStageLocation, fmt.Sprintf("Image [%s] time [%v] is very far after the time of location file [%s] record time [%v]: [%v]", imageGr.Filepath, imageGr.Timestamp, locationGr.Filepath, locationGr.Timestamp, timeDelta), fields)
+        } else {
+            fg.tracer.Warning(imageGr.Filepath, TraceStageLocation, fmt.Sprintf("Image [%s] time [%v] is too far after the time of location file [%s] record time [%v] and will be skipped: [%v]", imageGr.Filepath, imageGr.Timestamp, locationGr.Filepath, locationGr.Timestamp, timeDelta), fields)
+        }
+
+        return SkipReasonLocationTooFar, nil
+    }
+
+    // The location index should exclusively be loaded with geographic data.
+    // This should never happen.
+    if locationGr.HasGeographic == false {
+        log.Panicf("location record indicates no geographic data; this should never happen")
+    }
+
+    imageGr.Latitude = locationGr.Latitude
+    imageGr.Longitude = locationGr.Longitude
+    imageGr.S2CellId = locationGr.S2CellId
+
+    cell := s2.CellID(locationGr.S2CellId)
+
+    comment := fmt.Sprintf("Updated geographic info from location record with filename [%s], timestamp [%s], and cell [%s]", path.Base(locationGr.Filepath), locationGr.Timestamp.Format(time.RFC3339), cell.ToToken())
+    imageGr.AddComment(comment)
+    imageGr.AddRelated(locationGr, GeographicRelationshipSourceLocationRecord)
+
+    imageGr.HasGeographic = true
+
+    return "", nil
+}
+
 // getCurrentPositionImages returns the images as the current position in the
 // image time-series index.
 func (fg *FindGroups) getCurrentPositionImages() (outputRecords []currentImageRecord, err error) {
@@ -363,55 +461,44 @@ func (fg *FindGroups) getCurrentPositionImages() (outputRecords []currentImageRe
 
         imageGr := item.(*geoindex.GeographicRecord)
 
-        PushDebugTrace(imageGr.Filepath, fmt.Sprintf("Original image record from index: %s HAS-GEOGRAPHIC=[%v]", imageGr, imageGr.HasGeographic))
-
-        if imageGr.HasGeographic == false {
-            // TODO(dustin): Note that we match for a location based on the timestamp in the index but that we group based on the timestamp in the image. The original was an earlier design but going with the last will likely always be at least identical accuracy and the design is a little more intuitive. Refactor the location-matching to use the image time.
-            matchedTe, err := fg.locationMatcherFn(imageTe)
-            if err != nil {
-                if log.Is(err, ErrNoNearLocationRecord) == true {
-                    fg.addUnassigned(imageGr, SkipReasonNoNearLocationRecord)
-                    continue
-                }
-
-                log.Panic(err)
-            }
+        fg.tracer.Debug(imageGr.Filepath, TraceStageGroup, fmt.Sprintf("Original image record from index: %s HAS-GEOGRAPHIC=[%v]", imageGr, imageGr.HasGeographic), nil)
 
-            locationItem := matchedTe.Items[0]
-            locationGr := locationItem.(*geoindex.GeographicRecord)
+        if fg.overrides != nil {
+            iov, err := fg.overrides.Lookup(imageGr.Filepath)
+            log.PanicIf(err)
 
-            timeDelta := imageGr.Timestamp.Sub(locationGr.Timestamp)
+            if iov != nil && iov.HasGroup() == true {
+                // The group was given explicitly so we don't need a location.
 
-            PushDebugTrace(imageGr.Filepath, fmt.Sprintf("Matched location: %s [%v] -> %s [%v] TIME-DELTA=[%v]", imageGr, imageGr.Timestamp, locationGr, locationGr.Timestamp, timeDelta))
+                nearestCityKey := OverrideCityKey(iov.Group)
+                if _, found := fg.nearestCityIndex[nearestCityKey]; found == false {
+                    fg.nearestCityIndex[nearestCityKey] = overrideCityRecord(iov.Group, imageGr)
+                }
 
-            if timeDelta > LocationMatchTimeWarnIntervalThreshold {
-                if timeDelta < LocationMatchTimeSkipIntervalThreshold {
-                    PushWarningTrace(imageGr.Filepath, fmt.Sprintf("Image [%s] time [%v] is very far after the time of location file [%s] record time [%v]: [%v]", imageGr.Filepath, imageGr.Timestamp, locationGr.Filepath, locationGr.Timestamp, timeDelta))
-                } else {
-                    PushWarningTrace(imageGr.Filepath, fmt.Sprintf("Image [%s] time [%v] is too far after the time of location file [%s] record time [%v] and will be skipped: [%v]", imageGr.Filepath, imageGr.Timestamp, locationGr.Filepath, locationGr.Timestamp, timeDelta))
+                cir := currentImageRecord{
+                    ImageUnixTime:    imageTe.Time,
+                    GeographicRecord: imageGr,
+                    NearestCityKey:   nearestCityKey,
                 }
 
-                fg.addUnassigned(imageGr, SkipReasonLocationTooFar)
-                continue
-            }
+                fg.tracer.Debug(imageGr.Filepath, TraceStageOverride, fmt.Sprintf("Grouped by override: TIMESTAMP=[%v] GROUP=[%s]", cir.ImageUnixTime, iov.Group), map[string]interface{}{"group": iov.Group})
 
-            // The location index should exclusively be loaded with
-            // geographic data. This should never happen.
-            if locationGr.HasGeographic == false {
-                log.Panicf("location record indicates no geographic data; this should never happen")
+                outputRecords = append(outputRecords, cir)
+                continue
             }
+        }
 
-            imageGr.Latitude = locationGr.Latitude
-            imageGr.Longitude = locationGr.Longitude
-            imageGr.S2CellId = locationGr.S2CellId
-
-            cell := s2.CellID(locationGr.S2CellId)
+        if imageGr.HasGeographic == false {
+            skipReason, err := fg.assignLocationRecord(imageTe, imageGr)
+            log.PanicIf(err)
 
-            comment := fmt.Sprintf("Updated geographic info from location record with filename [%s], timestamp [%s], and cell [%s]", path.Base(locationGr.Filepath), locationGr.Timestamp.Format(time.RFC3339), cell.ToToken())
-            imageGr.AddComment(comment)
-            imageGr.AddRelated(locationGr, GeographicRelationshipSourceLocationRecord)
+            if skipReason != "" && fg.snapToAdjacentImage(imageGr) == false {
+                if cir, grouped := fg.unlocated(imageTe, imageGr, skipReason); grouped == true {
+                    outputRecords = append(outputRecords, cir)
+                }
 
-            imageGr.HasGeographic = true
+                continue
+            }
         }
 
         // Now, we'll construct the group that this image should be a part
@@ -426,7 +513,10 @@ func (fg *FindGroups) getCurrentPositionImages() (outputRecords []currentImageRe
 
         if err != nil {
             if log.Is(err, geoattractorindex.ErrNoNearestCity) == true {
-                fg.addUnassigned(imageGr, SkipReasonNoNearCity)
+                if cir, grouped := fg.unlocated(imageTe, imageGr, SkipReasonNoNearCity); grouped == true {
+                    outputRecords = append(outputRecords, cir)
+                }
+
                 continue
             }
 
@@ -436,13 +526,25 @@ func (fg *FindGroups) getCurrentPositionImages() (outputRecords []currentImageRe
         nearestCityKey := fmt.Sprintf("%s,%s", sourceName, cr.Id)
         fg.nearestCityIndex[nearestCityKey] = cr
 
+        if fg.tracer.IsTraced(imageGr.Filepath) == true {
+            distanceKm := getDistanceKm(imageGr.Latitude, imageGr.Longitude, cr.Latitude, cr.Longitude)
+
+            fields := map[string]interface{}{
+                "nearest_city_key": nearestCityKey,
+                "city":             cr.CityAndProvinceState(),
+                "distance_km":      distanceKm,
+            }
+
+            fg.tracer.Debug(imageGr.Filepath, TraceStageCity, fmt.Sprintf("Nearest city is [%s] at (%.6f, %.6f): %.3f km", cr.CityAndProvinceState(), cr.Latitude, cr.Longitude, distanceKm), fields)
+        }
+
         cir := currentImageRecord{
             ImageUnixTime:    imageTe.Time,
             GeographicRecord: imageGr,
             NearestCityKey:   nearestCityKey,
         }
 
-        PushDebugTrace(imageGr.Filepath, fmt.Sprintf("Final image collected/compiled from indices: TIMESTAMP=[%v] NEAREST-CITY=[%s]", cir.ImageUnixTime, cir.NearestCityKey))
+        fg.tracer.Debug(imageGr.Filepath, TraceStageGroup, fmt.Sprintf("Final image collected/compiled from indices: TIMESTAMP=[%v] NEAREST-CITY=[%s]", cir.ImageUnixTime, cir.NearestCityKey), map[string]interface{}{"nearest_city_key": cir.NearestCityKey})
 
         outputRecords = append(outputRecords, cir)
     }
@@ -42,7 +42,7 @@ func TestFindGroups_AddUnassigned(t *testing.T) {
         S2CellId: 123,
     }
 
-    reason := "some reason"
+    reason := SkipReasonNoNearCity
 
     fg.addUnassigned(gr, reason)
 
@@ -1185,3 +1185,119 @@ func ExampleFindGroups_FindNext() {
 }
 
 // TODO(dustin): !! Add test for `findLocationByTimeWithSparseLocations`.
+
+func TestFindGroups_FindNext_UnknownLocationFallback(t *testing.T) {
+    locationTi := geoindex.NewTimeIndex()
+
+    gr := geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc.Add(oneDay*1), true, chicagoCoordinates[0], chicagoCoordinates[1], nil)
+    locationTi.AddWithRecord(gr)
+
+    imageTi := geoindex.NewTimeIndex()
+
+    im := geoindex.ImageMetadata{
+        CameraModel: "some model",
+    }
+
+    gr = geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image1.jpg", epochUtc.Add(oneDay*1+time.Minute*1), false, 0, 0, im)
+    imageTi.AddWithRecord(gr)
+
+    // Nowhere near any location records.
+    gr = geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image2.jpg", epochUtc.Add(oneDay*3), false, 0, 0, im)
+    imageTi.AddWithRecord(gr)
+
+    gr = geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image3.jpg", epochUtc.Add(oneDay*3+time.Minute*1), false, 0, 0, im)
+    imageTi.AddWithRecord(gr)
+
+    ci := getTestCityIndex()
+
+    fg := NewFindGroups(locationTi.Series(), imageTi.Series(), ci)
+    fg.SetUnknownLocationFallback(DefaultUnknownLocationLabel)
+
+    finishedGroupKey, finishedGroup, err := fg.FindNext()
+    log.PanicIf(err)
+
+    if IsUnknownLocationCityKey(finishedGroupKey.NearestCityKey) == true || len(finishedGroup) != 1 {
+        t.Fatalf("First group not correct: %s (%d)", finishedGroupKey, len(finishedGroup))
+    }
+
+    finishedGroupKey, finishedGroup, err = fg.FindNext()
+    log.PanicIf(err)
+
+    if finishedGroupKey.NearestCityKey != UnknownLocationCityKey(DefaultUnknownLocationLabel) {
+        t.Fatalf("Second group not for unknown location: %s", finishedGroupKey)
+    } else if len(finishedGroup) != 2 {
+        t.Fatalf("Second group should have two images: (%d)", len(finishedGroup))
+    }
+
+    cr := fg.NearestCityIndex()[finishedGroupKey.NearestCityKey]
+    if cr.City != DefaultUnknownLocationLabel {
+        t.Fatalf("City record for unknown location not correct: [%s]", cr.City)
+    }
+
+    _, _, err = fg.FindNext()
+    if err != ErrNoMoreGroups {
+        t.Fatalf("Expected no more groups: %v", err)
+    }
+
+    if len(fg.UnassignedRecords()) != 0 {
+        t.Fatalf("Expected no unassigned records: (%d)", len(fg.UnassignedRecords()))
+    }
+}
+
+func TestFindGroups_FindNext_AdjacentImageWindow(t *testing.T) {
+    locationTi := geoindex.NewTimeIndex()
+
+    gr := geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc, true, chicagoCoordinates[0], chicagoCoordinates[1], nil)
+    locationTi.AddWithRecord(gr)
+
+    imageTi := geoindex.NewTimeIndex()
+
+    phoneIm := geoindex.ImageMetadata{
+        CameraModel: "phone",
+    }
+
+    dslrIm := geoindex.ImageMetadata{
+        CameraModel: "dslr",
+    }
+
+    // Has its own location but there's no location data nearby.
+    phoneGr := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "phone1.jpg", epochUtc.Add(oneDay*2), true, detroitCoordinates[0], detroitCoordinates[1], phoneIm)
+    imageTi.AddWithRecord(phoneGr)
+
+    // Within the window.
+    dslrGr1 := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "dslr1.jpg", epochUtc.Add(oneDay*2+time.Minute*2), false, 0, 0, dslrIm)
+    imageTi.AddWithRecord(dslrGr1)
+
+    // Outside of the window.
+    dslrGr2 := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "dslr2.jpg", epochUtc.Add(oneDay*2+time.Hour*1), false, 0, 0, dslrIm)
+    imageTi.AddWithRecord(dslrGr2)
+
+    ci := getTestCityIndex()
+
+    fg := NewFindGroups(locationTi.Series(), imageTi.Series(), ci)
+    fg.SetAdjacentImageWindow(time.Minute * 5)
+
+    for {
+        _, _, err := fg.FindNext()
+        if err == ErrNoMoreGroups {
+            break
+        }
+
+        log.PanicIf(err)
+    }
+
+    if dslrGr1.HasGeographic == false || dslrGr1.Latitude != detroitCoordinates[0] || dslrGr1.Longitude != detroitCoordinates[1] {
+        t.Fatalf("Image within the window did not borrow the location: %s", dslrGr1)
+    }
+
+    encoded := dslrGr1.Encode()
+    relationships := encoded["relationships"].(map[string][]map[string]interface{})
+    if len(relationships[GeographicRelationshipSourceAdjacentImage]) != 1 {
+        t.Fatalf("Adjacent-image relationship not recorded: %v", relationships)
+    }
+
+    unassignedRecords := fg.UnassignedRecords()
+    if len(unassignedRecords) != 1 || unassignedRecords[0].Geographic != dslrGr2 {
+        t.Fatalf("Expected only the image outside of the window to be unassigned: %v", unassignedRecords)
+    }
+}
@@ -0,0 +1,330 @@
+package geoautogroup
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "sort"
+    "time"
+
+    "encoding/json"
+
+    "github.com/dsoprea/go-geographic-attractor"
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+)
+
+const (
+    // GroupsDocumentSchemaVersion is the version of the groups document that
+    // we write. Increment this whenever the shape changes and update
+    // "schema/groups.schema.json" to match.
+    GroupsDocumentSchemaVersion = 1
+)
+
+var (
+    ErrGroupsDocumentVersionNotSupported = errors.New("groups document schema-version not supported")
+)
+
+// GroupsDocumentRecord is one grouped image.
+type GroupsDocumentRecord struct {
+    Filepath      string    `json:"filepath"`
+    Timestamp     time.Time `json:"timestamp"`
+    HasGeographic bool      `json:"has_geographic"`
+    Latitude      float64   `json:"latitude"`
+    Longitude     float64   `json:"longitude"`
+    S2CellId      uint64    `json:"s2_cell_id"`
+    CameraModel   string    `json:"camera_model"`
+
+    // LocationMethod is one of the `LocationMethod*` constants.
+    LocationMethod string `json:"location_method"`
+
+    // Relationships are the indices of the records in the group's
+    // `LocationSources` that the location came from, keyed by relationship
+    // type.
+    Relationships map[string][]int `json:"relationships,omitempty"`
+
+    // MergeNotes describe how the image's original group was merged into
+    // this one.
+    MergeNotes []string `json:"merge_notes,omitempty"`
+}
+
+// GroupsDocumentLocationSource is a record that one or more images in the group
+// took their location from.
+type GroupsDocumentLocationSource struct {
+    Filepath  string    `json:"filepath"`
+    Timestamp time.Time `json:"timestamp"`
+    Latitude  float64   `json:"latitude"`
+    Longitude float64   `json:"longitude"`
+}
+
+// GroupsDocumentGroup is one finished group.
+type GroupsDocumentGroup struct {
+    GroupKey  GroupKey `json:"group_key"`
+    KeyPhrase string   `json:"key_phrase"`
+    Trip      string   `json:"trip,omitempty"`
+
+    Records []GroupsDocumentRecord `json:"records"`
+
+    // LocationSources are the distinct records that the images took their
+    // locations from. These can't be keyed by file-path since every point
+    // from a data file has the same one.
+    LocationSources []GroupsDocumentLocationSource `json:"location_sources"`
+}
+
+// GroupsDocumentCity is one city that groups were assigned to.
+type GroupsDocumentCity struct {
+    Id                   string  `json:"id"`
+    City                 string  `json:"city"`
+    ProvinceState        string  `json:"province_state,omitempty"`
+    CityAndProvinceState string  `json:"city_and_province_state"`
+    Country              string  `json:"country"`
+    Latitude             float64 `json:"latitude"`
+    Longitude            float64 `json:"longitude"`
+}
+
+// GroupsDocument is the "groups.json" output of a grouping run. Its shape is
+// described by "schema/groups.schema.json".
+type GroupsDocument struct {
+    SchemaVersion int                   `json:"schema_version"`
+    Groups        []GroupsDocumentGroup `json:"groups"`
+
+    // CityIndex is keyed by the `NearestCityKey` of the group keys.
+    CityIndex map[string]GroupsDocumentCity `json:"city_index"`
+}
+
+// NewGroupsDocument returns a document with the cities that have been
+// encountered so far and no groups.
+func NewGroupsDocument(fg *FindGroups) *GroupsDocument {
+    cityIndex := make(map[string]GroupsDocumentCity)
+    for key, cr := range fg.NearestCityIndex() {
+        cityIndex[key] = GroupsDocumentCity{
+            Id:                   cr.Id,
+            City:                 cr.City,
+            ProvinceState:        cr.ProvinceState,
+            CityAndProvinceState: cr.CityAndProvinceState(),
+            Country:              cr.Country,
+            Latitude:             cr.Latitude,
+            Longitude:            cr.Longitude,
+        }
+    }
+
+    return &GroupsDocument{
+        SchemaVersion: GroupsDocumentSchemaVersion,
+        Groups:        make([]GroupsDocumentGroup, 0),
+        CityIndex:     cityIndex,
+    }
+}
+
+// key distinguishes the source from other points in the same file.
+func (gdls GroupsDocumentLocationSource) key() string {
+    return fmt.Sprintf("%s|%s|%f|%f", gdls.Filepath, gdls.Timestamp.UTC().Format(time.RFC3339Nano), gdls.Latitude, gdls.Longitude)
+}
+
+// decodeLocationSource returns the source record from its encoded form, which
+// is all that the relationships give us.
+func decodeLocationSource(encoded map[string]interface{}) (gdls GroupsDocumentLocationSource) {
+    gdls.Filepath, _ = encoded["filepath"].(string)
+    gdls.Latitude, _ = encoded["latitude"].(float64)
+    gdls.Longitude, _ = encoded["longitude"].(float64)
+
+    switch timestamp := encoded["timestamp"].(type) {
+    case time.Time:
+        gdls.Timestamp = timestamp
+    case string:
+        gdls.Timestamp, _ = time.Parse(time.RFC3339, timestamp)
+    }
+
+    return gdls
+}
+
+// NewGroupsDocumentGroup describes a finished group for the groups document.
+// `gr` provides the merge notes and may be nil.
+func (fg *FindGroups) NewGroupsDocumentGroup(gr *GroupsReducer, groupKey GroupKey, records []*geoindex.GeographicRecord, trip string) (gdg GroupsDocumentGroup, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    gdg = GroupsDocumentGroup{
+        GroupKey:        groupKey,
+        KeyPhrase:       groupKey.KeyPhrase(),
+        Trip:            trip,
+        Records:         make([]GroupsDocumentRecord, len(records)),
+        LocationSources: make([]GroupsDocumentLocationSource, 0),
+    }
+
+    // Images often share a source record, so only store each once.
+    sourceIndices := make(map[string]int)
+
+    for i, imageGr := range records {
+        method, err := fg.LocationMethod(imageGr)
+        log.PanicIf(err)
+
+        gdr := GroupsDocumentRecord{
+            Filepath:       imageGr.Filepath,
+            Timestamp:      imageGr.Timestamp,
+            HasGeographic:  imageGr.HasGeographic,
+            Latitude:       imageGr.Latitude,
+            Longitude:      imageGr.Longitude,
+            S2CellId:       imageGr.S2CellId,
+            LocationMethod: method,
+        }
+
+        if im, ok := imageGr.Metadata.(geoindex.ImageMetadata); ok == true {
+            gdr.CameraModel = im.CameraModel
+        }
+
+        if gr != nil {
+            gdr.MergeNotes = gr.MergeNotes(imageGr.Filepath)
+        }
+
+        // Relocate relationships to reduce duplication and clutter.
+
+        encodedRelationships := imageGr.Encode()["relationships"].(map[string][]map[string]interface{})
+        if len(encodedRelationships) > 0 {
+            gdr.Relationships = make(map[string][]int)
+
+            for relationshipType, encodedGrList := range encodedRelationships {
+                indices := make([]int, len(encodedGrList))
+                for j, encodedGr := range encodedGrList {
+                    gdls := decodeLocationSource(encodedGr)
+
+                    key := gdls.key()
+
+                    index, found := sourceIndices[key]
+                    if found == false {
+                        index = len(gdg.LocationSources)
+                        sourceIndices[key] = index
+
+                        gdg.LocationSources = append(gdg.LocationSources, gdls)
+                    }
+
+                    indices[j] = index
+                }
+
+                gdr.Relationships[relationshipType] = indices
+            }
+        }
+
+        gdg.Records[i] = gdr
+    }
+
+    return gdg, nil
+}
+
+// GeographicRecord returns the image as a record. Only what the document
+// stores is restored; the metadata only has the camera-model.
+func (gdr GroupsDocumentRecord) GeographicRecord() *geoindex.GeographicRecord {
+    im := geoindex.ImageMetadata{
+        CameraModel: gdr.CameraModel,
+    }
+
+    gr := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, gdr.Filepath, gdr.Timestamp, gdr.HasGeographic, gdr.Latitude, gdr.Longitude, im)
+    gr.S2CellId = gdr.S2CellId
+
+    return gr
+}
+
+// Track returns the location records that images in the group were matched
+// to, in chronological order. Unlike `FindGroups.LocationRecordsBetween`, this
+// only has the records that were actually used.
+func (gdg GroupsDocumentGroup) Track() []*geoindex.GeographicRecord {
+    indices := make(map[int]struct{})
+    for _, gdr := range gdg.Records {
+        for _, index := range gdr.Relationships[GeographicRelationshipSourceLocationRecord] {
+            indices[index] = struct{}{}
+        }
+    }
+
+    track := make(locationSourcesByTime, 0, len(indices))
+    for index, _ := range indices {
+        if index < 0 || index >= len(gdg.LocationSources) {
+            continue
+        }
+
+        track = append(track, gdg.LocationSources[index])
+    }
+
+    sort.Sort(track)
+
+    records := make([]*geoindex.GeographicRecord, len(track))
+    for i, gdls := range track {
+        records[i] = geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, gdls.Filepath, gdls.Timestamp, true, gdls.Latitude, gdls.Longitude, nil)
+    }
+
+    return records
+}
+
+type locationSourcesByTime []GroupsDocumentLocationSource
+
+func (lsbt locationSourcesByTime) Len() int {
+    return len(lsbt)
+}
+
+func (lsbt locationSourcesByTime) Less(i, j int) bool {
+    return lsbt[i].Timestamp.Before(lsbt[j].Timestamp)
+}
+
+func (lsbt locationSourcesByTime) Swap(i, j int) {
+    lsbt[i], lsbt[j] = lsbt[j], lsbt[i]
+}
+
+// NearestCityIndex returns the city index as city records, the same as
+// `FindGroups.NearestCityIndex`.
+func (gd *GroupsDocument) NearestCityIndex() map[string]geoattractor.CityRecord {
+    nearestCityIndex := make(map[string]geoattractor.CityRecord, len(gd.CityIndex))
+    for key, gdc := range gd.CityIndex {
+        nearestCityIndex[key] = geoattractor.CityRecord{
+            Id:            gdc.Id,
+            City:          gdc.City,
+            ProvinceState: gdc.ProvinceState,
+            Country:       gdc.Country,
+            Latitude:      gdc.Latitude,
+            Longitude:     gdc.Longitude,
+        }
+    }
+
+    return nearestCityIndex
+}
+
+// Write writes the document as indented JSON.
+func (gd *GroupsDocument) Write(w io.Writer) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    e := json.NewEncoder(w)
+    e.SetIndent("", "  ")
+
+    err = e.Encode(gd)
+    log.PanicIf(err)
+
+    return nil
+}
+
+// ReadGroupsDocument reads a "groups.json" back in. Documents written before
+// the schema was versioned, or by a newer version than we understand, return
+// `ErrGroupsDocumentVersionNotSupported`.
+func ReadGroupsDocument(r io.Reader) (gd *GroupsDocument, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    gd = new(GroupsDocument)
+
+    d := json.NewDecoder(r)
+
+    err = d.Decode(gd)
+    log.PanicIf(err)
+
+    if gd.SchemaVersion < 1 || gd.SchemaVersion > GroupsDocumentSchemaVersion {
+        return nil, ErrGroupsDocumentVersionNotSupported
+    }
+
+    return gd, nil
+}
@@ -0,0 +1,202 @@
+package geoautogroup
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/dsoprea/go-geographic-attractor"
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+)
+
+func TestGroupsDocument_RoundTrip(t *testing.T) {
+    fg := NewFindGroups(getTestLocationTs(), nil, nil)
+
+    fg.nearestCityIndex["GeoNames,4887398"] = geoattractor.CityRecord{
+        Id:        "4887398",
+        City:      "Chicago",
+        Country:   "United States",
+        Latitude:  chicagoCoordinates[0],
+        Longitude: chicagoCoordinates[1],
+    }
+
+    im := geoindex.ImageMetadata{
+        CameraModel: "phone",
+    }
+
+    gr1 := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "1.jpg", epochUtc, true, chicagoCoordinates[0], chicagoCoordinates[1], im)
+    gr2 := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "2.jpg", epochUtc.Add(time.Minute), false, 0, 0, im)
+
+    groupKey := GroupKey{
+        TimeKey:        epochUtc,
+        NearestCityKey: "GeoNames,4887398",
+        CameraModel:    "phone",
+    }
+
+    gd := NewGroupsDocument(fg)
+
+    gdg, err := fg.NewGroupsDocumentGroup(nil, groupKey, []*geoindex.GeographicRecord{gr1, gr2}, "some trip")
+    log.PanicIf(err)
+
+    gd.Groups = append(gd.Groups, gdg)
+
+    b := new(bytes.Buffer)
+
+    err = gd.Write(b)
+    log.PanicIf(err)
+
+    if strings.Contains(b.String(), "\"schema_version\": 1") == false {
+        t.Fatalf("Schema-version not written:\n%s", b.String())
+    }
+
+    recovered, err := ReadGroupsDocument(b)
+    log.PanicIf(err)
+
+    if len(recovered.Groups) != 1 {
+        t.Fatalf("Expected one group: (%d)", len(recovered.Groups))
+    }
+
+    recoveredGroup := recovered.Groups[0]
+
+    if recoveredGroup.GroupKey.NearestCityKey != groupKey.NearestCityKey || recoveredGroup.GroupKey.TimeKey.Equal(epochUtc) == false {
+        t.Fatalf("Group key not correct: %v", recoveredGroup.GroupKey)
+    } else if recoveredGroup.KeyPhrase != groupKey.KeyPhrase() {
+        t.Fatalf("Key-phrase not correct: [%s]", recoveredGroup.KeyPhrase)
+    } else if recoveredGroup.Trip != "some trip" {
+        t.Fatalf("Trip not correct: [%s]", recoveredGroup.Trip)
+    } else if len(recoveredGroup.Records) != 2 {
+        t.Fatalf("Expected two records: (%d)", len(recoveredGroup.Records))
+    }
+
+    gdr1 := recoveredGroup.Records[0]
+    gdr2 := recoveredGroup.Records[1]
+
+    if gdr1.Filepath != "1.jpg" || gdr1.LocationMethod != LocationMethodNative || gdr1.CameraModel != "phone" || gdr1.Latitude != chicagoCoordinates[0] {
+        t.Fatalf("First record not correct: %v", gdr1)
+    } else if gdr2.Filepath != "2.jpg" || gdr2.LocationMethod != LocationMethodNone || gdr2.HasGeographic != false {
+        t.Fatalf("Second record not correct: %v", gdr2)
+    } else if recovered.CityIndex["GeoNames,4887398"].City != "Chicago" {
+        t.Fatalf("City index not correct: %v", recovered.CityIndex)
+    }
+}
+
+func TestReadGroupsDocument_VersionNotSupported(t *testing.T) {
+    for _, raw := range []string{`{"groups": []}`, `{"schema_version": 999, "groups": []}`} {
+        _, err := ReadGroupsDocument(strings.NewReader(raw))
+        if err != ErrGroupsDocumentVersionNotSupported {
+            t.Fatalf("Expected version error for [%s]: %v", raw, err)
+        }
+    }
+}
+
+func TestGroupsDocumentGroup_Track(t *testing.T) {
+    gdg := GroupsDocumentGroup{
+        Records: []GroupsDocumentRecord{
+            {
+                Filepath: "2.jpg",
+                Relationships: map[string][]int{
+                    GeographicRelationshipSourceLocationRecord: []int{1},
+                },
+            },
+            {
+                Filepath: "1.jpg",
+                Relationships: map[string][]int{
+                    GeographicRelationshipSourceLocationRecord: []int{0},
+                },
+            },
+            {
+                Filepath: "3.jpg",
+                Relationships: map[string][]int{
+                    GeographicRelationshipSourceAdjacentImage: []int{2},
+                },
+            },
+        },
+        LocationSources: []GroupsDocumentLocationSource{
+            {Filepath: "track.gpx", Timestamp: epochUtc.Add(time.Minute)},
+            {Filepath: "track.gpx", Timestamp: epochUtc},
+            {Filepath: "2.jpg", Timestamp: epochUtc.Add(time.Minute)},
+        },
+    }
+
+    track := gdg.Track()
+
+    if len(track) != 2 {
+        t.Fatalf("Expected two track records: (%d)", len(track))
+    } else if track[0].Timestamp.Equal(epochUtc) == false || track[1].Timestamp.Equal(epochUtc.Add(time.Minute)) == false {
+        t.Fatalf("Track not correct or not ordered: [%s] [%s]", track[0].Timestamp, track[1].Timestamp)
+    }
+}
+
+func TestNewGroupsDocumentGroup_SharedSourceFilepath(t *testing.T) {
+    fg := NewFindGroups(getTestLocationTs(), nil, nil)
+
+    im := geoindex.ImageMetadata{
+        CameraModel: "phone",
+    }
+
+    // Every point from a data file has the same file-path.
+
+    locationGr1 := geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "track.gpx", epochUtc, true, chicagoCoordinates[0], chicagoCoordinates[1], nil)
+    locationGr2 := geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "track.gpx", epochUtc.Add(time.Hour), true, chicagoCoordinates[0]+1, chicagoCoordinates[1]+1, nil)
+
+    gr1 := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "1.jpg", epochUtc, true, chicagoCoordinates[0], chicagoCoordinates[1], im)
+    gr1.AddRelated(locationGr1, GeographicRelationshipSourceLocationRecord)
+
+    gr2 := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "2.jpg", epochUtc.Add(time.Minute), true, chicagoCoordinates[0], chicagoCoordinates[1], im)
+    gr2.AddRelated(locationGr1, GeographicRelationshipSourceLocationRecord)
+
+    gr3 := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "3.jpg", epochUtc.Add(time.Hour), true, chicagoCoordinates[0]+1, chicagoCoordinates[1]+1, im)
+    gr3.AddRelated(locationGr2, GeographicRelationshipSourceLocationRecord)
+
+    groupKey := GroupKey{
+        TimeKey:        epochUtc,
+        NearestCityKey: "GeoNames,4887398",
+        CameraModel:    "phone",
+    }
+
+    gdg, err := fg.NewGroupsDocumentGroup(nil, groupKey, []*geoindex.GeographicRecord{gr1, gr2, gr3}, "")
+    log.PanicIf(err)
+
+    // Write it and read it back so that we check what a reader would see.
+
+    gd := NewGroupsDocument(fg)
+    gd.Groups = append(gd.Groups, gdg)
+
+    b := new(bytes.Buffer)
+
+    err = gd.Write(b)
+    log.PanicIf(err)
+
+    recovered, err := ReadGroupsDocument(b)
+    log.PanicIf(err)
+
+    recoveredGroup := recovered.Groups[0]
+
+    if len(recoveredGroup.LocationSources) != 2 {
+        t.Fatalf("Expected two distinct location sources: (%d)", len(recoveredGroup.LocationSources))
+    }
+
+    for i, gdr := range recoveredGroup.Records {
+        indices := gdr.Relationships[GeographicRelationshipSourceLocationRecord]
+        if len(indices) != 1 {
+            t.Fatalf("Record (%d) does not have exactly one location source: %v", i, indices)
+        }
+
+        gdls := recoveredGroup.LocationSources[indices[0]]
+        if gdls.Filepath != "track.gpx" || gdls.Latitude != gdr.Latitude || gdls.Longitude != gdr.Longitude {
+            t.Fatalf("Record (%d) location source not correct: %v", i, gdls)
+        }
+    }
+
+    track := recoveredGroup.Track()
+
+    if len(track) != 2 {
+        t.Fatalf("Expected two track records: (%d)", len(track))
+    } else if track[0].Filepath != "track.gpx" || track[0].Timestamp.Equal(epochUtc) == false {
+        t.Fatalf("First track record not correct: [%s] [%s]", track[0].Filepath, track[0].Timestamp)
+    } else if track[1].Filepath != "track.gpx" || track[1].Timestamp.Equal(epochUtc.Add(time.Hour)) == false || track[1].Latitude != chicagoCoordinates[0]+1 {
+        t.Fatalf("Second track record not correct: [%s] [%s]", track[1].Filepath, track[1].Timestamp)
+    }
+}
@@ -12,15 +12,32 @@ const (
 )
 
 type GroupsReducer struct {
-    fg *FindGroups
+    fg     *FindGroups
+    tracer *ImageTracer
+
+    // mergeNotes are the comments about merges keyed by image file-path.
+    mergeNotes map[string][]string
 }
 
 func NewGroupsReducer(fg *FindGroups) *GroupsReducer {
     return &GroupsReducer{
-        fg: fg,
+        fg:         fg,
+        mergeNotes: make(map[string][]string),
     }
 }
 
+// SetImageTracer has trace events recorded for the images that the tracer
+// selects when their groups are merged.
+func (gr *GroupsReducer) SetImageTracer(it *ImageTracer) {
+    gr.tracer = it
+}
+
+// MergeNotes returns the comments that were recorded for the given image when
+// its group was merged into another. This is only meaningful after `Reduce()`.
+func (gr *GroupsReducer) MergeNotes(filepath string) []string {
+    return gr.mergeNotes[filepath]
+}
+
 type collectedGroup struct {
     GroupKey GroupKey
     Records  []*geoindex.GeographicRecord
@@ -76,7 +93,13 @@ func (gr *GroupsReducer) Reduce() (finishedGroups map[string][]*collectedGroup,
 
         lastWasLarge := len(lastCg.Records) > trivialGroupMaximumSize
         currentIsLarge := len(records) > trivialGroupMaximumSize
-        if isDifferentDay || lastWasLarge && currentIsLarge {
+
+        // Groups named by an override are never merged with anything else, and
+        // groups of images without a location are only merged with each other.
+        isOverride := IsOverrideCityKey(lastCg.GroupKey.NearestCityKey) || IsOverrideCityKey(groupKey.NearestCityKey)
+        isMixedUnknown := IsUnknownLocationCityKey(lastCg.GroupKey.NearestCityKey) != IsUnknownLocationCityKey(groupKey.NearestCityKey)
+
+        if isDifferentDay || lastWasLarge && currentIsLarge || isOverride || isMixedUnknown {
             // Either the current and the last group are not trivial or on
             // different days. Don't merge. Start tracking the new group and
             // return the last one.
@@ -106,8 +129,10 @@ func (gr *GroupsReducer) Reduce() (finishedGroups map[string][]*collectedGroup,
             // Add a comment to each of these images.
 
             comment := fmt.Sprintf("Appended to a larger group when dropping trivial group: %s (%d) => %s (%d)", groupKey, len(records), lastCg.GroupKey, originalLen)
-            for _, gr := range records {
-                gr.AddComment(comment)
+            for _, imageGr := range records {
+                imageGr.AddComment(comment)
+                gr.mergeNotes[imageGr.Filepath] = append(gr.mergeNotes[imageGr.Filepath], comment)
+                gr.tracer.Debug(imageGr.Filepath, TraceStageReduce, comment, map[string]interface{}{"group": lastCg.GroupKey.KeyPhrase()})
             }
         } else {
             // If the current group is trivial, regardless of how big the last one was. Either way, we're merging.
@@ -119,8 +144,10 @@ func (gr *GroupsReducer) Reduce() (finishedGroups map[string][]*collectedGroup,
             // Add a comment to each of these images.
 
             comment := fmt.Sprintf("Prepended to a larger group when dropping trivial group: %s (%d) => %s (%d)", lastCg.GroupKey, len(lastCg.Records), groupKey, originalLen)
-            for _, gr := range lastCg.Records {
-                gr.AddComment(comment)
+            for _, imageGr := range lastCg.Records {
+                imageGr.AddComment(comment)
+                gr.mergeNotes[imageGr.Filepath] = append(gr.mergeNotes[imageGr.Filepath], comment)
+                gr.tracer.Debug(imageGr.Filepath, TraceStageReduce, comment, map[string]interface{}{"group": groupKey.KeyPhrase()})
             }
 
             lastCg.GroupKey = groupKey
@@ -0,0 +1,277 @@
+package geoautogroup
+
+import (
+    "fmt"
+    "os"
+    "sync"
+    "time"
+
+    "encoding/gob"
+    "path/filepath"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+)
+
+const (
+    // imageMetadataCacheVersion should be incremented whenever the structure
+    // of the cache changes. Caches with a different version are discarded.
+    imageMetadataCacheVersion = 2
+)
+
+var (
+    imageCacheLogger = log.NewLogger("geoautogroup.image_cache")
+)
+
+type cachedImageRecord struct {
+    Timestamp     time.Time
+    HasGeographic bool
+    Latitude      float64
+    Longitude     float64
+    S2CellId      uint64
+    Metadata      geoindex.ImageMetadata
+}
+
+type imageCacheEntry struct {
+    Size    int64
+    ModTime int64
+
+    // Records are the records that the image produced when parsed. This will
+    // be empty if the image could not be used, which we also want to remember.
+    Records []cachedImageRecord
+}
+
+type imageCacheContent struct {
+    Version            int
+    ImageTimestampSkew time.Duration
+    Entries            map[string]imageCacheEntry
+}
+
+// ImageMetadataCache remembers the metadata parsed from images so that we do
+// not have to parse the EXIF again on subsequent runs. Entries are keyed by
+// file-path and are only used if the size and modification-time still match.
+type ImageMetadataCache struct {
+    filepath           string
+    imageTimestampSkew time.Duration
+    entries            map[string]imageCacheEntry
+
+    hits    int
+    misses  int
+    updated bool
+
+    m sync.Mutex
+}
+
+// OpenImageMetadataCache loads the cache from the given file if it exists. If
+// it doesn't exist or was created with a different timestamp-skew, we'll start
+// with an empty cache.
+func OpenImageMetadataCache(cacheFilepath string, imageTimestampSkew time.Duration) (imc *ImageMetadataCache, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    imc = &ImageMetadataCache{
+        filepath:           cacheFilepath,
+        imageTimestampSkew: imageTimestampSkew,
+        entries:            make(map[string]imageCacheEntry),
+    }
+
+    f, err := os.Open(cacheFilepath)
+    if err != nil {
+        if os.IsNotExist(err) == true {
+            return imc, nil
+        }
+
+        log.Panic(err)
+    }
+
+    defer f.Close()
+
+    content := imageCacheContent{}
+
+    d := gob.NewDecoder(f)
+
+    err = d.Decode(&content)
+    if err != nil {
+        imageCacheLogger.Warningf(nil, "Image cache [%s] could not be read and will be rebuilt: [%s]", cacheFilepath, err.Error())
+        return imc, nil
+    }
+
+    if content.Version != imageMetadataCacheVersion {
+        imageCacheLogger.Warningf(nil, "Image cache [%s] has a different version (%d) and will be rebuilt.", cacheFilepath, content.Version)
+        return imc, nil
+    } else if content.ImageTimestampSkew != imageTimestampSkew {
+        imageCacheLogger.Warningf(nil, "Image cache [%s] was built with a different timestamp skew [%v] and will be rebuilt.", cacheFilepath, content.ImageTimestampSkew)
+        return imc, nil
+    }
+
+    imc.entries = content.Entries
+
+    return imc, nil
+}
+
+// Get returns the records cached for the given image if the image has not
+// changed since it was cached.
+func (imc *ImageMetadataCache) Get(imageFilepath string, fi os.FileInfo) (records []*geoindex.GeographicRecord, found bool) {
+    imc.m.Lock()
+    defer imc.m.Unlock()
+
+    entry, found := imc.entries[imageFilepath]
+    if found == false || entry.Size != fi.Size() || entry.ModTime != fi.ModTime().UnixNano() {
+        imc.misses++
+        return nil, false
+    }
+
+    imc.hits++
+
+    records = make([]*geoindex.GeographicRecord, len(entry.Records))
+    for i, cir := range entry.Records {
+        gr := geoindex.NewGeographicRecord(
+            geoindex.SourceImageJpeg,
+            imageFilepath,
+            cir.Timestamp,
+            cir.HasGeographic,
+            cir.Latitude,
+            cir.Longitude,
+            cir.Metadata)
+
+        gr.S2CellId = cir.S2CellId
+
+        records[i] = gr
+    }
+
+    return records, true
+}
+
+// Set stores the records that were parsed from the given image.
+func (imc *ImageMetadataCache) Set(imageFilepath string, fi os.FileInfo, records []*geoindex.GeographicRecord) {
+    cachedRecords := make([]cachedImageRecord, len(records))
+    for i, gr := range records {
+        var im geoindex.ImageMetadata
+        if metadata, ok := gr.Metadata.(geoindex.ImageMetadata); ok == true {
+            im = metadata
+        }
+
+        cachedRecords[i] = cachedImageRecord{
+            Timestamp:     gr.Timestamp,
+            HasGeographic: gr.HasGeographic,
+            Latitude:      gr.Latitude,
+            Longitude:     gr.Longitude,
+            S2CellId:      gr.S2CellId,
+            Metadata:      im,
+        }
+    }
+
+    imc.m.Lock()
+    defer imc.m.Unlock()
+
+    imc.entries[imageFilepath] = imageCacheEntry{
+        Size:    fi.Size(),
+        ModTime: fi.ModTime().UnixNano(),
+        Records: cachedRecords,
+    }
+
+    imc.updated = true
+}
+
+// Prune drops the entries of any image that no longer exists. Otherwise, the
+// cache would keep growing as images are moved, renamed, or deleted. Entries
+// for images that weren't part of the current run are kept as long as the
+// image is still there, since the same cache might be used for more than one
+// set of paths. Relative paths are never dropped since we can't tell what they
+// were relative to.
+func (imc *ImageMetadataCache) Prune() (removed int, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    imc.m.Lock()
+    defer imc.m.Unlock()
+
+    for imageFilepath := range imc.entries {
+        if filepath.IsAbs(imageFilepath) == false {
+            continue
+        }
+
+        _, err := os.Stat(imageFilepath)
+        if err == nil {
+            continue
+        } else if os.IsNotExist(err) == false {
+            log.Panic(err)
+        }
+
+        delete(imc.entries, imageFilepath)
+        removed++
+    }
+
+    if removed > 0 {
+        imc.updated = true
+    }
+
+    return removed, nil
+}
+
+// Stats returns how many lookups were satisfied from the cache and how many
+// weren't.
+func (imc *ImageMetadataCache) Stats() (hits, misses int) {
+    imc.m.Lock()
+    defer imc.m.Unlock()
+
+    return imc.hits, imc.misses
+}
+
+func (imc *ImageMetadataCache) String() string {
+    hits, misses := imc.Stats()
+    return fmt.Sprintf("ImageMetadataCache<FILEPATH=[%s] ENTRIES=(%d) HITS=(%d) MISSES=(%d)>", imc.filepath, len(imc.entries), hits, misses)
+}
+
+// Save writes the cache back to its file if anything has changed. We write to
+// a temporary file first so that an interrupted save can not corrupt an
+// existing cache.
+func (imc *ImageMetadataCache) Save() (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    imc.m.Lock()
+    defer imc.m.Unlock()
+
+    if imc.updated == false {
+        return nil
+    }
+
+    content := imageCacheContent{
+        Version:            imageMetadataCacheVersion,
+        ImageTimestampSkew: imc.imageTimestampSkew,
+        Entries:            imc.entries,
+    }
+
+    tempFilepath := imc.filepath + ".tmp"
+
+    f, err := os.Create(tempFilepath)
+    log.PanicIf(err)
+
+    e := gob.NewEncoder(f)
+
+    err = e.Encode(content)
+    if err != nil {
+        f.Close()
+        log.Panic(err)
+    }
+
+    err = f.Close()
+    log.PanicIf(err)
+
+    err = os.Rename(tempFilepath, imc.filepath)
+    log.PanicIf(err)
+
+    imc.updated = false
+
+    return nil
+}
@@ -0,0 +1,186 @@
+package geoautogroup
+
+import (
+    "os"
+    "path"
+    "reflect"
+    "testing"
+    "time"
+
+    "io/ioutil"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+)
+
+func TestImageMetadataCache_RoundTrip(t *testing.T) {
+    defer func() {
+        if state := recover(); state != nil {
+            err := log.Wrap(state.(error))
+            log.PrintError(err)
+
+            t.Fatalf("Test failed.")
+        }
+    }()
+
+    tempPath, err := ioutil.TempDir("", "")
+    log.PanicIf(err)
+
+    defer os.RemoveAll(tempPath)
+
+    cacheFilepath := path.Join(tempPath, "images.cache")
+
+    paths := []string{
+        path.Join(testAssetsPath, "test_sources_path1"),
+    }
+
+    imc, err := OpenImageMetadataCache(cacheFilepath, time.Duration(0))
+    log.PanicIf(err)
+
+    originalTi, err := GetImageTimeIndex(paths, time.Duration(0), nil, 0, imc, nil, nil, false)
+    log.PanicIf(err)
+
+    hits, misses := imc.Stats()
+    if hits != 0 {
+        t.Fatalf("Expected no hits on an empty cache: (%d)", hits)
+    } else if misses != 6 {
+        t.Fatalf("Expected a miss for every image: (%d)", misses)
+    }
+
+    err = imc.Save()
+    log.PanicIf(err)
+
+    // Reopen and make sure that nothing is parsed the second time.
+
+    imc, err = OpenImageMetadataCache(cacheFilepath, time.Duration(0))
+    log.PanicIf(err)
+
+    cachedTi, err := GetImageTimeIndex(paths, time.Duration(0), nil, 0, imc, nil, nil, false)
+    log.PanicIf(err)
+
+    hits, misses = imc.Stats()
+    if hits != 6 {
+        t.Fatalf("Expected a hit for every image: (%d)", hits)
+    } else if misses != 0 {
+        t.Fatalf("Expected no misses: (%d)", misses)
+    }
+
+    originalTs := originalTi.Series()
+    cachedTs := cachedTi.Series()
+
+    if len(cachedTs) != len(originalTs) {
+        t.Fatalf("Cached index has a different size: (%d) != (%d)", len(cachedTs), len(originalTs))
+    }
+
+    for i, originalTe := range originalTs {
+        originalGr := originalTe.Items[0].(*geoindex.GeographicRecord)
+        cachedGr := cachedTs[i].Items[0].(*geoindex.GeographicRecord)
+
+        if cachedGr.Filepath != originalGr.Filepath {
+            t.Fatalf("Entry (%d) file-path does not match: [%s] != [%s]", i, cachedGr.Filepath, originalGr.Filepath)
+        } else if cachedGr.Timestamp.Equal(originalGr.Timestamp) == false {
+            t.Fatalf("Entry (%d) timestamp does not match: [%v] != [%v]", i, cachedGr.Timestamp, originalGr.Timestamp)
+        } else if cachedGr.HasGeographic != originalGr.HasGeographic || cachedGr.S2CellId != originalGr.S2CellId {
+            t.Fatalf("Entry (%d) geographic information does not match.", i)
+        }
+
+        originalIm := originalGr.Metadata.(geoindex.ImageMetadata)
+        cachedIm := cachedGr.Metadata.(geoindex.ImageMetadata)
+
+        if reflect.DeepEqual(cachedIm, originalIm) == false {
+            t.Fatalf("Entry (%d) metadata does not match: %v != %v", i, cachedIm, originalIm)
+        }
+    }
+
+    // A different skew invalidates the cache.
+
+    imc, err = OpenImageMetadataCache(cacheFilepath, time.Hour)
+    log.PanicIf(err)
+
+    if len(imc.entries) != 0 {
+        t.Fatalf("Cache with a different skew should have been discarded.")
+    }
+}
+
+func TestImageMetadataCache_Prune(t *testing.T) {
+    defer func() {
+        if state := recover(); state != nil {
+            err := log.Wrap(state.(error))
+            log.PrintError(err)
+
+            t.Fatalf("Test failed.")
+        }
+    }()
+
+    tempPath, err := ioutil.TempDir("", "")
+    log.PanicIf(err)
+
+    defer os.RemoveAll(tempPath)
+
+    imagesPath := path.Join(tempPath, "images")
+    otherImagesPath := path.Join(tempPath, "other_images")
+
+    copyImage := func(filename, toPath string) {
+        data, err := ioutil.ReadFile(path.Join(testAssetsPath, "test_sources_path1", filename))
+        log.PanicIf(err)
+
+        err = os.MkdirAll(toPath, 0755)
+        log.PanicIf(err)
+
+        err = ioutil.WriteFile(path.Join(toPath, filename), data, 0644)
+        log.PanicIf(err)
+    }
+
+    copyImage("20180331121807_IMG_1060.JPG", imagesPath)
+    copyImage("DSC07191.JPG", imagesPath)
+    copyImage("20180331121807_IMG_1060.JPG", otherImagesPath)
+
+    cacheFilepath := path.Join(tempPath, "images.cache")
+
+    imc, err := OpenImageMetadataCache(cacheFilepath, time.Duration(0))
+    log.PanicIf(err)
+
+    _, err = GetImageTimeIndex([]string{imagesPath, otherImagesPath}, time.Duration(0), nil, 0, imc, nil, nil, false)
+    log.PanicIf(err)
+
+    err = imc.Save()
+    log.PanicIf(err)
+
+    if len(imc.entries) != 3 {
+        t.Fatalf("Expected an entry for every image: (%d)", len(imc.entries))
+    }
+
+    // Remove one of the images and only walk its directory. The entry for the
+    // removed image should be dropped but the entry for the image that we
+    // didn't walk should be kept.
+
+    removedFilepath := path.Join(imagesPath, "DSC07191.JPG")
+
+    err = os.Remove(removedFilepath)
+    log.PanicIf(err)
+
+    imc, err = OpenImageMetadataCache(cacheFilepath, time.Duration(0))
+    log.PanicIf(err)
+
+    _, err = GetImageTimeIndex([]string{imagesPath}, time.Duration(0), nil, 0, imc, nil, nil, false)
+    log.PanicIf(err)
+
+    hits, misses := imc.Stats()
+    if hits != 1 || misses != 0 {
+        t.Fatalf("Expected only the remaining image to be looked-up: (%d) hits, (%d) misses", hits, misses)
+    }
+
+    err = imc.Save()
+    log.PanicIf(err)
+
+    imc, err = OpenImageMetadataCache(cacheFilepath, time.Duration(0))
+    log.PanicIf(err)
+
+    if len(imc.entries) != 2 {
+        t.Fatalf("Expected two entries after pruning: (%d)", len(imc.entries))
+    } else if _, found := imc.entries[removedFilepath]; found == true {
+        t.Fatalf("Entry for the removed image was not pruned.")
+    } else if _, found := imc.entries[path.Join(otherImagesPath, "20180331121807_IMG_1060.JPG")]; found == false {
+        t.Fatalf("Entry for the image that wasn't walked should have been kept.")
+    }
+}
@@ -0,0 +1,308 @@
+package geoautogroup
+
+import (
+    "fmt"
+    "os"
+    "runtime"
+    "strings"
+    "sync"
+    "time"
+
+    "path/filepath"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+)
+
+var (
+    imageLoaderLogger = log.NewLogger("geoautogroup.image_loader")
+)
+
+var (
+    // imageFileExtensions are the extensions that the image processors in
+    // go-geographic-index will recognize. Compared case-insensitively.
+    //
+    // This mirrors what `geoindex.RegisterImageFileProcessors` registers. The
+    // collector doesn't provide a way to ask which extensions it will handle,
+    // so there's nothing that we can derive this list from. We filter here so
+    // that we can count the images up front and hand them to the workers
+    // without walking twice. If geoindex starts supporting another format, it
+    // has to be added here or those images will never be loaded.
+    imageFileExtensions = []string{
+        ".jpg",
+        ".jpeg",
+    }
+)
+
+// FindImageFiles walks the given paths once and returns the file-paths of all
+// images that we'd attempt to load, in walk order.
+func FindImageFiles(paths []string) (filepaths []string, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    filepaths = make([]string, 0)
+
+    cb := func(currentFilepath string, info os.FileInfo, err error) error {
+        if err != nil {
+            return err
+        }
+
+        if info.IsDir() == true {
+            return nil
+        }
+
+        extension := strings.ToLower(filepath.Ext(currentFilepath))
+        for _, imageExtension := range imageFileExtensions {
+            if extension == imageExtension {
+                filepaths = append(filepaths, currentFilepath)
+                break
+            }
+        }
+
+        return nil
+    }
+
+    for _, scanPath := range paths {
+        err := filepath.Walk(scanPath, cb)
+        log.PanicIf(err)
+    }
+
+    return filepaths, nil
+}
+
+// loadImageFile parses a single image and returns whatever records the image
+// processors produced for it. This is usually one record, or none if the image
+// did not have a usable timestamp.
+func loadImageFile(imageFilepath string, imageTimestampSkew time.Duration) (records []*geoindex.GeographicRecord, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    // We use a private index and collector per file so that nothing is shared
+    // between the workers.
+
+    ti := geoindex.NewTimeIndex()
+    gc := geoindex.NewGeographicCollector(ti, nil)
+
+    err = geoindex.RegisterImageFileProcessors(gc, imageTimestampSkew, nil)
+    log.PanicIf(err)
+
+    err = gc.ReadFromPath(imageFilepath)
+    log.PanicIf(err)
+
+    records = make([]*geoindex.GeographicRecord, 0)
+    for _, te := range ti.Series() {
+        for _, item := range te.Items {
+            records = append(records, item.(*geoindex.GeographicRecord))
+        }
+    }
+
+    return records, nil
+}
+
+type imageLoadResult struct {
+    position int
+    records  []*geoindex.GeographicRecord
+    err      error
+}
+
+// ImageLoader parses image metadata using a pool of workers.
+type ImageLoader struct {
+    imageTimestampSkew time.Duration
+    concurrency        int
+    cache              *ImageMetadataCache
+    overrides          *ImageOverrides
+    tracer             *ImageTracer
+}
+
+// NewImageLoader returns a new loader. If `concurrency` is zero, we'll use one
+// worker per CPU.
+func NewImageLoader(imageTimestampSkew time.Duration, concurrency int) *ImageLoader {
+    if concurrency < 0 {
+        log.Panicf("concurrency can not be negative: (%d)", concurrency)
+    } else if concurrency == 0 {
+        concurrency = runtime.NumCPU()
+    }
+
+    return &ImageLoader{
+        imageTimestampSkew: imageTimestampSkew,
+        concurrency:        concurrency,
+    }
+}
+
+// SetCache will have the loader reuse records from the given cache for images
+// that haven't changed and add the records for images that it had to parse.
+func (il *ImageLoader) SetCache(imc *ImageMetadataCache) {
+    il.cache = imc
+}
+
+// SetOverrides will have the loader apply any matching override to the
+// records of each image. The cache always stores the records as they were
+// parsed.
+func (il *ImageLoader) SetOverrides(ios *ImageOverrides) {
+    il.overrides = ios
+}
+
+// SetImageTracer will have the loader record trace events for the images that
+// the tracer selects.
+func (il *ImageLoader) SetImageTracer(it *ImageTracer) {
+    il.tracer = it
+}
+
+// applyOverrides applies the override for the given image, if any.
+func (il *ImageLoader) applyOverrides(imageFilepath string, records []*geoindex.GeographicRecord) (overridden []*geoindex.GeographicRecord, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    if il.overrides == nil {
+        return records, nil
+    }
+
+    iov, err := il.overrides.Lookup(imageFilepath)
+    log.PanicIf(err)
+
+    if iov == nil {
+        return records, nil
+    }
+
+    if len(records) == 0 && iov.HasTimestamp() == false {
+        imageLoaderLogger.Warningf(nil, "Image [%s] has no timestamp and override %s does not provide one. It will be skipped.", imageFilepath, iov)
+    }
+
+    fields := map[string]interface{}{
+        "source": iov.sourceFilepath,
+        "match":  iov.Match,
+    }
+
+    if iov.HasGroup() == true {
+        fields["group"] = iov.Group
+    }
+
+    il.tracer.Debug(imageFilepath, TraceStageOverride, fmt.Sprintf("Applying override: %s", iov), fields)
+
+    return iov.Apply(imageFilepath, records), nil
+}
+
+// loadImageFile returns the records for the given image, from the cache if
+// possible.
+func (il *ImageLoader) loadImageFile(imageFilepath string) (records []*geoindex.GeographicRecord, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    if il.cache == nil {
+        records, err := loadImageFile(imageFilepath, il.imageTimestampSkew)
+        log.PanicIf(err)
+
+        return records, nil
+    }
+
+    fi, err := os.Stat(imageFilepath)
+    log.PanicIf(err)
+
+    if records, found := il.cache.Get(imageFilepath, fi); found == true {
+        return records, nil
+    }
+
+    records, err = loadImageFile(imageFilepath, il.imageTimestampSkew)
+    log.PanicIf(err)
+
+    il.cache.Set(imageFilepath, fi, records)
+
+    return records, nil
+}
+
+// Load parses all of the given images and adds them to the given index. The
+// records are added in the same order as the file-paths regardless of the
+// order that the workers finish in, so the resulting index is identical to one
+// that was loaded serially. `progressCb` is called once for every file that has
+// been processed and may be nil.
+func (il *ImageLoader) Load(filepaths []string, ti *geoindex.TimeIndex, progressCb func(filepath string)) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    positionsC := make(chan int)
+    resultsC := make(chan imageLoadResult)
+
+    wg := new(sync.WaitGroup)
+
+    for i := 0; i < il.concurrency; i++ {
+        wg.Add(1)
+
+        go func() {
+            defer wg.Done()
+
+            for position := range positionsC {
+                records, err := il.loadImageFile(filepaths[position])
+                if err == nil {
+                    il.tracer.Debug(filepaths[position], TraceStageLoad, fmt.Sprintf("Loaded (%d) records", len(records)), nil)
+
+                    records, err = il.applyOverrides(filepaths[position], records)
+                }
+
+                resultsC <- imageLoadResult{
+                    position: position,
+                    records:  records,
+                    err:      err,
+                }
+            }
+        }()
+    }
+
+    go func() {
+        for i := range filepaths {
+            positionsC <- i
+        }
+
+        close(positionsC)
+
+        wg.Wait()
+        close(resultsC)
+    }()
+
+    loaded := make([][]*geoindex.GeographicRecord, len(filepaths))
+
+    var firstErr error
+    for result := range resultsC {
+        if result.err != nil {
+            imageLoaderLogger.Errorf(nil, result.err, "Could not load image: [%s]", filepaths[result.position])
+
+            if firstErr == nil {
+                firstErr = result.err
+            }
+
+            // Keep draining so that the workers can exit.
+            continue
+        }
+
+        loaded[result.position] = result.records
+
+        if progressCb != nil {
+            progressCb(filepaths[result.position])
+        }
+    }
+
+    log.PanicIf(firstErr)
+
+    for _, records := range loaded {
+        for _, gr := range records {
+            err := ti.AddWithRecord(gr)
+            log.PanicIf(err)
+        }
+    }
+
+    return nil
+}
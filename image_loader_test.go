@@ -0,0 +1,87 @@
+package geoautogroup
+
+import (
+    "path"
+    "testing"
+    "time"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+)
+
+func TestFindImageFiles(t *testing.T) {
+    paths := []string{
+        path.Join(testAssetsPath, "test_sources_path1"),
+    }
+
+    filepaths, err := FindImageFiles(paths)
+    log.PanicIf(err)
+
+    if len(filepaths) != 6 {
+        t.Fatalf("The number of images found is not correct: (%d)", len(filepaths))
+    }
+
+    for _, filepath := range filepaths {
+        if path.Ext(filepath) == ".gpx" {
+            t.Fatalf("Non-image file was returned: [%s]", filepath)
+        }
+    }
+}
+
+func TestImageLoader_Load_SameAsSerial(t *testing.T) {
+    paths := []string{
+        path.Join(testAssetsPath, "test_sources_path1"),
+    }
+
+    filepaths, err := FindImageFiles(paths)
+    log.PanicIf(err)
+
+    serialTi := geoindex.NewTimeIndex()
+
+    il := NewImageLoader(time.Duration(0), 1)
+
+    err = il.Load(filepaths, serialTi, nil)
+    log.PanicIf(err)
+
+    parallelTi := geoindex.NewTimeIndex()
+
+    processed := 0
+    progressCb := func(filepath string) {
+        processed++
+    }
+
+    il = NewImageLoader(time.Duration(0), 4)
+
+    err = il.Load(filepaths, parallelTi, progressCb)
+    log.PanicIf(err)
+
+    if processed != len(filepaths) {
+        t.Fatalf("Progress callback was not called for every file: (%d) != (%d)", processed, len(filepaths))
+    }
+
+    serialTs := serialTi.Series()
+    parallelTs := parallelTi.Series()
+
+    if len(serialTs) != len(parallelTs) {
+        t.Fatalf("Parallel load produced a different number of entries: (%d) != (%d)", len(parallelTs), len(serialTs))
+    }
+
+    for i, serialTe := range serialTs {
+        parallelTe := parallelTs[i]
+
+        if parallelTe.Time != serialTe.Time {
+            t.Fatalf("Entry (%d) has a different time: [%v] != [%v]", i, parallelTe.Time, serialTe.Time)
+        } else if len(parallelTe.Items) != len(serialTe.Items) {
+            t.Fatalf("Entry (%d) has a different number of items.", i)
+        }
+
+        for j, item := range serialTe.Items {
+            serialGr := item.(*geoindex.GeographicRecord)
+            parallelGr := parallelTe.Items[j].(*geoindex.GeographicRecord)
+
+            if parallelGr.Filepath != serialGr.Filepath {
+                t.Fatalf("Entry (%d) item (%d) is not the same file: [%s] != [%s]", i, j, parallelGr.Filepath, serialGr.Filepath)
+            }
+        }
+    }
+}
@@ -1,45 +1,218 @@
 package geoautogroup
 
 import (
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+    "sync"
+
+    "encoding/json"
+    "path/filepath"
+
     "github.com/dsoprea/go-logging"
 )
 
+// Stages that trace events are recorded in.
+const (
+    TraceStageLoad     = "load"
+    TraceStageOverride = "override"
+    TraceStageLocation = "location"
+    TraceStageAdjacent = "adjacent"
+    TraceStageFallback = "fallback"
+    TraceStageCity     = "city"
+    TraceStageGroup    = "group"
+    TraceStageTimeKey  = "time_key"
+    TraceStageSmooth   = "smooth"
+    TraceStageReduce   = "reduce"
+)
+
+// Levels of trace events.
+const (
+    TraceLevelDebug   = "debug"
+    TraceLevelWarning = "warning"
+)
+
 var (
     itLogger = log.NewLogger("geoautogroup.image_trace")
-
-    imageTraceIndex map[string][]string
 )
 
-func InitImageTrace(filepaths []string) {
-    imageTraceIndex = make(map[string][]string)
+// ImageTraceEvent is one thing that happened to an image while it was being
+// processed.
+type ImageTraceEvent struct {
+    Filepath string                 `json:"filepath"`
+    Stage    string                 `json:"stage"`
+    Level    string                 `json:"level"`
+    Message  string                 `json:"message"`
+    Fields   map[string]interface{} `json:"fields,omitempty"`
+}
+
+func (ite ImageTraceEvent) String() string {
+    return fmt.Sprintf("[%s] %s", ite.Stage, ite.Message)
+}
+
+// ImageTracer records what happens to a selection of images while they are
+// loaded and grouped. Each grouping run should have its own tracer. It is safe
+// for concurrent use and all methods can be called on a nil tracer, which
+// traces nothing.
+type ImageTracer struct {
+    filepaths map[string]struct{}
+    patterns  []string
+    traceAll  bool
 
-    for _, filepath := range filepaths {
-        imageTraceIndex[filepath] = nil
+    events map[string][]ImageTraceEvent
+    order  []string
+    mutex  sync.Mutex
+}
+
+// NewImageTracer returns a tracer for the given file-paths and glob patterns.
+// Patterns without a path separator are matched against the filename. If
+// `traceAll` is true, every image is traced.
+func NewImageTracer(filepaths []string, patterns []string, traceAll bool) (it *ImageTracer, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    for _, pattern := range patterns {
+        _, err := filepath.Match(pattern, "")
+        if err != nil {
+            log.Panicf("trace pattern [%s] not valid: %s", pattern, err.Error())
+        }
+    }
+
+    it = &ImageTracer{
+        filepaths: make(map[string]struct{}),
+        patterns:  patterns,
+        traceAll:  traceAll,
+        events:    make(map[string][]ImageTraceEvent),
+        order:     make([]string, 0),
     }
+
+    for _, imageFilepath := range filepaths {
+        it.filepaths[imageFilepath] = struct{}{}
+    }
+
+    return it, nil
 }
 
-func PushDebugTrace(filepath, message string) {
-    if imageTraceIndex != nil {
-        if comments, found := imageTraceIndex[filepath]; found == true {
-            imageTraceIndex[filepath] = append(comments, message)
+// IsTraced indicates whether events for the given image are recorded.
+func (it *ImageTracer) IsTraced(imageFilepath string) bool {
+    if it == nil {
+        return false
+    } else if it.traceAll == true {
+        return true
+    } else if _, found := it.filepaths[imageFilepath]; found == true {
+        return true
+    }
+
+    for _, pattern := range it.patterns {
+        target := imageFilepath
+        if strings.ContainsRune(pattern, filepath.Separator) == false {
+            target = filepath.Base(imageFilepath)
+        }
+
+        if matched, _ := filepath.Match(pattern, target); matched == true {
+            return true
         }
     }
+
+    return false
+}
+
+func (it *ImageTracer) push(level, imageFilepath, stage, message string, fields map[string]interface{}) {
+    if it.IsTraced(imageFilepath) == false {
+        return
+    }
+
+    ite := ImageTraceEvent{
+        Filepath: imageFilepath,
+        Stage:    stage,
+        Level:    level,
+        Message:  message,
+        Fields:   fields,
+    }
+
+    it.mutex.Lock()
+    defer it.mutex.Unlock()
+
+    if events, found := it.events[imageFilepath]; found == true {
+        it.events[imageFilepath] = append(events, ite)
+    } else {
+        it.events[imageFilepath] = []ImageTraceEvent{ite}
+        it.order = append(it.order, imageFilepath)
+    }
 }
 
-func PushWarningTrace(filepath, message string) {
+// Debug records an event for the image if it is being traced. `fields` may be
+// nil.
+func (it *ImageTracer) Debug(imageFilepath, stage, message string, fields map[string]interface{}) {
+    it.push(TraceLevelDebug, imageFilepath, stage, message, fields)
+}
+
+// Warning logs the message and records an event for the image if it is being
+// traced. The message is logged even if there's no tracer.
+func (it *ImageTracer) Warning(imageFilepath, stage, message string, fields map[string]interface{}) {
     itLogger.Warningf(nil, message)
 
-    if imageTraceIndex != nil {
-        if comments, found := imageTraceIndex[filepath]; found == true {
-            imageTraceIndex[filepath] = append(comments, message)
-        }
+    it.push(TraceLevelWarning, imageFilepath, stage, message, fields)
+}
+
+// Filepaths returns the traced images that have events, in the order that
+// they were first seen.
+func (it *ImageTracer) Filepaths() []string {
+    if it == nil {
+        return nil
     }
+
+    it.mutex.Lock()
+    defer it.mutex.Unlock()
+
+    filepaths := make([]string, len(it.order))
+    copy(filepaths, it.order)
+
+    return filepaths
 }
 
-func IsImageTraceIndexInited() bool {
-    return imageTraceIndex != nil
+// Events returns the events recorded for the given image.
+func (it *ImageTracer) Events(imageFilepath string) []ImageTraceEvent {
+    if it == nil {
+        return nil
+    }
+
+    it.mutex.Lock()
+    defer it.mutex.Unlock()
+
+    events := it.events[imageFilepath]
+
+    copied := make([]ImageTraceEvent, len(events))
+    copy(copied, events)
+
+    return copied
 }
 
-func ImageTraceIndex() map[string][]string {
-    return imageTraceIndex
+// WriteJsonLines writes every event as one JSON object per line. The images
+// are written in filename order and the events for each in the order that
+// they were recorded.
+func (it *ImageTracer) WriteJsonLines(w io.Writer) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    filepaths := it.Filepaths()
+    sort.Strings(filepaths)
+
+    e := json.NewEncoder(w)
+
+    for _, imageFilepath := range filepaths {
+        for _, ite := range it.Events(imageFilepath) {
+            err := e.Encode(ite)
+            log.PanicIf(err)
+        }
+    }
+
+    return nil
 }
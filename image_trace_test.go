@@ -0,0 +1,89 @@
+package geoautogroup
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+
+    "encoding/json"
+
+    "github.com/dsoprea/go-logging"
+)
+
+func TestImageTracer_IsTraced(t *testing.T) {
+    it, err := NewImageTracer([]string{"/a/image1.jpg"}, []string{"IMG_*.JPG", "/b/*/*.jpg"}, false)
+    log.PanicIf(err)
+
+    traced := []string{
+        "/a/image1.jpg",
+        "/c/IMG_0001.JPG",
+        "/b/2018/image.jpg",
+    }
+
+    for _, imageFilepath := range traced {
+        if it.IsTraced(imageFilepath) == false {
+            t.Fatalf("Expected image to be traced: [%s]", imageFilepath)
+        }
+    }
+
+    untraced := []string{
+        "/a/image2.jpg",
+        "/b/image.jpg",
+    }
+
+    for _, imageFilepath := range untraced {
+        if it.IsTraced(imageFilepath) == true {
+            t.Fatalf("Expected image to not be traced: [%s]", imageFilepath)
+        }
+    }
+
+    it, err = NewImageTracer(nil, nil, true)
+    log.PanicIf(err)
+
+    if it.IsTraced("/a/image2.jpg") == false {
+        t.Fatalf("Expected every image to be traced.")
+    }
+
+    // A nil tracer traces nothing.
+
+    it = nil
+
+    if it.IsTraced("/a/image1.jpg") == true {
+        t.Fatalf("Expected nil tracer to not trace anything.")
+    }
+
+    it.Debug("/a/image1.jpg", TraceStageLoad, "message", nil)
+}
+
+func TestImageTracer_WriteJsonLines(t *testing.T) {
+    it, err := NewImageTracer([]string{"/a/image2.jpg", "/a/image1.jpg"}, nil, false)
+    log.PanicIf(err)
+
+    it.Debug("/a/image2.jpg", TraceStageLoad, "loaded", nil)
+    it.Debug("/a/image1.jpg", TraceStageLocation, "matched", map[string]interface{}{"time_delta": "1m0s"})
+    it.Debug("/a/image1.jpg", TraceStageGroup, "grouped", nil)
+    it.Debug("/a/image3.jpg", TraceStageLoad, "untraced", nil)
+
+    if filepaths := it.Filepaths(); len(filepaths) != 2 || filepaths[0] != "/a/image2.jpg" {
+        t.Fatalf("Traced file-paths not correct: %v", filepaths)
+    }
+
+    b := new(bytes.Buffer)
+
+    err = it.WriteJsonLines(b)
+    log.PanicIf(err)
+
+    lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+    if len(lines) != 3 {
+        t.Fatalf("Expected three events: (%d)", len(lines))
+    }
+
+    ite := ImageTraceEvent{}
+
+    err = json.Unmarshal([]byte(lines[0]), &ite)
+    log.PanicIf(err)
+
+    if ite.Filepath != "/a/image1.jpg" || ite.Stage != TraceStageLocation || ite.Fields["time_delta"] != "1m0s" {
+        t.Fatalf("First event not correct: %v", ite)
+    }
+}
@@ -44,28 +44,8 @@ type bufferedGroup struct {
     // locationIndex is a map of nearest-cities to the first index at which they
     // appear.
     locationIndex map[string]int
-}
-
-func (bg *bufferedGroup) dump(printDetail bool) {
-    fmt.Printf("BUFFERED GROUP\n")
-    fmt.Printf("--------------\n")
-    fmt.Printf("Have complete group? [%v]\n", bg.haveCompleteGroup())
-    fmt.Printf("Have partial group? [%v]\n", bg.havePartialGroup())
-    fmt.Printf("First time-key: [%s]\n", bg.firstTimeKey)
-    fmt.Printf("Last time-key: [%s]\n", bg.lastTimeKey)
-    fmt.Printf("Image count: (%d)\n", len(bg.images))
-
-    if printDetail == true {
-        fmt.Printf("\n")
 
-        for i, bi := range bg.images {
-            fmt.Printf("> Image (%d): EFF-TIME-KEY=[%s] CITY=[%s] FILEPATH=[%s]\n", i, bi.effectiveTimekey, bi.nearestCityKey, bi.gr.Filepath)
-        }
-
-        fmt.Printf("\n")
-    }
-
-    fmt.Printf("\n")
+    tracer *ImageTracer
 }
 
 // haveCompleteGroup will return true if we have more than one time-key in the
@@ -203,9 +183,16 @@ func (bg *bufferedGroup) pushImage(nearestCityKey string, gr *geoindex.Geographi
     var effectiveTimekey time.Time
     if lastBi.nearestCityKey == nearestCityKey {
         effectiveTimekey = bg.lastTimeKey
-        gr.AddComment(fmt.Sprintf("Inheriting time-key [%s] of previous record with same city [%s]: [%s] (%.6f, %.6f)", effectiveTimekey, nearestCityKey, path.Base(lastBi.gr.Filepath), lastBi.gr.Latitude, lastBi.gr.Longitude))
+
+        comment := fmt.Sprintf("Inheriting time-key [%s] of previous record with same city [%s]: [%s] (%.6f, %.6f)", effectiveTimekey, nearestCityKey, path.Base(lastBi.gr.Filepath), lastBi.gr.Latitude, lastBi.gr.Longitude)
+        gr.AddComment(comment)
+
+        bg.tracer.Debug(gr.Filepath, TraceStageTimeKey, comment, map[string]interface{}{"time_key": effectiveTimekey, "previous_filepath": lastBi.gr.Filepath})
     } else {
-        gr.AddComment(fmt.Sprintf("Left-adjacent image in buffer is [%s] with different city [%s] at coordinates (%.6f, %.6f) and time-key [%v]", path.Base(lastBi.gr.Filepath), lastBi.nearestCityKey, lastBi.gr.Latitude, lastBi.gr.Longitude, bg.lastTimeKey))
+        comment := fmt.Sprintf("Left-adjacent image in buffer is [%s] with different city [%s] at coordinates (%.6f, %.6f) and time-key [%v]", path.Base(lastBi.gr.Filepath), lastBi.nearestCityKey, lastBi.gr.Latitude, lastBi.gr.Longitude, bg.lastTimeKey)
+        gr.AddComment(comment)
+
+        bg.tracer.Debug(gr.Filepath, TraceStageTimeKey, comment, map[string]interface{}{"previous_filepath": lastBi.gr.Filepath})
     }
 
     // Now, append.
@@ -243,8 +230,11 @@ func (bg *bufferedGroup) pushImage(nearestCityKey string, gr *geoindex.Geographi
         // city (but still within the same time-key of our new image. By.
         // Otherwise, we'll just update and reupdate all of the adjacent images
         // that we add that we already know to have the same city.
+        //
+        // Images with a location are never smoothed to the group of images
+        // without one or to a group named by an override.
         previousBi := bg.images[len_-2]
-        if previousBi.nearestCityKey != nearestCityKey && previousBi.effectiveTimekey == currentTimekey {
+        if previousBi.nearestCityKey != nearestCityKey && previousBi.effectiveTimekey == currentTimekey && IsUnknownLocationCityKey(nearestCityKey) == false && IsOverrideCityKey(nearestCityKey) == false {
             start_index := index + 1
             n := len(bg.images) - start_index
 
@@ -255,12 +245,23 @@ func (bg *bufferedGroup) pushImage(nearestCityKey string, gr *geoindex.Geographi
                     log.Panicf("current BI during smoothing is no longer the same time-key: [%v] != [%s]", bi.effectiveTimekey, currentTimekey)
                 }
 
-                if bi.nearestCityKey != nearestCityKey {
+                // Groups named by an override are never smoothed away.
+                if bi.nearestCityKey != nearestCityKey && IsOverrideCityKey(bi.nearestCityKey) == false {
                     // The amount of time elapsed between this image and the first
                     // image we encountered at the same city and time-key.
                     timeSinceAberration := bi.gr.Timestamp.Sub(firstEncounteredBi.gr.Timestamp)
 
-                    bi.gr.AddComment(fmt.Sprintf("Smoothed image <time-key [%v] timestamp [%v] city [%s] file [%s]> to city [%s] (from just-pushed image <time-key [%v] timestamp [%v] city [%s] file [%s]>). TIME-BETWEEN=[%s] STEP=(%d/%d)", bi.effectiveTimekey, bi.gr.Timestamp, bi.nearestCityKey, path.Base(bi.gr.Filepath), nearestCityKey, currentTimekey, gr.Timestamp, nearestCityKey, path.Base(gr.Filepath), timeSinceAberration, i+1, n))
+                    comment := fmt.Sprintf("Smoothed image <time-key [%v] timestamp [%v] city [%s] file [%s]> to city [%s] (from just-pushed image <time-key [%v] timestamp [%v] city [%s] file [%s]>). TIME-BETWEEN=[%s] STEP=(%d/%d)", bi.effectiveTimekey, bi.gr.Timestamp, bi.nearestCityKey, path.Base(bi.gr.Filepath), nearestCityKey, currentTimekey, gr.Timestamp, nearestCityKey, path.Base(gr.Filepath), timeSinceAberration, i+1, n)
+                    bi.gr.AddComment(comment)
+
+                    fields := map[string]interface{}{
+                        "from_nearest_city_key": bi.nearestCityKey,
+                        "to_nearest_city_key":   nearestCityKey,
+                        "pushed_filepath":       gr.Filepath,
+                    }
+
+                    bg.tracer.Debug(bi.gr.Filepath, TraceStageSmooth, comment, fields)
+
                     bi.nearestCityKey = nearestCityKey
                 }
             }
@@ -302,21 +303,7 @@ func initBufferedGroup(nearestCityKey string, initialGr *geoindex.GeographicReco
 
 type iterativeGroupBuffers struct {
     groupsByCameraModel map[string]*bufferedGroup
-}
-
-func (igb *iterativeGroupBuffers) dump(printDetail bool) {
-    if len(igb.groupsByCameraModel) == 0 {
-        fmt.Printf("No images buffered.\n\n")
-        return
-    }
-
-    for cameraModel, bg := range igb.groupsByCameraModel {
-        fmt.Printf("BUFFERED GROUP [%s]\n", cameraModel)
-        fmt.Printf("=============================\n")
-        fmt.Printf("\n")
-
-        bg.dump(printDetail)
-    }
+    tracer              *ImageTracer
 }
 
 func newIterativeGroupBuffers() *iterativeGroupBuffers {
@@ -418,6 +405,9 @@ func (igb *iterativeGroupBuffers) pushImage(nearestCityKey string, gr *geoindex.
     if existingGroupBuffer, found := igb.groupsByCameraModel[cameraModel]; found == true {
         existingGroupBuffer.pushImage(nearestCityKey, gr)
     } else {
-        igb.groupsByCameraModel[cameraModel] = initBufferedGroup(nearestCityKey, gr)
+        bg := initBufferedGroup(nearestCityKey, gr)
+        bg.tracer = igb.tracer
+
+        igb.groupsByCameraModel[cameraModel] = bg
     }
 }
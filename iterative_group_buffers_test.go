@@ -513,3 +513,57 @@ func TestIterativeGroupBuffers_popFirstPartialGroup(t *testing.T) {
         t.Fatalf("Expected zero models to be registered after popping the second complete group.")
     }
 }
+
+func TestBufferedGroup_pushImage_Trace(t *testing.T) {
+    it, err := NewImageTracer(nil, nil, true)
+    if err != nil {
+        t.Fatalf("Could not create tracer: %v", err)
+    }
+
+    gr1 := geoindex.NewGeographicRecord("source-name", "11.jpg", epochUtc.Add(time.Second*1), true, 12.34, 34.56, nil)
+    bg := initBufferedGroup("city A", gr1)
+    bg.tracer = it
+
+    gr2 := geoindex.NewGeographicRecord("source-name", "22.jpg", epochUtc.Add(time.Second*2), true, 12.34, 34.56, nil)
+    bg.pushImage("city A", gr2)
+
+    gr3 := geoindex.NewGeographicRecord("source-name", "33.jpg", epochUtc.Add(time.Second*3), true, 12.34, 34.56, nil)
+    bg.pushImage("city B", gr3)
+
+    gr4 := geoindex.NewGeographicRecord("source-name", "44.jpg", epochUtc.Add(time.Second*4), true, 12.34, 34.56, nil)
+    bg.pushImage("city A", gr4)
+
+    if bg.images[2].nearestCityKey != "city A" {
+        t.Fatalf("Expected the third image to be smoothed: [%s]", bg.images[2].nearestCityKey)
+    }
+
+    events := it.Events("22.jpg")
+    if len(events) != 1 || events[0].Stage != TraceStageTimeKey {
+        t.Fatalf("Expected the time-key inheritance to be traced: %v", events)
+    }
+
+    events = it.Events("33.jpg")
+    if len(events) != 2 || events[1].Stage != TraceStageSmooth || events[1].Fields["to_nearest_city_key"] != "city A" {
+        t.Fatalf("Expected the smoothing to be traced: %v", events)
+    }
+}
+
+func TestBufferedGroup_pushImage_NotSmoothedToOverride(t *testing.T) {
+    overrideCityKey := OverrideCityKey("Wedding")
+
+    gr1 := geoindex.NewGeographicRecord("source-name", "11.jpg", epochUtc.Add(time.Second*1), true, 12.34, 34.56, nil)
+    bg := initBufferedGroup("city A", gr1)
+
+    gr2 := geoindex.NewGeographicRecord("source-name", "22.jpg", epochUtc.Add(time.Second*2), true, 12.34, 34.56, nil)
+    bg.pushImage(overrideCityKey, gr2)
+
+    gr3 := geoindex.NewGeographicRecord("source-name", "33.jpg", epochUtc.Add(time.Second*3), true, 12.34, 34.56, nil)
+    bg.pushImage("city B", gr3)
+
+    gr4 := geoindex.NewGeographicRecord("source-name", "44.jpg", epochUtc.Add(time.Second*4), true, 12.34, 34.56, nil)
+    bg.pushImage(overrideCityKey, gr4)
+
+    if bg.images[2].nearestCityKey != "city B" {
+        t.Fatalf("Located image should not have been smoothed into the override group: [%s]", bg.images[2].nearestCityKey)
+    }
+}
@@ -0,0 +1,420 @@
+package geoautogroup
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "time"
+
+    "crypto/sha1"
+    "encoding/hex"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-index"
+    "github.com/dsoprea/time-to-go"
+)
+
+// locationSourceFile describes one data file that was loaded into the location
+// database. We use the size and modification-time to decide whether we need to
+// hash the file again and the hash to decide whether it actually changed.
+type locationSourceFile struct {
+    Filepath string
+    Size     int64
+    ModTime  int64
+    Sha1     []byte
+}
+
+// locationDatabaseSeries is what we store for each series in the time-series
+// stream: the location records loaded from one data file plus the manifest
+// entry for that file. Keeping one series per file means that adding or
+// removing a file only adds or drops that file's series.
+type locationDatabaseSeries struct {
+    Source locationSourceFile
+    Series timeindex.TimeSlice
+
+    // PrunedRanges are the time ranges that have been removed from this series
+    // since it was loaded. They are forgotten if the file changes and has to be
+    // loaded again.
+    PrunedRanges []locationPruneRange
+}
+
+type locationPruneRange struct {
+    Start time.Time
+    End   time.Time
+}
+
+// isLegacy indicates a series that was written before we stored one series per
+// file. These will not have a source and will be dropped on the next update.
+func (lds locationDatabaseSeries) isLegacy() bool {
+    return lds.Source.Filepath == ""
+}
+
+// seriesSha1 identifies the series in the stream. We mix the file-path in so
+// that two files with identical content still produce distinct series. The
+// stat info is mixed in so that a file that was only touched is written again
+// with its new stat info.
+func (lds locationDatabaseSeries) seriesSha1() []byte {
+    h := sha1.New()
+
+    h.Write([]byte(lds.Source.Filepath))
+    h.Write([]byte{0})
+    h.Write(lds.Source.Sha1)
+    h.Write([]byte(fmt.Sprintf("%d,%d", lds.Source.Size, lds.Source.ModTime)))
+
+    // A pruned series has different content than what the file produces, so it
+    // must be a different series.
+    for _, lpr := range lds.PrunedRanges {
+        h.Write([]byte(lpr.Start.Format(time.RFC3339Nano)))
+        h.Write([]byte(lpr.End.Format(time.RFC3339Nano)))
+    }
+
+    return h.Sum(nil)
+}
+
+// seriesFooter returns a footer describing this series.
+func (lds locationDatabaseSeries) seriesFooter() *timetogo.SeriesFooter1 {
+    var headTime, tailTime time.Time
+    if len(lds.Series) > 0 {
+        headTime = lds.Series[0].Time
+        tailTime = lds.Series[len(lds.Series)-1].Time
+    }
+
+    return timetogo.NewSeriesFooter1(
+        headTime,
+        tailTime,
+        uint64(len(lds.Series)),
+        lds.seriesSha1())
+}
+
+// locationDatabaseEncoder provides the data for whichever series the updater
+// is currently writing. Series are identified by their source SHA1.
+type locationDatabaseEncoder struct {
+    series map[string]locationDatabaseSeries
+}
+
+func newLocationDatabaseEncoder() *locationDatabaseEncoder {
+    return &locationDatabaseEncoder{
+        series: make(map[string]locationDatabaseSeries),
+    }
+}
+
+func (lde *locationDatabaseEncoder) add(lds locationDatabaseSeries) {
+    key := hex.EncodeToString(lds.seriesSha1())
+    lde.series[key] = lds
+}
+
+// WriteData encodes the series identified by the given footer.
+func (lde *locationDatabaseEncoder) WriteData(w io.Writer, sf timetogo.SeriesFooter) (n int, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    key := hex.EncodeToString(sf.SourceSha1())
+
+    lds, found := lde.series[key]
+    if found == false {
+        log.Panicf("location database series not found: [%s]", key)
+    }
+
+    gsoed := timetogo.NewGobSingleObjectEncoderDatasource(lds)
+
+    n, err = gsoed.WriteData(w, sf)
+    log.PanicIf(err)
+
+    return n, nil
+}
+
+// visitLocationDatabase reads every series in the stream and passes each to the
+// callback along with whether its checksum matched. If a series can not be
+// decoded, the callback is given the error and an empty series rather than the
+// visit being aborted, so that the caller can decide whether it's fatal. If the
+// stream is empty, the error from the iterator is returned as-is so that the
+// caller can check for `io.EOF`.
+func visitLocationDatabase(rs io.ReadSeeker, cb func(i int, lds locationDatabaseSeries, checksumOk bool, decodeErr error) error) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    streamReader := timetogo.NewStreamReader(rs)
+
+    streamIterator, err := timetogo.NewIterator(streamReader)
+    if err != nil {
+        return err
+    }
+
+    count_ := streamIterator.Count()
+    for i := 0; i < count_; i++ {
+        sisi := streamIterator.SeriesInfo(i)
+
+        lds := locationDatabaseSeries{}
+        gsodd := timetogo.NewGobSingleObjectDecoderDatasource(&lds)
+
+        _, _, checksumOk, decodeErr := streamReader.ReadSeriesWithIndexedInfo(sisi, gsodd)
+        if decodeErr != nil {
+            lds = locationDatabaseSeries{}
+            checksumOk = false
+        }
+
+        err = cb(i, lds, checksumOk, decodeErr)
+        log.PanicIf(err)
+    }
+
+    return nil
+}
+
+// readLocationDatabase reads and verifies every series in the stream. If the
+// stream is empty, the error from the iterator is returned as-is so that the
+// caller can check for `io.EOF`.
+func readLocationDatabase(rs io.ReadSeeker) (stored []locationDatabaseSeries, err error) {
+    stored = make([]locationDatabaseSeries, 0)
+
+    cb := func(i int, lds locationDatabaseSeries, checksumOk bool, decodeErr error) error {
+        if decodeErr != nil {
+            return decodeErr
+        } else if checksumOk != true {
+            return ErrLocationTimeIndexChecksumFail
+        }
+
+        stored = append(stored, lds)
+        return nil
+    }
+
+    err = visitLocationDatabase(rs, cb)
+    if err != nil {
+        return nil, err
+    }
+
+    return stored, nil
+}
+
+// writeLocationDatabase brings the stream in line with the given series.
+// Series that are already in the stream are skipped, new ones are added, and
+// the ones that aren't given are dropped.
+func writeLocationDatabase(rws io.ReadWriteSeeker, current []locationDatabaseSeries) (totalSize, adds, skips, drops int, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    lde := newLocationDatabaseEncoder()
+    updater := timetogo.NewUpdater(rws, lde)
+
+    for _, lds := range current {
+        lde.add(lds)
+        updater.AddSeries(lds.seriesFooter())
+    }
+
+    size, stats, err := updater.Write()
+    log.PanicIf(err)
+
+    adds = int(stats.Adds)
+    skips = int(stats.Skips)
+    drops = int(stats.Drops)
+
+    if adds+skips != len(current) {
+        log.Panicf("update operation did not account for all series: (%d) + (%d) != (%d)", adds, skips, len(current))
+    }
+
+    return int(size), adds, skips, drops, nil
+}
+
+// mergeLocationDatabaseSeries combines the records of all of the given series
+// into one index.
+func mergeLocationDatabaseSeries(stored []locationDatabaseSeries) (ti *geoindex.TimeIndex, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    ti = geoindex.NewTimeIndex()
+
+    for _, lds := range stored {
+        for _, te := range lds.Series {
+            for _, item := range te.Items {
+                err := ti.AddWithRecord(item.(*geoindex.GeographicRecord))
+                log.PanicIf(err)
+            }
+        }
+    }
+
+    return ti, nil
+}
+
+// loadLocationSeries parses a single data file into its own series.
+func loadLocationSeries(lsf locationSourceFile) (lds locationDatabaseSeries, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    ti := geoindex.NewTimeIndex()
+    gc := geoindex.NewGeographicCollector(ti, nil)
+
+    err = geoindex.RegisterDataFileProcessors(gc)
+    log.PanicIf(err)
+
+    err = gc.ReadFromPath(lsf.Filepath)
+    log.PanicIf(err)
+
+    lds = locationDatabaseSeries{
+        Source: lsf,
+        Series: ti.Series(),
+    }
+
+    return lds, nil
+}
+
+// FindDataFiles returns the sorted file-paths of all location data files under
+// the given paths.
+func FindDataFiles(paths []string) (filepaths []string, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    gc := geoindex.NewGeographicCollector(nil, nil)
+
+    err = geoindex.RegisterDataFileProcessors(gc)
+    log.PanicIf(err)
+
+    for _, scanPath := range paths {
+        err := gc.ReadFromPath(scanPath)
+        log.PanicIf(err)
+    }
+
+    filepaths = gc.VisitedFilepaths()
+
+    sortedFiles := sort.StringSlice(filepaths)
+    sortedFiles.Sort()
+
+    return filepaths, nil
+}
+
+// getFileSha1 returns the SHA1 of the contents of the given file.
+func getFileSha1(filepath string) (fileSha1 []byte, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    f, err := os.Open(filepath)
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    h := sha1.New()
+
+    _, err = io.Copy(h, f)
+    log.PanicIf(err)
+
+    return h.Sum(nil), nil
+}
+
+// getLocationSourceManifest stats the given files and produces a manifest for
+// them. Hashes are carried over from `previous` for any file whose size and
+// modification-time have not changed. Everything else is hashed.
+func getLocationSourceManifest(filepaths []string, previous []locationSourceFile) (manifest []locationSourceFile, rehashed int, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    previousIndex := make(map[string]locationSourceFile)
+    for _, lsf := range previous {
+        previousIndex[lsf.Filepath] = lsf
+    }
+
+    manifest = make([]locationSourceFile, len(filepaths))
+    for i, filepath := range filepaths {
+        fi, err := os.Stat(filepath)
+        log.PanicIf(err)
+
+        lsf := locationSourceFile{
+            Filepath: filepath,
+            Size:     fi.Size(),
+            ModTime:  fi.ModTime().UnixNano(),
+        }
+
+        if previousLsf, found := previousIndex[filepath]; found == true && previousLsf.Size == lsf.Size && previousLsf.ModTime == lsf.ModTime {
+            lsf.Sha1 = previousLsf.Sha1
+        } else {
+            lsf.Sha1, err = getFileSha1(filepath)
+            log.PanicIf(err)
+
+            rehashed++
+        }
+
+        manifest[i] = lsf
+    }
+
+    return manifest, rehashed, nil
+}
+
+// hasLocationSourceStatChanges indicates whether any file has the same content
+// as before but a different size or modification-time.
+func hasLocationSourceStatChanges(previous, current []locationSourceFile) bool {
+    previousIndex := make(map[string]locationSourceFile)
+    for _, lsf := range previous {
+        previousIndex[lsf.Filepath] = lsf
+    }
+
+    for _, lsf := range current {
+        previousLsf, found := previousIndex[lsf.Filepath]
+        if found == false || bytes.Compare(previousLsf.Sha1, lsf.Sha1) != 0 {
+            continue
+        }
+
+        if previousLsf.Size != lsf.Size || previousLsf.ModTime != lsf.ModTime {
+            return true
+        }
+    }
+
+    return false
+}
+
+// diffLocationSourceManifests returns the file-paths whose records must be
+// thrown away (changed or removed) and the file-paths that must be loaded
+// (changed or added).
+func diffLocationSourceManifests(previous, current []locationSourceFile) (stale map[string]struct{}, load []string) {
+    stale = make(map[string]struct{})
+    load = make([]string, 0)
+
+    previousIndex := make(map[string]locationSourceFile)
+    for _, lsf := range previous {
+        previousIndex[lsf.Filepath] = lsf
+    }
+
+    currentIndex := make(map[string]struct{})
+    for _, lsf := range current {
+        currentIndex[lsf.Filepath] = struct{}{}
+
+        previousLsf, found := previousIndex[lsf.Filepath]
+        if found == false {
+            load = append(load, lsf.Filepath)
+        } else if bytes.Compare(previousLsf.Sha1, lsf.Sha1) != 0 {
+            stale[lsf.Filepath] = struct{}{}
+            load = append(load, lsf.Filepath)
+        }
+    }
+
+    for _, lsf := range previous {
+        if _, found := currentIndex[lsf.Filepath]; found == false {
+            stale[lsf.Filepath] = struct{}{}
+        }
+    }
+
+    return stale, load
+}
@@ -0,0 +1,292 @@
+package geoautogroup
+
+import (
+    "fmt"
+    "os"
+    "path"
+    "sort"
+    "testing"
+    "time"
+
+    "io/ioutil"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+)
+
+func TestDiffLocationSourceManifests(t *testing.T) {
+    previous := []locationSourceFile{
+        {Filepath: "unchanged.gpx", Sha1: []byte{1}},
+        {Filepath: "changed.gpx", Sha1: []byte{2}},
+        {Filepath: "removed.gpx", Sha1: []byte{3}},
+    }
+
+    current := []locationSourceFile{
+        {Filepath: "unchanged.gpx", Sha1: []byte{1}},
+        {Filepath: "changed.gpx", Sha1: []byte{22}},
+        {Filepath: "added.gpx", Sha1: []byte{4}},
+    }
+
+    stale, load := diffLocationSourceManifests(previous, current)
+
+    if len(stale) != 2 {
+        t.Fatalf("Expected exactly two stale files: %v", stale)
+    } else if _, found := stale["changed.gpx"]; found == false {
+        t.Fatalf("Changed file not marked as stale.")
+    } else if _, found := stale["removed.gpx"]; found == false {
+        t.Fatalf("Removed file not marked as stale.")
+    }
+
+    sort.Strings(load)
+
+    if len(load) != 2 || load[0] != "added.gpx" || load[1] != "changed.gpx" {
+        t.Fatalf("Files to load not correct: %v", load)
+    }
+}
+
+func TestGetLocationTimeIndex_SeriesPerFile(t *testing.T) {
+    defer func() {
+        if state := recover(); state != nil {
+            err := log.Wrap(state.(error))
+            log.PrintError(err)
+
+            t.Fatalf("Test failed.")
+        }
+    }()
+
+    sourcesPath, err := ioutil.TempDir("", "")
+    log.PanicIf(err)
+
+    defer os.RemoveAll(sourcesPath)
+
+    copyTestAsset := func(assetRelFilepath, filename string) {
+        data, err := ioutil.ReadFile(path.Join(testAssetsPath, assetRelFilepath))
+        log.PanicIf(err)
+
+        err = ioutil.WriteFile(path.Join(sourcesPath, filename), data, 0644)
+        log.PanicIf(err)
+    }
+
+    copyTestAsset("test_sources_path2/19020100_Portugal.CSV.gpx", "first.gpx")
+
+    f, err := ioutil.TempFile("", "")
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    filepath := f.Name()
+
+    paths := []string{
+        sourcesPath,
+    }
+
+    _, _, dbUpdated, err := GetLocationTimeIndex(paths, filepath, false)
+    log.PanicIf(err)
+
+    if dbUpdated == false {
+        t.Fatalf("DB is supposed to have changed.")
+    }
+
+    // Add a second file. This should add a second series.
+
+    copyTestAsset("test_sources_path1/19020100_Portugal.CSV.gpx", "second.gpx")
+
+    ti, _, dbUpdated, err := GetLocationTimeIndex(paths, filepath, false)
+    log.PanicIf(err)
+
+    if dbUpdated == false {
+        t.Fatalf("DB is supposed to have changed after adding a file.")
+    }
+
+    if len(ti.Series()) < 254 {
+        t.Fatalf("Merged index is missing records: (%d)", len(ti.Series()))
+    }
+
+    g, err := os.Open(filepath)
+    log.PanicIf(err)
+
+    defer g.Close()
+
+    stored, err := readLocationDatabase(g)
+    log.PanicIf(err)
+
+    if len(stored) != 2 {
+        t.Fatalf("Expected one series per file: (%d)", len(stored))
+    }
+
+    for _, lds := range stored {
+        filename := path.Base(lds.Source.Filepath)
+        if filename != "first.gpx" && filename != "second.gpx" {
+            t.Fatalf("Series source not correct: [%s]", lds.Source.Filepath)
+        }
+    }
+
+    // Remove the first file. Only the second series should remain.
+
+    err = os.Remove(path.Join(sourcesPath, "first.gpx"))
+    log.PanicIf(err)
+
+    ti, _, dbUpdated, err = GetLocationTimeIndex(paths, filepath, false)
+    log.PanicIf(err)
+
+    if dbUpdated == false {
+        t.Fatalf("DB is supposed to have changed after removing a file.")
+    } else if len(ti.Series()) != 254 {
+        t.Fatalf("Record count after removal not correct: (%d)", len(ti.Series()))
+    }
+}
+
+func TestFindLocationGaps(t *testing.T) {
+    ti := geoindex.NewTimeIndex()
+
+    timestamps := []time.Time{
+        epochUtc,
+        epochUtc.Add(time.Hour),
+        epochUtc.Add(time.Hour * 12),
+        epochUtc.Add(time.Hour * 13),
+        epochUtc.Add(time.Hour * 30),
+    }
+
+    for i, timestamp := range timestamps {
+        gr := geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, fmt.Sprintf("file%d.gpx", i), timestamp, true, 1.1, 10.1, nil)
+
+        err := ti.AddWithRecord(gr)
+        log.PanicIf(err)
+    }
+
+    gaps := FindLocationGaps(ti.Series(), time.Hour*8)
+
+    if len(gaps) != 2 {
+        t.Fatalf("Expected exactly two gaps: (%d)", len(gaps))
+    }
+
+    if gaps[0].Before.Filepath != "file1.gpx" || gaps[0].After.Filepath != "file2.gpx" {
+        t.Fatalf("First gap not correct: [%s] [%s]", gaps[0].Before.Filepath, gaps[0].After.Filepath)
+    } else if gaps[0].Duration() != time.Hour*11 {
+        t.Fatalf("First gap duration not correct: [%s]", gaps[0].Duration())
+    }
+
+    if gaps[1].Before.Filepath != "file3.gpx" || gaps[1].After.Filepath != "file4.gpx" {
+        t.Fatalf("Second gap not correct: [%s] [%s]", gaps[1].Before.Filepath, gaps[1].After.Filepath)
+    }
+}
+
+func TestPruneLocationDatabase(t *testing.T) {
+    defer func() {
+        if state := recover(); state != nil {
+            err := log.Wrap(state.(error))
+            log.PrintError(err)
+
+            t.Fatalf("Test failed.")
+        }
+    }()
+
+    paths := []string{
+        path.Join(testAssetsPath, "test_sources_path1"),
+    }
+
+    f, err := ioutil.TempFile("", "")
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    filepath := f.Name()
+
+    _, _, _, err = GetLocationTimeIndex(paths, filepath, false)
+    log.PanicIf(err)
+
+    // Remove everything after the first record.
+
+    start := time.Unix(1549002149, 0)
+    end := time.Unix(1549024530, 0)
+
+    removed, err := PruneLocationDatabase(filepath, start, end)
+    log.PanicIf(err)
+
+    if removed == 0 {
+        t.Fatalf("Expected records to be pruned.")
+    }
+
+    infos, err := GetLocationDatabaseInfo(filepath)
+    log.PanicIf(err)
+
+    if len(infos) != 1 {
+        t.Fatalf("Expected exactly one series: (%d)", len(infos))
+    } else if infos[0].RecordCount != 1 {
+        t.Fatalf("Expected exactly one record to remain: (%d)", infos[0].RecordCount)
+    } else if infos[0].PrunedRanges != 1 {
+        t.Fatalf("Expected the pruned range to be recorded: (%d)", infos[0].PrunedRanges)
+    } else if infos[0].ChecksumOk == false {
+        t.Fatalf("Checksum failed after prune.")
+    }
+
+    // The data file hasn't changed, so the prune should stick.
+
+    ti, _, dbUpdated, err := GetLocationTimeIndex(paths, filepath, false)
+    log.PanicIf(err)
+
+    if dbUpdated == true {
+        t.Fatalf("DB is supposed to not have changed.")
+    } else if len(ti.Series()) != 1 {
+        t.Fatalf("Pruned records came back: (%d)", len(ti.Series()))
+    }
+}
+
+func TestGetLocationDatabaseInfo_CorruptSeries(t *testing.T) {
+    defer func() {
+        if state := recover(); state != nil {
+            err := log.Wrap(state.(error))
+            log.PrintError(err)
+
+            t.Fatalf("Test failed.")
+        }
+    }()
+
+    paths := []string{
+        path.Join(testAssetsPath, "test_sources_path1"),
+    }
+
+    f, err := ioutil.TempFile("", "")
+    log.PanicIf(err)
+
+    defer os.Remove(f.Name())
+
+    filepath := f.Name()
+
+    f.Close()
+
+    _, _, _, err = GetLocationTimeIndex(paths, filepath, false)
+    log.PanicIf(err)
+
+    // Clobber the data at the front of the first series. The footers are at
+    // the end of the stream and are left alone.
+
+    f, err = os.OpenFile(filepath, os.O_RDWR, 0644)
+    log.PanicIf(err)
+
+    garbage := make([]byte, 32)
+    for i := range garbage {
+        garbage[i] = 0xff
+    }
+
+    _, err = f.WriteAt(garbage, 4)
+    log.PanicIf(err)
+
+    f.Close()
+
+    infos, err := GetLocationDatabaseInfo(filepath)
+    log.PanicIf(err)
+
+    if len(infos) == 0 {
+        t.Fatalf("Expected the series to still be described.")
+    } else if infos[0].Ok() != false {
+        t.Fatalf("Corrupt series was not flagged: %v", infos[0])
+    }
+
+    // Reading the records is still an error.
+
+    _, err = ReadLocationDatabase(filepath)
+    if err == nil {
+        t.Fatalf("Expected an error reading a corrupt database.")
+    }
+}
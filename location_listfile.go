@@ -0,0 +1,684 @@
+package geoautogroup
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "encoding/csv"
+
+    "github.com/dsoprea/go-geographic-attractor/index"
+    "github.com/dsoprea/go-geographic-attractor/parse"
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-index"
+)
+
+const (
+    // ListfileVersionKeyword is the first column of the first record of a
+    // versioned list-file. The second column is the version. A list-file
+    // without this record is read as version 1.
+    ListfileVersionKeyword = "listfile"
+
+    // ListfileDataPriority is the priority of records loaded from data files.
+    ListfileDataPriority = 0
+
+    // ListfileDefaultPriority is the priority of list-file rows that don't
+    // provide one.
+    ListfileDefaultPriority = 10
+)
+
+const (
+    ListfileKindId          = "id"
+    ListfileKindCoordinates = "coordinates"
+    ListfileKindCity        = "city"
+)
+
+var (
+    // ListfilePointPriorityWindow is how far on either side of a single-point
+    // list-file row that lower-priority location records are suppressed.
+    ListfilePointPriorityWindow = time.Minute * 10
+
+    // ListfileRangeInterval is how far apart the points of a range row are.
+    // Since the range suppresses everything within it, this has to be small
+    // enough that every image in the range is within the matcher's rounding
+    // window of a point.
+    ListfileRangeInterval = LocationMatchRoundingWindowDuration
+)
+
+var (
+    ErrListfileCityNotFound = errors.New("list-file city not found")
+)
+
+// LocationListfileEntry is one row of a list-file.
+type LocationListfileEntry struct {
+    // Filepath is the list-file that the row came from.
+    Filepath string
+
+    // Row is the number of the row in the list-file, not counting comments or
+    // the version record.
+    Row int
+
+    // Description describes where the coordinates came from.
+    Description string
+
+    Latitude  float64
+    Longitude float64
+
+    Start time.Time
+
+    // End is the end of the period if the row is a range. This is the same as
+    // `Start` for single points.
+    End time.Time
+
+    Priority int
+    Comment  string
+}
+
+// IsRange indicates whether the row describes a period rather than a point.
+func (lle LocationListfileEntry) IsRange() bool {
+    return lle.End.Equal(lle.Start) == false
+}
+
+// period returns the period that this row takes precedence over. Single points
+// cover a small window on either side.
+func (lle LocationListfileEntry) period() (start, end time.Time) {
+    if lle.IsRange() == false {
+        return lle.Start.Add(-ListfilePointPriorityWindow), lle.End.Add(ListfilePointPriorityWindow)
+    }
+
+    return lle.Start, lle.End
+}
+
+// Covers indicates whether the given time is within the period that this row
+// takes precedence over.
+func (lle LocationListfileEntry) Covers(t time.Time) bool {
+    start, end := lle.period()
+
+    return t.Before(start) == false && t.After(end) == false
+}
+
+// Records returns the location records for the row: one for a point or one
+// every `ListfileRangeInterval` from the start of a range through its end.
+func (lle LocationListfileEntry) Records() []*geoindex.GeographicRecord {
+    timestamps := []time.Time{lle.Start}
+    if lle.IsRange() == true {
+        for t := lle.Start.Add(ListfileRangeInterval); t.Before(lle.End) == true; t = t.Add(ListfileRangeInterval) {
+            timestamps = append(timestamps, t)
+        }
+
+        timestamps = append(timestamps, lle.End)
+    }
+
+    records := make([]*geoindex.GeographicRecord, len(timestamps))
+    for i, timestamp := range timestamps {
+        gr := geoindex.NewGeographicRecord(
+            GeographicSourceListfile,
+            lle.Filepath,
+            timestamp,
+            true,
+            lle.Latitude,
+            lle.Longitude,
+            nil)
+
+        gr.AddComment(fmt.Sprintf("List-file [%s] row (%d): %s", lle.Filepath, lle.Row, lle.Description))
+
+        if lle.Comment != "" {
+            gr.AddComment(lle.Comment)
+        }
+
+        records[i] = gr
+    }
+
+    return records
+}
+
+func (lle LocationListfileEntry) String() string {
+    return fmt.Sprintf("LocationListfileEntry<FILE=[%s] ROW=(%d) START=[%s] END=[%s] PRIORITY=(%d) LOCATION=[%s]>", lle.Filepath, lle.Row, lle.Start.Format(time.RFC3339), lle.End.Format(time.RFC3339), lle.Priority, lle.Description)
+}
+
+// cityNameQuery is a city name and, optionally, the country code that it
+// should be in.
+type cityNameQuery struct {
+    name        string
+    countryCode string
+}
+
+func parseCityNameQuery(phrase string) cityNameQuery {
+    cnq := cityNameQuery{
+        name: strings.TrimSpace(phrase),
+    }
+
+    if i := strings.LastIndex(phrase, ","); i != -1 {
+        countryCode := strings.TrimSpace(phrase[i+1:])
+        if len(countryCode) == 2 {
+            cnq.name = strings.TrimSpace(phrase[:i])
+            cnq.countryCode = strings.ToUpper(countryCode)
+        }
+    }
+
+    return cnq
+}
+
+type cityNameMatch struct {
+    id         string
+    name       string
+    latitude   float64
+    longitude  float64
+    population int64
+}
+
+// CityNameResolver finds cities by name in the GeoNames cities file. Only
+// populated places (feature-class "P") are considered. If a name matches more
+// than one city, the city with the largest population wins.
+type CityNameResolver struct {
+    citiesFilepath string
+}
+
+func NewCityNameResolver(citiesFilepath string) *CityNameResolver {
+    return &CityNameResolver{
+        citiesFilepath: citiesFilepath,
+    }
+}
+
+// resolve searches for all of the given names in one pass over the cities
+// file. Names that can't be found will not be in the result.
+func (cnr *CityNameResolver) resolve(queries []cityNameQuery) (matches map[cityNameQuery]cityNameMatch, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    matches = make(map[cityNameQuery]cityNameMatch)
+
+    if len(queries) == 0 {
+        return matches, nil
+    }
+
+    byName := make(map[string][]cityNameQuery)
+    for _, cnq := range queries {
+        key := strings.ToLower(cnq.name)
+        byName[key] = append(byName[key], cnq)
+    }
+
+    g, err := geoattractorparse.GetCitydataReadCloser(cnr.citiesFilepath)
+    log.PanicIf(err)
+
+    defer g.Close()
+
+    s := bufio.NewScanner(g)
+    s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+    for s.Scan() {
+        parts := strings.Split(s.Text(), "\t")
+        if len(parts) < 15 {
+            continue
+        }
+
+        // Only populated places. Otherwise, a state or region that shares its
+        // name with a city (and has a larger population) would win.
+        if parts[6] != "P" {
+            continue
+        }
+
+        names := []string{parts[1], parts[2]}
+        if parts[3] != "" {
+            names = append(names, strings.Split(parts[3], ",")...)
+        }
+
+        seen := make(map[string]struct{})
+        for _, name := range names {
+            key := strings.ToLower(name)
+            if _, found := seen[key]; found == true {
+                continue
+            }
+
+            seen[key] = struct{}{}
+
+            candidates, found := byName[key]
+            if found == false {
+                continue
+            }
+
+            latitude, err := strconv.ParseFloat(parts[4], 64)
+            if err != nil {
+                continue
+            }
+
+            longitude, err := strconv.ParseFloat(parts[5], 64)
+            if err != nil {
+                continue
+            }
+
+            // The population is often empty.
+            population, _ := strconv.ParseInt(parts[14], 10, 64)
+
+            cnm := cityNameMatch{
+                id:         parts[0],
+                name:       parts[1],
+                latitude:   latitude,
+                longitude:  longitude,
+                population: population,
+            }
+
+            for _, cnq := range candidates {
+                if cnq.countryCode != "" && cnq.countryCode != parts[8] {
+                    continue
+                }
+
+                if existing, found := matches[cnq]; found == false || cnm.population > existing.population {
+                    matches[cnq] = cnm
+                }
+            }
+        }
+    }
+
+    err = s.Err()
+    log.PanicIf(err)
+
+    return matches, nil
+}
+
+// ReadLocationListfile parses a list-file. `cnr` is only required if the
+// list-file has city-name rows.
+//
+// Version 1 list-files have three columns: the source name and ID of a city
+// in the city index and an RFC3339 timestamp.
+//
+// Version 2 list-files start with a "listfile,2" record and then have rows
+// with up to six columns:
+//
+//    kind,location,start,end,priority,comment
+//
+// The kind is "id" (location is "<source>:<ID>"), "coordinates" (location is
+// "<latitude>,<longitude>"), or "city" (location is a city name with an
+// optional ", <country code>" suffix). Since coordinates, and city names with a
+// country code, contain a comma, the location has to be quoted:
+//
+//    coordinates,"41.8781,-87.6298",2019-02-10T05:00:00-05:00
+//    city,"Porto, PT",2019-01-01T00:00:00Z,2019-01-05T00:00:00Z
+//
+// If an end timestamp is given then points are created from the start through
+// the end every `ListfileRangeInterval`. Rows with a higher priority win over
+// lower-priority rows and data-file points (priority zero) for the same
+// period. The priority defaults to `ListfileDefaultPriority`.
+func ReadLocationListfile(ci *geoattractorindex.CityIndex, cnr *CityNameResolver, filepath string, r io.Reader) (entries []LocationListfileEntry, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    c := csv.NewReader(r)
+
+    c.Comment = '#'
+    c.FieldsPerRecord = -1
+    c.TrimLeadingSpace = true
+
+    entries = make([]LocationListfileEntry, 0)
+    cityQueries := make(map[int]cityNameQuery)
+    version := 0
+
+    for {
+        record, err := c.Read()
+        if err != nil {
+            if err == io.EOF {
+                break
+            }
+
+            log.Panic(err)
+        }
+
+        if version == 0 {
+            if record[0] == ListfileVersionKeyword {
+                if len(record) != 2 {
+                    log.Panicf("list-file [%s]: version record should have two columns", filepath)
+                }
+
+                version, err = strconv.Atoi(record[1])
+                if err != nil || version != 2 {
+                    log.Panicf("list-file [%s]: version not supported: [%s]", filepath, record[1])
+                }
+
+                continue
+            }
+
+            version = 1
+        }
+
+        var lle LocationListfileEntry
+        if version == 1 {
+            lle, err = parseListfileRowV1(ci, record)
+        } else {
+            var cnq *cityNameQuery
+            lle, cnq, err = parseListfileRowV2(ci, record)
+
+            if err == nil && cnq != nil {
+                cityQueries[len(entries)] = *cnq
+            }
+        }
+
+        if err != nil {
+            log.Panicf("list-file [%s] row (%d): %s", filepath, len(entries)+1, err.Error())
+        }
+
+        lle.Filepath = filepath
+        lle.Row = len(entries) + 1
+
+        entries = append(entries, lle)
+    }
+
+    if len(cityQueries) > 0 {
+        if cnr == nil {
+            log.Panicf("list-file [%s] has city-name rows but no cities file is available to search", filepath)
+        }
+
+        queries := make([]cityNameQuery, 0, len(cityQueries))
+        for _, cnq := range cityQueries {
+            queries = append(queries, cnq)
+        }
+
+        matches, err := cnr.resolve(queries)
+        log.PanicIf(err)
+
+        for i, cnq := range cityQueries {
+            cnm, found := matches[cnq]
+            if found == false {
+                log.Panicf("list-file [%s] row (%d): city [%s] (country [%s]): %s", filepath, entries[i].Row, cnq.name, cnq.countryCode, ErrListfileCityNotFound.Error())
+            }
+
+            entries[i].Latitude = cnm.latitude
+            entries[i].Longitude = cnm.longitude
+            entries[i].Description = fmt.Sprintf("City [%s] resolved to [%s] (GeoNames %s)", cnq.name, cnm.name, cnm.id)
+        }
+    }
+
+    return entries, nil
+}
+
+func lookupListfileCity(ci *geoattractorindex.CityIndex, sourceName, id string) (lle LocationListfileEntry, err error) {
+    cr, err := ci.GetById(sourceName, id)
+    if err != nil {
+        if err == geoattractorindex.ErrNotFound {
+            return lle, fmt.Errorf("could not find record from source [%s] with ID [%s]", sourceName, id)
+        }
+
+        return lle, err
+    }
+
+    lle = LocationListfileEntry{
+        Description: fmt.Sprintf("City [%s] (%s %s)", cr.CityAndProvinceState(), sourceName, id),
+        Latitude:    cr.Latitude,
+        Longitude:   cr.Longitude,
+        Priority:    ListfileDefaultPriority,
+    }
+
+    return lle, nil
+}
+
+func parseListfileRowV1(ci *geoattractorindex.CityIndex, record []string) (lle LocationListfileEntry, err error) {
+    if len(record) != 3 {
+        return lle, fmt.Errorf("expected three columns but found (%d)", len(record))
+    }
+
+    sourceName := record[0]
+    id := record[1]
+    timestampPhrase := record[2]
+
+    timestamp, err := time.Parse(time.RFC3339, timestampPhrase)
+    if err != nil {
+        return lle, fmt.Errorf("could not parse [%s]: %s", timestampPhrase, err)
+    }
+
+    lle, err = lookupListfileCity(ci, sourceName, id)
+    if err != nil {
+        return lle, err
+    }
+
+    lle.Start = timestamp
+    lle.End = timestamp
+
+    return lle, nil
+}
+
+func parseListfileRowV2(ci *geoattractorindex.CityIndex, record []string) (lle LocationListfileEntry, cnq *cityNameQuery, err error) {
+    if len(record) < 3 || len(record) > 6 {
+        return lle, nil, fmt.Errorf("expected between three and six columns but found (%d)", len(record))
+    }
+
+    // Pad the optional columns.
+    for len(record) < 6 {
+        record = append(record, "")
+    }
+
+    kind := record[0]
+    location := record[1]
+    startPhrase := record[2]
+    endPhrase := record[3]
+    priorityPhrase := record[4]
+    comment := record[5]
+
+    switch kind {
+    case ListfileKindId:
+        parts := strings.SplitN(location, ":", 2)
+        if len(parts) != 2 {
+            return lle, nil, fmt.Errorf("ID should look like \"<source>:<ID>\": [%s]", location)
+        }
+
+        lle, err = lookupListfileCity(ci, parts[0], parts[1])
+        if err != nil {
+            return lle, nil, err
+        }
+    case ListfileKindCoordinates:
+        parts := strings.Split(location, ",")
+        if len(parts) != 2 {
+            return lle, nil, fmt.Errorf("coordinates should look like \"<latitude>,<longitude>\": [%s]", location)
+        }
+
+        latitude, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+        if err != nil || latitude < -90 || latitude > 90 {
+            return lle, nil, fmt.Errorf("latitude not valid: [%s]", parts[0])
+        }
+
+        longitude, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+        if err != nil || longitude < -180 || longitude > 180 {
+            return lle, nil, fmt.Errorf("longitude not valid: [%s]", parts[1])
+        }
+
+        lle = LocationListfileEntry{
+            Description: fmt.Sprintf("Coordinates (%.6f, %.6f)", latitude, longitude),
+            Latitude:    latitude,
+            Longitude:   longitude,
+        }
+    case ListfileKindCity:
+        query := parseCityNameQuery(location)
+        if query.name == "" {
+            return lle, nil, fmt.Errorf("city name is empty")
+        }
+
+        // The coordinates are filled after all of the rows are read.
+        cnq = &query
+    default:
+        return lle, nil, fmt.Errorf("kind not valid: [%s]", kind)
+    }
+
+    lle.Start, err = time.Parse(time.RFC3339, startPhrase)
+    if err != nil {
+        return lle, nil, fmt.Errorf("could not parse start [%s]: %s", startPhrase, err)
+    }
+
+    lle.End = lle.Start
+    if endPhrase != "" {
+        lle.End, err = time.Parse(time.RFC3339, endPhrase)
+        if err != nil {
+            return lle, nil, fmt.Errorf("could not parse end [%s]: %s", endPhrase, err)
+        }
+
+        if lle.End.Before(lle.Start) == true {
+            return lle, nil, fmt.Errorf("end [%s] is before start [%s]", endPhrase, startPhrase)
+        }
+    }
+
+    lle.Priority = ListfileDefaultPriority
+    if priorityPhrase != "" {
+        lle.Priority, err = strconv.Atoi(priorityPhrase)
+        if err != nil {
+            return lle, nil, fmt.Errorf("priority not valid: [%s]", priorityPhrase)
+        }
+    }
+
+    lle.Comment = comment
+
+    return lle, cnq, nil
+}
+
+// LoadLocationListFile allows the user to provide a custom list of locations
+// and timestamps. This can be used to patch buggy location data. The records
+// are added alongside whatever is already in the index; use
+// `ApplyLocationListfileEntries` to have them take priority.
+func LoadLocationListFile(ci *geoattractorindex.CityIndex, filepath string, r io.Reader, ti *geoindex.TimeIndex) (recordsCount int, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    entries, err := ReadLocationListfile(ci, nil, filepath, r)
+    log.PanicIf(err)
+
+    for _, lle := range entries {
+        for _, gr := range lle.Records() {
+            err := ti.AddWithRecord(gr)
+            log.PanicIf(err)
+
+            recordsCount++
+        }
+    }
+
+    return recordsCount, nil
+}
+
+// listfileInterval is the period that a row takes precedence over.
+type listfileInterval struct {
+    start time.Time
+    end   time.Time
+}
+
+// listfileCoverage is a set of non-overlapping intervals sorted by time.
+type listfileCoverage []listfileInterval
+
+func (lc listfileCoverage) Len() int {
+    return len(lc)
+}
+
+func (lc listfileCoverage) Swap(i, j int) {
+    lc[i], lc[j] = lc[j], lc[i]
+}
+
+func (lc listfileCoverage) Less(i, j int) bool {
+    return lc[i].start.Before(lc[j].start)
+}
+
+// newListfileCoverage returns the periods covered by the rows that have a
+// higher priority than `priority`.
+func newListfileCoverage(entries []LocationListfileEntry, priority int) listfileCoverage {
+    intervals := make(listfileCoverage, 0)
+    for _, lle := range entries {
+        if lle.Priority <= priority {
+            continue
+        }
+
+        start, end := lle.period()
+
+        intervals = append(intervals, listfileInterval{start: start, end: end})
+    }
+
+    sort.Sort(intervals)
+
+    // Merge the overlapping intervals so that a search only has to look at
+    // one.
+
+    lc := make(listfileCoverage, 0, len(intervals))
+    for _, li := range intervals {
+        if len(lc) > 0 && li.start.After(lc[len(lc)-1].end) == false {
+            if li.end.After(lc[len(lc)-1].end) == true {
+                lc[len(lc)-1].end = li.end
+            }
+
+            continue
+        }
+
+        lc = append(lc, li)
+    }
+
+    return lc
+}
+
+// covers indicates whether the given time is within any of the intervals.
+func (lc listfileCoverage) covers(t time.Time) bool {
+    // Find the first interval that ends at or after the time.
+    i := sort.Search(len(lc), func(i int) bool {
+        return lc[i].end.Before(t) == false
+    })
+
+    return i < len(lc) && lc[i].start.After(t) == false
+}
+
+// ApplyLocationListfileEntries returns a new index with the records in the
+// given index plus the records for the given list-file rows. Any record that
+// falls within the period of a row with a higher priority is left out.
+// Records from the given index have priority `ListfileDataPriority`.
+func ApplyLocationListfileEntries(locationTs timeindex.TimeSlice, entries []LocationListfileEntry) (ti *geoindex.TimeIndex, suppressed int, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    // The rows that outrank each priority, as merged intervals.
+    coverages := make(map[int]listfileCoverage)
+
+    isSuppressed := func(t time.Time, priority int) bool {
+        lc, found := coverages[priority]
+        if found == false {
+            lc = newListfileCoverage(entries, priority)
+            coverages[priority] = lc
+        }
+
+        return lc.covers(t)
+    }
+
+    ti = geoindex.NewTimeIndex()
+
+    for _, te := range locationTs {
+        if isSuppressed(te.Time, ListfileDataPriority) == true {
+            suppressed += len(te.Items)
+            continue
+        }
+
+        for _, item := range te.Items {
+            err := ti.AddWithRecord(item.(*geoindex.GeographicRecord))
+            log.PanicIf(err)
+        }
+    }
+
+    for _, lle := range entries {
+        for _, gr := range lle.Records() {
+            if isSuppressed(gr.Timestamp, lle.Priority) == true {
+                suppressed++
+                continue
+            }
+
+            err := ti.AddWithRecord(gr)
+            log.PanicIf(err)
+        }
+    }
+
+    return ti, suppressed, nil
+}
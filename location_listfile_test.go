@@ -0,0 +1,236 @@
+package geoautogroup
+
+import (
+    "bytes"
+    "os"
+    "path"
+    "strings"
+    "testing"
+    "time"
+
+    "io/ioutil"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-index"
+)
+
+func TestReadLocationListfile_V2(t *testing.T) {
+    ci := getTestCityIndex()
+
+    citiesFilepath := path.Join(testAssetsPath, "allCountries.txt.multiple_major_cities_handpicked")
+    cnr := NewCityNameResolver(citiesFilepath)
+
+    s := `
+listfile,2
+# kind,location,start,end,priority,comment
+id,GeoNames:2935022,2019-01-01T00:00:00Z
+coordinates,"41.85,-87.65",2019-01-02T00:00:00Z,2019-01-02T06:00:00Z,20,Conference
+city,"Chicago, US",2019-01-03T00:00:00Z,,,
+`
+
+    b := bytes.NewBufferString(s)
+
+    entries, err := ReadLocationListfile(ci, cnr, "testfile", b)
+    log.PanicIf(err)
+
+    if len(entries) != 3 {
+        t.Fatalf("Expected three entries: (%d)", len(entries))
+    }
+
+    if entries[0].IsRange() == true || entries[0].Priority != ListfileDefaultPriority || entries[0].Latitude != 51.05089 {
+        t.Fatalf("First entry not correct: %s", entries[0])
+    }
+
+    if entries[1].IsRange() == false || entries[1].Priority != 20 || entries[1].Comment != "Conference" {
+        t.Fatalf("Second entry not correct: %s", entries[1])
+    } else if len(entries[1].Records()) != int(time.Hour*6/ListfileRangeInterval)+1 {
+        t.Fatalf("Expected a record at each end of the range and at every interval between: (%d)", len(entries[1].Records()))
+    }
+
+    if entries[2].Latitude != 41.85003 || entries[2].Longitude != -87.65005 {
+        t.Fatalf("City name not resolved correctly: %s", entries[2])
+    }
+}
+
+func TestReadLocationListfile_V2_CityNotFound(t *testing.T) {
+    ci := getTestCityIndex()
+
+    citiesFilepath := path.Join(testAssetsPath, "allCountries.txt.multiple_major_cities_handpicked")
+    cnr := NewCityNameResolver(citiesFilepath)
+
+    s := `
+listfile,2
+city,"Chicago, DE",2019-01-03T00:00:00Z
+`
+
+    b := bytes.NewBufferString(s)
+
+    _, err := ReadLocationListfile(ci, cnr, "testfile", b)
+    if err == nil {
+        t.Fatalf("Expected failure for city in wrong country.")
+    }
+}
+
+func TestCityNameResolver_OnlyPopulatedPlaces(t *testing.T) {
+    tempPath, err := ioutil.TempDir("", "")
+    log.PanicIf(err)
+
+    defer os.RemoveAll(tempPath)
+
+    // The state has the larger population and would win if it were
+    // considered.
+    rows := [][]string{
+        {"5128638", "New York", "New York", "NY,State of New York", "43.00035", "-75.4999", "A", "ADM1", "US", "", "NY", "", "", "", "19274244", "", "307", "America/New_York", "2019-01-01"},
+        {"5128581", "New York City", "New York City", "NYC,New York", "40.71427", "-74.00597", "P", "PPL", "US", "", "NY", "", "", "", "8175133", "", "10", "America/New_York", "2019-01-01"},
+    }
+
+    lines := make([]string, len(rows))
+    for i, row := range rows {
+        lines[i] = strings.Join(row, "\t")
+    }
+
+    citiesFilepath := path.Join(tempPath, "cities.txt")
+
+    err = ioutil.WriteFile(citiesFilepath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+    log.PanicIf(err)
+
+    cnr := NewCityNameResolver(citiesFilepath)
+
+    cnq := parseCityNameQuery("New York, US")
+
+    matches, err := cnr.resolve([]cityNameQuery{cnq})
+    log.PanicIf(err)
+
+    cnm, found := matches[cnq]
+    if found == false {
+        t.Fatalf("City not found.")
+    } else if cnm.id != "5128581" {
+        t.Fatalf("Expected the city rather than the state: [%s] [%s]", cnm.id, cnm.name)
+    }
+}
+
+func TestApplyLocationListfileEntries(t *testing.T) {
+    locationTi := geoindex.NewTimeIndex()
+
+    // Suppressed by the point.
+    gr := geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc.Add(time.Minute*5), true, chicagoCoordinates[0], chicagoCoordinates[1], nil)
+    locationTi.AddWithRecord(gr)
+
+    // Outside of the point's window.
+    gr = geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc.Add(time.Minute*30), true, chicagoCoordinates[0], chicagoCoordinates[1], nil)
+    locationTi.AddWithRecord(gr)
+
+    // Suppressed by the range.
+    gr = geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc.Add(time.Hour*3), true, chicagoCoordinates[0], chicagoCoordinates[1], nil)
+    locationTi.AddWithRecord(gr)
+
+    entries := []LocationListfileEntry{
+        {
+            Latitude:  detroitCoordinates[0],
+            Longitude: detroitCoordinates[1],
+            Start:     epochUtc,
+            End:       epochUtc,
+            Priority:  ListfileDefaultPriority,
+        },
+        {
+            Latitude:  nycCoordinates[0],
+            Longitude: nycCoordinates[1],
+            Start:     epochUtc.Add(time.Hour * 2),
+            End:       epochUtc.Add(time.Hour * 4),
+            Priority:  ListfileDefaultPriority,
+        },
+    }
+
+    ti, suppressed, err := ApplyLocationListfileEntries(locationTi.Series(), entries)
+    log.PanicIf(err)
+
+    if suppressed != 2 {
+        t.Fatalf("Expected two records to be suppressed: (%d)", suppressed)
+    }
+
+    // The range has a point every ten minutes from its start through its end.
+    ts := ti.Series()
+    if len(ts) != 15 {
+        t.Fatalf("Expected fifteen records: (%d)", len(ts))
+    }
+
+    if ts[1].Time.Equal(epochUtc.Add(time.Minute*30)) == false {
+        t.Fatalf("Unsuppressed record not retained: [%v]", ts[1].Time)
+    }
+}
+
+func TestApplyLocationListfileEntries_ImageInMiddleOfRange(t *testing.T) {
+    locationTi := geoindex.NewTimeIndex()
+
+    // Suppressed by the range.
+    gr := geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc.Add(time.Hour*3), true, chicagoCoordinates[0], chicagoCoordinates[1], nil)
+    locationTi.AddWithRecord(gr)
+
+    entries := []LocationListfileEntry{
+        {
+            Latitude:  nycCoordinates[0],
+            Longitude: nycCoordinates[1],
+            Start:     epochUtc.Add(time.Hour * 2),
+            End:       epochUtc.Add(time.Hour * 4),
+            Priority:  ListfileDefaultPriority,
+        },
+    }
+
+    ti, _, err := ApplyLocationListfileEntries(locationTi.Series(), entries)
+    log.PanicIf(err)
+
+    fg := NewFindGroups(ti.Series(), nil, nil)
+
+    // This is an hour from either end of the range.
+    imageTe := timeindex.TimeEntry{
+        Time: epochUtc.Add(time.Hour*3 + time.Minute*7),
+    }
+
+    matchedTe, err := fg.findLocationByTimeBestGuess(imageTe)
+    log.PanicIf(err)
+
+    matchedGr := matchedTe.Items[0].(*geoindex.GeographicRecord)
+    if matchedGr.Latitude != nycCoordinates[0] || matchedGr.Longitude != nycCoordinates[1] {
+        t.Fatalf("Image not matched to the range: (%.6f, %.6f)", matchedGr.Latitude, matchedGr.Longitude)
+    }
+}
+
+func TestListfileCoverage_Covers(t *testing.T) {
+    entries := []LocationListfileEntry{
+        {Start: epochUtc.Add(time.Hour * 5), End: epochUtc.Add(time.Hour * 6), Priority: 20},
+        {Start: epochUtc.Add(time.Hour * 2), End: epochUtc.Add(time.Hour * 4), Priority: 10},
+        {Start: epochUtc.Add(time.Hour * 3), End: epochUtc.Add(time.Hour * 5), Priority: 10},
+        {Start: epochUtc, End: epochUtc, Priority: 10},
+    }
+
+    lc := newListfileCoverage(entries, ListfileDataPriority)
+
+    // The overlapping ranges are merged.
+    if len(lc) != 2 {
+        t.Fatalf("Expected two intervals: (%d)", len(lc))
+    }
+
+    expected := map[time.Duration]bool{
+        -time.Minute * 11: false,
+        -time.Minute * 10: true,
+        time.Minute * 10:  true,
+        time.Minute * 11:  false,
+        time.Hour * 2:     true,
+        time.Hour * 5:     true,
+        time.Hour * 6:     true,
+        time.Hour*6 + 1:   false,
+    }
+
+    for offset, isCovered := range expected {
+        if lc.covers(epochUtc.Add(offset)) != isCovered {
+            t.Fatalf("Coverage of (%s) not correct: %v", offset, isCovered == false)
+        }
+    }
+
+    // Only the row with priority 20 outranks priority 10.
+    lc = newListfileCoverage(entries, ListfileDefaultPriority)
+    if lc.covers(epochUtc.Add(time.Hour*3)) != false || lc.covers(epochUtc.Add(time.Hour*5)) != true {
+        t.Fatalf("Coverage above priority not correct.")
+    }
+}
@@ -0,0 +1,218 @@
+package geoautogroup
+
+import (
+    "os"
+    "time"
+
+    "encoding/hex"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-index"
+)
+
+var (
+    locationMaintenanceLogger = log.NewLogger("geoautogroup.location_maintenance")
+)
+
+// LocationDatabaseSeriesInfo summarizes one series in the location database.
+type LocationDatabaseSeriesInfo struct {
+    SourceFilepath string    `json:"source_filepath"`
+    SourceSha1     string    `json:"source_sha1"`
+    SeriesSha1     string    `json:"series_sha1"`
+    HeadTime       time.Time `json:"head_time"`
+    TailTime       time.Time `json:"tail_time"`
+    RecordCount    int       `json:"record_count"`
+    PrunedRanges   int       `json:"pruned_ranges"`
+    ChecksumOk     bool      `json:"checksum_ok"`
+
+    // DecodeError is set if the series could not be decoded. The other fields
+    // will be empty and `ChecksumOk` will be false.
+    DecodeError string `json:"decode_error,omitempty"`
+}
+
+// Ok returns true if the series was decoded and its checksum matched.
+func (ldsi LocationDatabaseSeriesInfo) Ok() bool {
+    return ldsi.DecodeError == "" && ldsi.ChecksumOk == true
+}
+
+// GetLocationDatabaseInfo describes every series in the location database. The
+// checksum of every series is checked but nothing is loaded from the data
+// sources. A series that can't be decoded is described with its error rather
+// than failing the whole call.
+func GetLocationDatabaseInfo(locationsDatabaseFilepath string) (infos []LocationDatabaseSeriesInfo, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    f, err := os.Open(locationsDatabaseFilepath)
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    infos = make([]LocationDatabaseSeriesInfo, 0)
+
+    cb := func(i int, lds locationDatabaseSeries, checksumOk bool, decodeErr error) error {
+        if decodeErr != nil {
+            locationMaintenanceLogger.Warningf(nil, "Series (%d) could not be decoded: [%s]", i, decodeErr.Error())
+
+            ldsi := LocationDatabaseSeriesInfo{
+                DecodeError: decodeErr.Error(),
+            }
+
+            infos = append(infos, ldsi)
+            return nil
+        }
+
+        recordCount := 0
+        for _, te := range lds.Series {
+            recordCount += len(te.Items)
+        }
+
+        ldsi := LocationDatabaseSeriesInfo{
+            SourceFilepath: lds.Source.Filepath,
+            SourceSha1:     hex.EncodeToString(lds.Source.Sha1),
+            SeriesSha1:     hex.EncodeToString(lds.seriesSha1()),
+            RecordCount:    recordCount,
+            PrunedRanges:   len(lds.PrunedRanges),
+            ChecksumOk:     checksumOk,
+        }
+
+        if len(lds.Series) > 0 {
+            ldsi.HeadTime = lds.Series[0].Time
+            ldsi.TailTime = lds.Series[len(lds.Series)-1].Time
+        }
+
+        infos = append(infos, ldsi)
+        return nil
+    }
+
+    err = visitLocationDatabase(f, cb)
+    log.PanicIf(err)
+
+    return infos, nil
+}
+
+// ReadLocationDatabase returns the records from all series in the location
+// database as a single time-series.
+func ReadLocationDatabase(locationsDatabaseFilepath string) (ts timeindex.TimeSlice, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    f, err := os.Open(locationsDatabaseFilepath)
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    stored, err := readLocationDatabase(f)
+    log.PanicIf(err)
+
+    ti, err := mergeLocationDatabaseSeries(stored)
+    log.PanicIf(err)
+
+    return ti.Series(), nil
+}
+
+// LocationGap is a period with no location records.
+type LocationGap struct {
+    // Before is the last record before the gap.
+    Before *geoindex.GeographicRecord
+
+    // After is the first record after the gap.
+    After *geoindex.GeographicRecord
+}
+
+// Duration is the length of the gap.
+func (lg LocationGap) Duration() time.Duration {
+    return lg.After.Timestamp.Sub(lg.Before.Timestamp)
+}
+
+// FindLocationGaps returns every period between two adjacent location records
+// that is longer than the given duration.
+func FindLocationGaps(ts timeindex.TimeSlice, minimumDuration time.Duration) (gaps []LocationGap) {
+    gaps = make([]LocationGap, 0)
+
+    for i := 1; i < len(ts); i++ {
+        previousTe := ts[i-1]
+        currentTe := ts[i]
+
+        if currentTe.Time.Sub(previousTe.Time) <= minimumDuration {
+            continue
+        }
+
+        lg := LocationGap{
+            Before: previousTe.Items[len(previousTe.Items)-1].(*geoindex.GeographicRecord),
+            After:  currentTe.Items[0].(*geoindex.GeographicRecord),
+        }
+
+        gaps = append(gaps, lg)
+    }
+
+    return gaps
+}
+
+// PruneLocationDatabase removes all records between the two timestamps
+// (inclusive) from the location database. The pruned range is remembered with
+// each affected series, so it will survive subsequent updates unless the data
+// file that the series was loaded from changes.
+func PruneLocationDatabase(locationsDatabaseFilepath string, start, end time.Time) (removed int, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    if end.Before(start) == true {
+        log.Panicf("prune end [%v] is before start [%v]", end, start)
+    }
+
+    f, err := os.OpenFile(locationsDatabaseFilepath, os.O_RDWR, 0644)
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    stored, err := readLocationDatabase(f)
+    log.PanicIf(err)
+
+    for i, lds := range stored {
+        kept := make(timeindex.TimeSlice, 0, len(lds.Series))
+        for _, te := range lds.Series {
+            if te.Time.Before(start) == true || te.Time.After(end) == true {
+                kept = append(kept, te)
+                continue
+            }
+
+            removed += len(te.Items)
+        }
+
+        if len(kept) == len(lds.Series) {
+            continue
+        }
+
+        lpr := locationPruneRange{
+            Start: start,
+            End:   end,
+        }
+
+        lds.Series = kept
+        lds.PrunedRanges = append(lds.PrunedRanges, lpr)
+
+        stored[i] = lds
+    }
+
+    if removed == 0 {
+        return 0, nil
+    }
+
+    totalSize, adds, skips, drops, err := writeLocationDatabase(f, stored)
+    log.PanicIf(err)
+
+    locationMaintenanceLogger.Debugf(nil, "Prune complete. Location database is (%d) bytes. ADDS=(%d) SKIPS=(%d) DROPS=(%d)", totalSize, adds, skips, drops)
+
+    return removed, nil
+}
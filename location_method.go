@@ -0,0 +1,96 @@
+package geoautogroup
+
+import (
+    "time"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-index"
+)
+
+// How an image came to have the location that it was grouped by.
+const (
+    // LocationMethodNative is for images that had their own location.
+    LocationMethodNative = "native"
+
+    // LocationMethodMatched is for images that were matched to a location
+    // record.
+    LocationMethodMatched = "matched"
+
+    // LocationMethodAdjacent is for images that borrowed the location of an
+    // adjacent image.
+    LocationMethodAdjacent = "adjacent"
+
+    // LocationMethodOverride is for images whose location was given by an
+    // override.
+    LocationMethodOverride = "override"
+
+    // LocationMethodNone is for images that were grouped without a location.
+    LocationMethodNone = "none"
+)
+
+// hasRelationship indicates whether the record was related to another record
+// with the given relationship type.
+func hasRelationship(gr *geoindex.GeographicRecord, relationshipType string) bool {
+    encodedRelationships := gr.Encode()["relationships"].(map[string][]map[string]interface{})
+    return len(encodedRelationships[relationshipType]) > 0
+}
+
+// LocationMethod returns how the image came to have its location. This is only
+// meaningful after the image has been grouped.
+func (fg *FindGroups) LocationMethod(gr *geoindex.GeographicRecord) (method string, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    if gr.HasGeographic == false {
+        return LocationMethodNone, nil
+    } else if hasRelationship(gr, GeographicRelationshipSourceLocationRecord) == true {
+        return LocationMethodMatched, nil
+    } else if hasRelationship(gr, GeographicRelationshipSourceAdjacentImage) == true {
+        return LocationMethodAdjacent, nil
+    }
+
+    if fg.overrides != nil {
+        iov, err := fg.overrides.Lookup(gr.Filepath)
+        log.PanicIf(err)
+
+        if iov != nil && iov.HasLocation() == true {
+            return LocationMethodOverride, nil
+        }
+    }
+
+    return LocationMethodNative, nil
+}
+
+// LocationRecordsBetween returns the location records in the given period
+// along with the nearest record on either side of it. This is the track that
+// the images in the period were matched against.
+func (fg *FindGroups) LocationRecordsBetween(start, end time.Time) (records []*geoindex.GeographicRecord) {
+    records = make([]*geoindex.GeographicRecord, 0)
+
+    before, after := NearestLocationRecords(fg.locationTs, start, end)
+    if before != nil && before.Timestamp.Before(start) == true {
+        records = append(records, before)
+    }
+
+    position := timeindex.SearchTimes(fg.locationTs, start)
+    for ; position < len(fg.locationTs); position++ {
+        te := fg.locationTs[position]
+        if te.Time.After(end) == true {
+            break
+        }
+
+        for _, item := range te.Items {
+            records = append(records, item.(*geoindex.GeographicRecord))
+        }
+    }
+
+    if after != nil && after.Timestamp.After(end) == true {
+        records = append(records, after)
+    }
+
+    return records
+}
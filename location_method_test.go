@@ -0,0 +1,76 @@
+package geoautogroup
+
+import (
+    "testing"
+    "time"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-index"
+)
+
+func TestFindGroups_LocationMethod(t *testing.T) {
+    locationTs := getTestLocationTs()
+    fg := NewFindGroups(locationTs, nil, nil)
+
+    nativeGr := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "native.jpg", epochUtc, true, 1.1, 10.1, nil)
+
+    method, err := fg.LocationMethod(nativeGr)
+    log.PanicIf(err)
+
+    if method != LocationMethodNative {
+        t.Fatalf("Expected native location: [%s]", method)
+    }
+
+    matchedGr := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "matched.jpg", epochUtc.Add(time.Minute*2), false, 0, 0, nil)
+
+    imageTe := timeindex.TimeEntry{
+        Time:  matchedGr.Timestamp,
+        Items: []interface{}{matchedGr},
+    }
+
+    skipReason, err := fg.assignLocationRecord(imageTe, matchedGr)
+    log.PanicIf(err)
+
+    if skipReason != "" {
+        t.Fatalf("Expected image to be matched: [%s]", skipReason)
+    }
+
+    method, err = fg.LocationMethod(matchedGr)
+    log.PanicIf(err)
+
+    if method != LocationMethodMatched {
+        t.Fatalf("Expected matched location: [%s]", method)
+    }
+
+    unlocatedGr := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "unlocated.jpg", epochUtc, false, 0, 0, nil)
+
+    method, err = fg.LocationMethod(unlocatedGr)
+    log.PanicIf(err)
+
+    if method != LocationMethodNone {
+        t.Fatalf("Expected no location: [%s]", method)
+    }
+}
+
+func TestFindGroups_LocationRecordsBetween(t *testing.T) {
+    locationTs := getTestLocationTs()
+    fg := NewFindGroups(locationTs, nil, nil)
+
+    // Between the records at 1:05 and 1:20, exclusively, plus one on either
+    // side.
+
+    records := fg.LocationRecordsBetween(epochUtc.Add(time.Hour*1+time.Minute*6), epochUtc.Add(time.Hour*1+time.Minute*19))
+
+    expected := []string{"file11.gpx", "file12.gpx", "file13.gpx", "file14.gpx"}
+
+    if len(records) != len(expected) {
+        t.Fatalf("Track not the right length: (%d)", len(records))
+    }
+
+    for i, gr := range records {
+        if gr.Filepath != expected[i] {
+            t.Fatalf("Track record (%d) not correct: [%s] != [%s]", i, gr.Filepath, expected[i])
+        }
+    }
+}
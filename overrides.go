@@ -0,0 +1,369 @@
+package geoautogroup
+
+import (
+    "fmt"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "io/ioutil"
+    "path/filepath"
+
+    "github.com/dsoprea/go-logging"
+    "github.com/golang/geo/s2"
+    "gopkg.in/yaml.v2"
+
+    "github.com/dsoprea/go-geographic-attractor"
+    "github.com/dsoprea/go-geographic-index"
+)
+
+const (
+    // ImageOverridesFilename is the name of the per-directory override file.
+    // It applies to the images in its directory and all subdirectories unless
+    // a subdirectory has its own.
+    ImageOverridesFilename = ".autogroup-overrides.yaml"
+
+    // OverrideCitySourceName is the source-name used in the nearest-city keys
+    // of groups whose names were given by an override.
+    OverrideCitySourceName = "Override"
+)
+
+var (
+    overridesLogger = log.NewLogger("geoautogroup.overrides")
+)
+
+// ImageOverride assigns a location, a timestamp, and/or a fixed group name to
+// the images that match a path or glob.
+//
+// If `Match` is empty, the override matches every image. If it doesn't have a
+// path separator, it is matched against the filename. Otherwise, it is matched
+// against the absolute path of the image if it is absolute or against the path
+// relative to the directory of the override file if it isn't.
+type ImageOverride struct {
+    Match     string   `yaml:"match"`
+    Latitude  *float64 `yaml:"latitude"`
+    Longitude *float64 `yaml:"longitude"`
+    Timestamp string   `yaml:"timestamp"`
+    Group     string   `yaml:"group"`
+    Comment   string   `yaml:"comment"`
+
+    sourceFilepath string
+    basePath       string
+    timestamp      time.Time
+}
+
+func (iov ImageOverride) String() string {
+    return fmt.Sprintf("ImageOverride<SOURCE=[%s] MATCH=[%s]>", iov.sourceFilepath, iov.Match)
+}
+
+// HasLocation indicates whether the override assigns a location.
+func (iov ImageOverride) HasLocation() bool {
+    return iov.Latitude != nil && iov.Longitude != nil
+}
+
+// HasTimestamp indicates whether the override assigns a timestamp.
+func (iov ImageOverride) HasTimestamp() bool {
+    return iov.timestamp.IsZero() == false
+}
+
+// HasGroup indicates whether the override assigns a group name.
+func (iov ImageOverride) HasGroup() bool {
+    return iov.Group != ""
+}
+
+func (iov *ImageOverride) validate() (err error) {
+    if (iov.Latitude == nil) != (iov.Longitude == nil) {
+        return fmt.Errorf("latitude and longitude must be given together")
+    } else if iov.Latitude != nil && (*iov.Latitude < -90 || *iov.Latitude > 90) {
+        return fmt.Errorf("latitude not valid: (%.6f)", *iov.Latitude)
+    } else if iov.Longitude != nil && (*iov.Longitude < -180 || *iov.Longitude > 180) {
+        return fmt.Errorf("longitude not valid: (%.6f)", *iov.Longitude)
+    }
+
+    if iov.Timestamp != "" {
+        iov.timestamp, err = time.Parse(time.RFC3339, iov.Timestamp)
+        if err != nil {
+            return fmt.Errorf("could not parse timestamp [%s]: %s", iov.Timestamp, err)
+        }
+    }
+
+    if iov.HasLocation() == false && iov.HasTimestamp() == false && iov.HasGroup() == false {
+        return fmt.Errorf("override does not assign anything")
+    }
+
+    if iov.HasGroup() == true {
+        if err := ValidateGroupName(iov.Group); err != nil {
+            return err
+        }
+    }
+
+    if iov.Match != "" {
+        if _, err := filepath.Match(iov.Match, ""); err != nil {
+            return fmt.Errorf("match pattern not valid [%s]: %s", iov.Match, err)
+        }
+    }
+
+    return nil
+}
+
+// Matches indicates whether the override applies to the given image.
+func (iov ImageOverride) Matches(imageFilepath string) bool {
+    if iov.Match == "" {
+        return true
+    }
+
+    var target string
+    if strings.ContainsRune(iov.Match, filepath.Separator) == false {
+        target = filepath.Base(imageFilepath)
+    } else if filepath.IsAbs(iov.Match) == true || iov.basePath == "" {
+        target = imageFilepath
+    } else {
+        relPath, err := filepath.Rel(iov.basePath, imageFilepath)
+        if err != nil {
+            return false
+        }
+
+        target = relPath
+    }
+
+    matched, _ := filepath.Match(iov.Match, target)
+    return matched
+}
+
+// Apply updates the records that were loaded for the image. If the image
+// didn't produce any records (e.g. no timestamp) and the override has a
+// timestamp, a new record will be returned.
+func (iov ImageOverride) Apply(imageFilepath string, records []*geoindex.GeographicRecord) []*geoindex.GeographicRecord {
+    if len(records) == 0 {
+        if iov.HasTimestamp() == false {
+            return records
+        }
+
+        im := geoindex.ImageMetadata{}
+
+        gr := geoindex.NewGeographicRecord(
+            geoindex.SourceImageJpeg,
+            imageFilepath,
+            iov.timestamp,
+            false,
+            0,
+            0,
+            im)
+
+        records = []*geoindex.GeographicRecord{gr}
+    }
+
+    for _, gr := range records {
+        descriptions := make([]string, 0)
+
+        if iov.HasTimestamp() == true {
+            descriptions = append(descriptions, fmt.Sprintf("timestamp [%s] (was [%s])", iov.timestamp.Format(time.RFC3339), gr.Timestamp.Format(time.RFC3339)))
+            gr.Timestamp = iov.timestamp
+        }
+
+        if iov.HasLocation() == true {
+            descriptions = append(descriptions, fmt.Sprintf("location (%.6f, %.6f)", *iov.Latitude, *iov.Longitude))
+
+            gr.Latitude = *iov.Latitude
+            gr.Longitude = *iov.Longitude
+            gr.S2CellId = uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(gr.Latitude, gr.Longitude)))
+            gr.HasGeographic = true
+        }
+
+        if iov.HasGroup() == true {
+            descriptions = append(descriptions, fmt.Sprintf("group [%s]", iov.Group))
+        }
+
+        comment := fmt.Sprintf("Override from [%s] matching [%s]: %s", iov.sourceFilepath, iov.Match, strings.Join(descriptions, ", "))
+        if iov.Comment != "" {
+            comment = fmt.Sprintf("%s (%s)", comment, iov.Comment)
+        }
+
+        gr.AddComment(comment)
+    }
+
+    return records
+}
+
+// ValidateGroupName checks that a group name given by the user can be used in
+// the group's folder name and in its catalog page filename.
+func ValidateGroupName(groupName string) error {
+    if strings.ContainsAny(groupName, `/\`) == true {
+        return fmt.Errorf("group name can not contain a path separator: [%s]", groupName)
+    }
+
+    return nil
+}
+
+// OverrideCityKey returns the nearest-city key used for a group name that
+// was given by an override.
+func OverrideCityKey(groupName string) string {
+    return fmt.Sprintf("%s,%s", OverrideCitySourceName, groupName)
+}
+
+// IsOverrideCityKey indicates whether the nearest-city key is for a group name
+// that was given by an override.
+func IsOverrideCityKey(nearestCityKey string) bool {
+    return strings.HasPrefix(nearestCityKey, OverrideCitySourceName+",")
+}
+
+// overrideCityRecord returns a stand-in city record for a group name that was
+// given by an override.
+func overrideCityRecord(groupName string, gr *geoindex.GeographicRecord) geoattractor.CityRecord {
+    cr := geoattractor.CityRecord{
+        Id:   groupName,
+        City: groupName,
+    }
+
+    if gr.HasGeographic == true {
+        cr.Latitude = gr.Latitude
+        cr.Longitude = gr.Longitude
+    }
+
+    return cr
+}
+
+type imageOverridesDocument struct {
+    Overrides []ImageOverride `yaml:"overrides"`
+}
+
+func readImageOverridesFile(overridesFilepath, basePath string) (overrides []ImageOverride, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    data, err := ioutil.ReadFile(overridesFilepath)
+    log.PanicIf(err)
+
+    iod := imageOverridesDocument{}
+
+    err = yaml.UnmarshalStrict(data, &iod)
+    if err != nil {
+        log.Panicf("could not parse override file [%s]: %s", overridesFilepath, err)
+    }
+
+    overrides = make([]ImageOverride, len(iod.Overrides))
+    for i, iov := range iod.Overrides {
+        iov.sourceFilepath = overridesFilepath
+        iov.basePath = basePath
+
+        err := iov.validate()
+        if err != nil {
+            log.Panicf("override (%d) in [%s] not valid: %s", i, overridesFilepath, err)
+        }
+
+        overrides[i] = iov
+    }
+
+    return overrides, nil
+}
+
+// ImageOverrides finds the override for an image, if any, using a global
+// override file and/or the per-directory override files. Per-directory
+// overrides take precedence over global ones. Within a file, the first
+// matching override wins.
+type ImageOverrides struct {
+    global            []ImageOverride
+    useDirectoryFiles bool
+
+    // directories caches the overrides for each directory that we've looked
+    // in, including any inherited from parent directories.
+    directories map[string][]ImageOverride
+
+    m sync.Mutex
+}
+
+// NewImageOverrides returns a new `ImageOverrides`. If `globalFilepath` is not
+// empty, it will be loaded. Its relative match patterns are relative to the
+// current directory.
+func NewImageOverrides(globalFilepath string, useDirectoryFiles bool) (ios *ImageOverrides, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    ios = &ImageOverrides{
+        useDirectoryFiles: useDirectoryFiles,
+        directories:       make(map[string][]ImageOverride),
+    }
+
+    if globalFilepath != "" {
+        basePath, err := os.Getwd()
+        log.PanicIf(err)
+
+        ios.global, err = readImageOverridesFile(globalFilepath, basePath)
+        log.PanicIf(err)
+
+        overridesLogger.Debugf(nil, "Loaded (%d) global overrides from [%s].", len(ios.global), globalFilepath)
+    }
+
+    return ios, nil
+}
+
+// directoryOverrides returns the overrides that apply to the given directory.
+// The caller must hold the lock.
+func (ios *ImageOverrides) directoryOverrides(directoryPath string) (overrides []ImageOverride, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    if overrides, found := ios.directories[directoryPath]; found == true {
+        return overrides, nil
+    }
+
+    overridesFilepath := filepath.Join(directoryPath, ImageOverridesFilename)
+
+    if _, err := os.Stat(overridesFilepath); err == nil {
+        overrides, err = readImageOverridesFile(overridesFilepath, directoryPath)
+        log.PanicIf(err)
+    } else if os.IsNotExist(err) == false {
+        log.Panic(err)
+    } else if parentPath := filepath.Dir(directoryPath); parentPath != directoryPath {
+        overrides, err = ios.directoryOverrides(parentPath)
+        log.PanicIf(err)
+    }
+
+    ios.directories[directoryPath] = overrides
+
+    return overrides, nil
+}
+
+// Lookup returns the override for the given image or nil if there isn't one.
+func (ios *ImageOverrides) Lookup(imageFilepath string) (iov *ImageOverride, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    imageFilepath, err = filepath.Abs(imageFilepath)
+    log.PanicIf(err)
+
+    if ios.useDirectoryFiles == true {
+        ios.m.Lock()
+        overrides, err := ios.directoryOverrides(filepath.Dir(imageFilepath))
+        ios.m.Unlock()
+
+        log.PanicIf(err)
+
+        for i, candidate := range overrides {
+            if candidate.Matches(imageFilepath) == true {
+                return &overrides[i], nil
+            }
+        }
+    }
+
+    for i, candidate := range ios.global {
+        if candidate.Matches(imageFilepath) == true {
+            return &ios.global[i], nil
+        }
+    }
+
+    return nil, nil
+}
@@ -0,0 +1,161 @@
+package geoautogroup
+
+import (
+    "os"
+    "path"
+    "testing"
+    "time"
+
+    "io/ioutil"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+)
+
+func TestImageOverrides_Lookup(t *testing.T) {
+    tempPath, err := ioutil.TempDir("", "")
+    log.PanicIf(err)
+
+    defer os.RemoveAll(tempPath)
+
+    scansPath := path.Join(tempPath, "scans")
+    screenshotsPath := path.Join(tempPath, "scans", "screenshots")
+
+    err = os.MkdirAll(screenshotsPath, 0755)
+    log.PanicIf(err)
+
+    scansOverrides := `
+overrides:
+  - match: "print-*.jpg"
+    latitude: 41.85003
+    longitude: -87.65005
+    timestamp: "1985-06-01T12:00:00Z"
+    comment: "Grandma's prints"
+  - group: "Old scans"
+`
+
+    err = ioutil.WriteFile(path.Join(scansPath, ImageOverridesFilename), []byte(scansOverrides), 0644)
+    log.PanicIf(err)
+
+    globalOverrides := `
+overrides:
+  - match: "*.png.jpg"
+    group: "Screenshots"
+`
+
+    globalFilepath := path.Join(tempPath, "global.yaml")
+
+    err = ioutil.WriteFile(globalFilepath, []byte(globalOverrides), 0644)
+    log.PanicIf(err)
+
+    ios, err := NewImageOverrides(globalFilepath, true)
+    log.PanicIf(err)
+
+    iov, err := ios.Lookup(path.Join(scansPath, "print-001.jpg"))
+    log.PanicIf(err)
+
+    if iov == nil || iov.HasLocation() == false || iov.HasTimestamp() == false || iov.HasGroup() == true {
+        t.Fatalf("Expected the first directory override: %v", iov)
+    }
+
+    // Inherited from the parent directory.
+    iov, err = ios.Lookup(path.Join(screenshotsPath, "other.jpg"))
+    log.PanicIf(err)
+
+    if iov == nil || iov.Group != "Old scans" {
+        t.Fatalf("Expected the catch-all directory override: %v", iov)
+    }
+
+    // The directory overrides take precedence over the global ones.
+    iov, err = ios.Lookup(path.Join(screenshotsPath, "capture.png.jpg"))
+    log.PanicIf(err)
+
+    if iov == nil || iov.Group != "Old scans" {
+        t.Fatalf("Expected the directory override to win: %v", iov)
+    }
+
+    iov, err = ios.Lookup(path.Join(tempPath, "capture.png.jpg"))
+    log.PanicIf(err)
+
+    if iov == nil || iov.Group != "Screenshots" {
+        t.Fatalf("Expected the global override: %v", iov)
+    }
+
+    iov, err = ios.Lookup(path.Join(tempPath, "photo.jpg"))
+    log.PanicIf(err)
+
+    if iov != nil {
+        t.Fatalf("Expected no override: %v", iov)
+    }
+}
+
+func TestImageOverride_Apply(t *testing.T) {
+    latitude := chicagoCoordinates[0]
+    longitude := chicagoCoordinates[1]
+
+    iov := ImageOverride{
+        Latitude:  &latitude,
+        Longitude: &longitude,
+        Timestamp: "1985-06-01T12:00:00Z",
+    }
+
+    err := iov.validate()
+    log.PanicIf(err)
+
+    // An image without any records gets one if there's a timestamp.
+
+    records := iov.Apply("print.jpg", nil)
+    if len(records) != 1 {
+        t.Fatalf("Expected a record to be created: (%d)", len(records))
+    }
+
+    gr := records[0]
+
+    if gr.Timestamp.Equal(time.Date(1985, 6, 1, 12, 0, 0, 0, time.UTC)) == false {
+        t.Fatalf("Timestamp not overridden: [%v]", gr.Timestamp)
+    } else if gr.HasGeographic == false || gr.Latitude != latitude || gr.Longitude != longitude || gr.S2CellId == 0 {
+        t.Fatalf("Location not overridden: %s", gr)
+    }
+
+    // The location of an existing record is replaced.
+
+    gr = geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "photo.jpg", epochUtc, false, 0, 0, nil)
+
+    records = iov.Apply("photo.jpg", []*geoindex.GeographicRecord{gr})
+    if len(records) != 1 || records[0] != gr {
+        t.Fatalf("Expected the existing record to be updated.")
+    } else if gr.Latitude != latitude {
+        t.Fatalf("Location not overridden: %s", gr)
+    }
+}
+
+func TestImageOverride_Validate(t *testing.T) {
+    latitude := 100.0
+    longitude := 0.0
+
+    iov := ImageOverride{
+        Latitude:  &latitude,
+        Longitude: &longitude,
+    }
+
+    if err := iov.validate(); err == nil {
+        t.Fatalf("Expected failure for invalid latitude.")
+    }
+
+    iov = ImageOverride{
+        Match: "*.jpg",
+    }
+
+    if err := iov.validate(); err == nil {
+        t.Fatalf("Expected failure for override that doesn't assign anything.")
+    }
+
+    iov = ImageOverride{
+        Match: "*.jpg",
+        Group: "Trips/Wedding",
+    }
+
+    if err := iov.validate(); err == nil {
+        t.Fatalf("Expected failure for group name with a path separator.")
+    }
+}
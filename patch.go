@@ -0,0 +1,208 @@
+package geoautogroup
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "path"
+    "strings"
+    "time"
+
+    "encoding/json"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+    "github.com/dsoprea/go-time-index"
+)
+
+// UnassignedListEntry is one line of the unassigned-images list that is
+// written at the end of a grouping run.
+type UnassignedListEntry struct {
+    Filepath string
+    Reason   SkipReason
+}
+
+// ReadUnassignedList parses the unassigned-images list. Each line has the
+// file-path and the reason (as either a code or a description) separated by a
+// tab.
+func ReadUnassignedList(r io.Reader) (entries []UnassignedListEntry, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    entries = make([]UnassignedListEntry, 0)
+
+    s := bufio.NewScanner(r)
+    for i := 1; s.Scan() == true; i++ {
+        line := strings.TrimSpace(s.Text())
+        if line == "" {
+            continue
+        }
+
+        parts := strings.SplitN(line, "\t", 2)
+        if len(parts) != 2 {
+            log.Panicf("unassigned list line (%d) is not valid: [%s]", i, line)
+        }
+
+        reason, err := ParseSkipReason(parts[1])
+        if err != nil {
+            log.Panicf("unassigned list line (%d) has an unknown reason: [%s]", i, parts[1])
+        }
+
+        ule := UnassignedListEntry{
+            Filepath: parts[0],
+            Reason:   reason,
+        }
+
+        entries = append(entries, ule)
+    }
+
+    err = s.Err()
+    log.PanicIf(err)
+
+    return entries, nil
+}
+
+// ReadUnassignedReport reads the file-paths and reasons from the JSON form of
+// the unassigned report. The CSV form isn't supported.
+func ReadUnassignedReport(r io.Reader) (entries []UnassignedListEntry, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    reportEntries := make([]UnassignedReportEntry, 0)
+
+    d := json.NewDecoder(r)
+
+    err = d.Decode(&reportEntries)
+    log.PanicIf(err)
+
+    entries = make([]UnassignedListEntry, len(reportEntries))
+    for i, ure := range reportEntries {
+        reason, err := ParseSkipReason(ure.Reason)
+        if err != nil {
+            log.Panicf("unassigned report entry (%d) has an unknown reason: [%s]", i+1, ure.Reason)
+        }
+
+        entries[i] = UnassignedListEntry{
+            Filepath: ure.Filepath,
+            Reason:   reason,
+        }
+    }
+
+    return entries, nil
+}
+
+// IsPatchableReason indicates whether an image that was unassigned for the
+// given reason could be fixed by providing more location data.
+func IsPatchableReason(reason SkipReason) bool {
+    return reason == SkipReasonNoNearLocationRecord || reason == SkipReasonLocationTooFar
+}
+
+// PatchCluster is a set of images that are close together in time and that
+// need location data, along with the nearest location records on either side.
+type PatchCluster struct {
+    Images []*geoindex.GeographicRecord
+
+    // Before is the last location record before the first image. This is nil
+    // if there is no location data before the cluster.
+    Before *geoindex.GeographicRecord
+
+    // After is the first location record after the last image. This is nil if
+    // there is no location data after the cluster.
+    After *geoindex.GeographicRecord
+}
+
+// First returns the earliest image in the cluster.
+func (pc PatchCluster) First() *geoindex.GeographicRecord {
+    return pc.Images[0]
+}
+
+// Last returns the latest image in the cluster.
+func (pc PatchCluster) Last() *geoindex.GeographicRecord {
+    return pc.Images[len(pc.Images)-1]
+}
+
+// Nearest returns whichever of the location records on either side of the
+// cluster is closest in time, or nil if there are none.
+func (pc PatchCluster) Nearest() *geoindex.GeographicRecord {
+    if pc.Before == nil {
+        return pc.After
+    } else if pc.After == nil {
+        return pc.Before
+    }
+
+    beforeDelta := pc.First().Timestamp.Sub(pc.Before.Timestamp)
+    afterDelta := pc.After.Timestamp.Sub(pc.Last().Timestamp)
+
+    if afterDelta < beforeDelta {
+        return pc.After
+    }
+
+    return pc.Before
+}
+
+// PointTimestamps returns the timestamps of the list-file points that will
+// locate every image in the cluster. Each point covers the images up to the
+// matcher's rounding window after it. This is used rather than a range since
+// the points between the images wouldn't locate anything.
+func (pc PatchCluster) PointTimestamps() []time.Time {
+    timestamps := make([]time.Time, 0)
+    for _, gr := range pc.Images {
+        if len(timestamps) > 0 && gr.Timestamp.Sub(timestamps[len(timestamps)-1]) <= LocationMatchRoundingWindowDuration {
+            continue
+        }
+
+        timestamps = append(timestamps, gr.Timestamp)
+    }
+
+    return timestamps
+}
+
+func (pc PatchCluster) String() string {
+    return fmt.Sprintf("PatchCluster<IMAGES=(%d) FIRST=[%s] LAST=[%s]>", len(pc.Images), path.Base(pc.First().Filepath), path.Base(pc.Last().Filepath))
+}
+
+// FindPatchClusters groups the given images into clusters where no two
+// adjacent images are more than `maximumGap` apart and finds the location
+// records on either side of each cluster.
+func FindPatchClusters(locationTs, imageTs timeindex.TimeSlice, maximumGap time.Duration) (clusters []PatchCluster) {
+    clusters = make([]PatchCluster, 0)
+
+    var current *PatchCluster
+
+    flush := func() {
+        if current == nil {
+            return
+        }
+
+        current.Before, current.After = NearestLocationRecords(locationTs, current.First().Timestamp, current.Last().Timestamp)
+
+        clusters = append(clusters, *current)
+        current = nil
+    }
+
+    for _, te := range imageTs {
+        if current != nil && te.Time.Sub(current.Last().Timestamp) > maximumGap {
+            flush()
+        }
+
+        if current == nil {
+            current = &PatchCluster{
+                Images: make([]*geoindex.GeographicRecord, 0),
+            }
+        }
+
+        for _, item := range te.Items {
+            current.Images = append(current.Images, item.(*geoindex.GeographicRecord))
+        }
+    }
+
+    flush()
+
+    return clusters
+}
@@ -0,0 +1,129 @@
+package geoautogroup
+
+import (
+    "bytes"
+    "testing"
+    "time"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+)
+
+func TestReadUnassignedList(t *testing.T) {
+    s := "/a/image1.jpg\tno matching/near location record\n\n/a/image2.jpg\tno near city\n"
+
+    b := bytes.NewBufferString(s)
+
+    entries, err := ReadUnassignedList(b)
+    log.PanicIf(err)
+
+    if len(entries) != 2 {
+        t.Fatalf("Expected two entries: (%d)", len(entries))
+    }
+
+    if entries[0].Filepath != "/a/image1.jpg" || IsPatchableReason(entries[0].Reason) == false {
+        t.Fatalf("First entry not correct: %v", entries[0])
+    } else if entries[1].Filepath != "/a/image2.jpg" || IsPatchableReason(entries[1].Reason) == true {
+        t.Fatalf("Second entry not correct: %v", entries[1])
+    }
+}
+
+func TestReadUnassignedReport(t *testing.T) {
+    s := `[
+  {"filepath": "/a/image1.jpg", "reason": "no_near_location_record", "timestamp": "2019-01-01T00:00:00Z"},
+  {"filepath": "/a/image2.jpg", "reason": "no near city", "timestamp": "2019-01-01T00:00:00Z"}
+]`
+
+    entries, err := ReadUnassignedReport(bytes.NewBufferString(s))
+    log.PanicIf(err)
+
+    if len(entries) != 2 {
+        t.Fatalf("Expected two entries: (%d)", len(entries))
+    }
+
+    if entries[0].Filepath != "/a/image1.jpg" || IsPatchableReason(entries[0].Reason) == false {
+        t.Fatalf("First entry not correct: %v", entries[0])
+    } else if entries[1].Filepath != "/a/image2.jpg" || IsPatchableReason(entries[1].Reason) == true {
+        t.Fatalf("Second entry not correct: %v", entries[1])
+    }
+}
+
+func TestFindPatchClusters(t *testing.T) {
+    locationTi := geoindex.NewTimeIndex()
+
+    gr := geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc, true, chicagoCoordinates[0], chicagoCoordinates[1], nil)
+    locationTi.AddWithRecord(gr)
+
+    gr = geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc.Add(time.Hour*10), true, detroitCoordinates[0], detroitCoordinates[1], nil)
+    locationTi.AddWithRecord(gr)
+
+    imageTi := geoindex.NewTimeIndex()
+
+    im := geoindex.ImageMetadata{
+        CameraModel: "some model",
+    }
+
+    // First cluster. Closer to Chicago.
+    gr = geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image1.jpg", epochUtc.Add(time.Hour*2), false, 0, 0, im)
+    imageTi.AddWithRecord(gr)
+
+    gr = geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image2.jpg", epochUtc.Add(time.Hour*3), false, 0, 0, im)
+    imageTi.AddWithRecord(gr)
+
+    // Second cluster. Closer to Detroit.
+    gr = geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image3.jpg", epochUtc.Add(time.Hour*8), false, 0, 0, im)
+    imageTi.AddWithRecord(gr)
+
+    clusters := FindPatchClusters(locationTi.Series(), imageTi.Series(), time.Hour*2)
+
+    if len(clusters) != 2 {
+        t.Fatalf("Expected two clusters: (%d)", len(clusters))
+    }
+
+    if len(clusters[0].Images) != 2 || clusters[0].Nearest().Latitude != chicagoCoordinates[0] {
+        t.Fatalf("First cluster not correct: %s", clusters[0])
+    } else if len(clusters[1].Images) != 1 || clusters[1].Nearest().Latitude != detroitCoordinates[0] {
+        t.Fatalf("Second cluster not correct: %s", clusters[1])
+    }
+}
+
+func TestPatchCluster_PointTimestamps(t *testing.T) {
+    im := geoindex.ImageMetadata{
+        CameraModel: "some model",
+    }
+
+    offsets := []time.Duration{
+        0,
+        time.Minute * 5,
+        time.Minute * 10,
+        time.Minute * 11,
+        time.Minute * 40,
+        time.Minute * 41,
+    }
+
+    pc := PatchCluster{
+        Images: make([]*geoindex.GeographicRecord, len(offsets)),
+    }
+
+    for i, offset := range offsets {
+        pc.Images[i] = geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image.jpg", epochUtc.Add(offset), false, 0, 0, im)
+    }
+
+    timestamps := pc.PointTimestamps()
+
+    expected := []time.Time{
+        epochUtc,
+        epochUtc.Add(time.Minute * 11),
+        epochUtc.Add(time.Minute * 40),
+    }
+
+    if len(timestamps) != len(expected) {
+        t.Fatalf("Expected (%d) points: %v", len(expected), timestamps)
+    }
+
+    for i, timestamp := range timestamps {
+        if timestamp.Equal(expected[i]) == false {
+            t.Fatalf("Point (%d) not correct: [%s] != [%s]", i, timestamp, expected[i])
+        }
+    }
+}
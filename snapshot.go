@@ -0,0 +1,147 @@
+package geoautogroup
+
+import (
+    "fmt"
+    "io"
+    "sort"
+    "time"
+)
+
+// BufferedImageSnapshot describes one image waiting in a buffered group.
+type BufferedImageSnapshot struct {
+    Filepath         string    `json:"filepath"`
+    Timestamp        time.Time `json:"timestamp"`
+    EffectiveTimeKey time.Time `json:"effective_time_key"`
+    NearestCityKey   string    `json:"nearest_city_key"`
+}
+
+// BufferedGroupSnapshot describes the images buffered for one camera-model.
+type BufferedGroupSnapshot struct {
+    CameraModel       string                  `json:"camera_model"`
+    FirstTimeKey      time.Time               `json:"first_time_key"`
+    LastTimeKey       time.Time               `json:"last_time_key"`
+    HaveCompleteGroup bool                    `json:"have_complete_group"`
+    Images            []BufferedImageSnapshot `json:"images"`
+}
+
+// NearestCitySnapshot describes one city that images have been grouped by.
+type NearestCitySnapshot struct {
+    Id                   string  `json:"id"`
+    CityAndProvinceState string  `json:"city_and_province_state"`
+    Country              string  `json:"country"`
+    Latitude             float64 `json:"latitude"`
+    Longitude            float64 `json:"longitude"`
+}
+
+// FindGroupsSnapshot is a copy of the grouping state at one point in time. It
+// can be serialized as JSON.
+type FindGroupsSnapshot struct {
+    // CurrentPosition is the index of the next image to be processed.
+    CurrentPosition int `json:"current_position"`
+    ImageCount      int `json:"image_count"`
+
+    // BufferedGroups are ordered by camera-model.
+    BufferedGroups []BufferedGroupSnapshot `json:"buffered_groups"`
+
+    // NearestCityIndex is keyed the same as in the grouping.
+    NearestCityIndex map[string]NearestCitySnapshot `json:"nearest_city_index"`
+
+    UnassignedCount int `json:"unassigned_count"`
+}
+
+func (fgs FindGroupsSnapshot) String() string {
+    return fmt.Sprintf("FindGroupsSnapshot<POSITION=(%d/%d) BUFFERED-GROUPS=(%d) CITIES=(%d) UNASSIGNED=(%d)>", fgs.CurrentPosition, fgs.ImageCount, len(fgs.BufferedGroups), len(fgs.NearestCityIndex), fgs.UnassignedCount)
+}
+
+// Dump pretty-prints the snapshot. If `printDetail` is true, every buffered
+// image is printed.
+func (fgs FindGroupsSnapshot) Dump(w io.Writer, printDetail bool) {
+    fmt.Fprintf(w, "Position: (%d/%d)\n", fgs.CurrentPosition, fgs.ImageCount)
+    fmt.Fprintf(w, "Cities: (%d)\n", len(fgs.NearestCityIndex))
+    fmt.Fprintf(w, "Unassigned: (%d)\n", fgs.UnassignedCount)
+    fmt.Fprintf(w, "\n")
+
+    if len(fgs.BufferedGroups) == 0 {
+        fmt.Fprintf(w, "No images buffered.\n\n")
+        return
+    }
+
+    for _, bgs := range fgs.BufferedGroups {
+        fmt.Fprintf(w, "BUFFERED GROUP [%s]\n", bgs.CameraModel)
+        fmt.Fprintf(w, "=============================\n")
+        fmt.Fprintf(w, "\n")
+        fmt.Fprintf(w, "Have complete group? [%v]\n", bgs.HaveCompleteGroup)
+        fmt.Fprintf(w, "First time-key: [%s]\n", bgs.FirstTimeKey)
+        fmt.Fprintf(w, "Last time-key: [%s]\n", bgs.LastTimeKey)
+        fmt.Fprintf(w, "Image count: (%d)\n", len(bgs.Images))
+
+        if printDetail == true {
+            fmt.Fprintf(w, "\n")
+
+            for i, bis := range bgs.Images {
+                fmt.Fprintf(w, "> Image (%d): EFF-TIME-KEY=[%s] CITY=[%s] FILEPATH=[%s]\n", i, bis.EffectiveTimeKey, bis.NearestCityKey, bis.Filepath)
+            }
+        }
+
+        fmt.Fprintf(w, "\n")
+    }
+}
+
+// snapshot returns a copy of the state of the buffered group.
+func (bg *bufferedGroup) snapshot(cameraModel string) BufferedGroupSnapshot {
+    images := make([]BufferedImageSnapshot, len(bg.images))
+    for i, bi := range bg.images {
+        images[i] = BufferedImageSnapshot{
+            Filepath:         bi.gr.Filepath,
+            Timestamp:        bi.gr.Timestamp,
+            EffectiveTimeKey: bi.effectiveTimekey,
+            NearestCityKey:   bi.nearestCityKey,
+        }
+    }
+
+    return BufferedGroupSnapshot{
+        CameraModel:       cameraModel,
+        FirstTimeKey:      bg.firstTimeKey,
+        LastTimeKey:       bg.lastTimeKey,
+        HaveCompleteGroup: bg.haveCompleteGroup(),
+        Images:            images,
+    }
+}
+
+// snapshot returns a copy of the state of every buffered group, ordered by
+// camera-model.
+func (igb *iterativeGroupBuffers) snapshot() []BufferedGroupSnapshot {
+    cameraModels := igb.bufferedCameraModels()
+    sort.Strings(cameraModels)
+
+    snapshots := make([]BufferedGroupSnapshot, len(cameraModels))
+    for i, cameraModel := range cameraModels {
+        snapshots[i] = igb.groupsByCameraModel[cameraModel].snapshot(cameraModel)
+    }
+
+    return snapshots
+}
+
+// Snapshot returns a copy of the current grouping state. Nothing in it refers
+// back to the live state, so it can be kept or serialized while grouping
+// continues.
+func (fg *FindGroups) Snapshot() FindGroupsSnapshot {
+    nearestCityIndex := make(map[string]NearestCitySnapshot, len(fg.nearestCityIndex))
+    for nearestCityKey, cr := range fg.nearestCityIndex {
+        nearestCityIndex[nearestCityKey] = NearestCitySnapshot{
+            Id:                   cr.Id,
+            CityAndProvinceState: cr.CityAndProvinceState(),
+            Country:              cr.Country,
+            Latitude:             cr.Latitude,
+            Longitude:            cr.Longitude,
+        }
+    }
+
+    return FindGroupsSnapshot{
+        CurrentPosition:  fg.currentImagePosition,
+        ImageCount:       len(fg.imageTs),
+        BufferedGroups:   fg.bufferedGroups.snapshot(),
+        NearestCityIndex: nearestCityIndex,
+        UnassignedCount:  len(fg.unassignedRecords),
+    }
+}
@@ -0,0 +1,82 @@
+package geoautogroup
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+    "time"
+
+    "encoding/json"
+
+    "github.com/dsoprea/go-geographic-attractor"
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+)
+
+func TestFindGroups_Snapshot(t *testing.T) {
+    fg := NewFindGroups(getTestLocationTs(), nil, nil)
+
+    fg.nearestCityIndex["GeoNames,4887398"] = geoattractor.CityRecord{
+        Id:        "4887398",
+        City:      "Chicago",
+        Country:   "United States",
+        Latitude:  chicagoCoordinates[0],
+        Longitude: chicagoCoordinates[1],
+    }
+
+    phoneIm := geoindex.ImageMetadata{
+        CameraModel: "phone",
+    }
+
+    dslrIm := geoindex.ImageMetadata{
+        CameraModel: "dslr",
+    }
+
+    gr1 := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "1.jpg", epochUtc, true, chicagoCoordinates[0], chicagoCoordinates[1], phoneIm)
+    fg.bufferedGroups.pushImage("GeoNames,4887398", gr1)
+
+    gr2 := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "2.jpg", epochUtc.Add(time.Second), true, chicagoCoordinates[0], chicagoCoordinates[1], phoneIm)
+    fg.bufferedGroups.pushImage("GeoNames,4887398", gr2)
+
+    gr3 := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "3.jpg", epochUtc.Add(time.Second*2), true, chicagoCoordinates[0], chicagoCoordinates[1], dslrIm)
+    fg.bufferedGroups.pushImage("GeoNames,4887398", gr3)
+
+    fg.addUnassigned(geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "4.jpg", epochUtc, false, 0, 0, phoneIm), SkipReasonNoNearLocationRecord)
+
+    fgs := fg.Snapshot()
+
+    if fgs.UnassignedCount != 1 {
+        t.Fatalf("Unassigned count not correct: (%d)", fgs.UnassignedCount)
+    } else if len(fgs.BufferedGroups) != 2 {
+        t.Fatalf("Expected two buffered groups: (%d)", len(fgs.BufferedGroups))
+    } else if fgs.BufferedGroups[0].CameraModel != "dslr" || fgs.BufferedGroups[1].CameraModel != "phone" {
+        t.Fatalf("Buffered groups not ordered by camera-model: %v", fgs.BufferedGroups)
+    } else if len(fgs.BufferedGroups[1].Images) != 2 || fgs.BufferedGroups[1].Images[1].Filepath != "2.jpg" {
+        t.Fatalf("Buffered images not correct: %v", fgs.BufferedGroups[1].Images)
+    } else if fgs.NearestCityIndex["GeoNames,4887398"].CityAndProvinceState != "Chicago" {
+        t.Fatalf("Nearest-city index not correct: %v", fgs.NearestCityIndex)
+    }
+
+    // The snapshot should survive a round-trip through JSON.
+
+    encoded, err := json.Marshal(fgs)
+    log.PanicIf(err)
+
+    recovered := FindGroupsSnapshot{}
+
+    err = json.Unmarshal(encoded, &recovered)
+    log.PanicIf(err)
+
+    if recovered.String() != fgs.String() {
+        t.Fatalf("Snapshot not recovered correctly: %s != %s", recovered, fgs)
+    } else if recovered.BufferedGroups[1].Images[0].EffectiveTimeKey.Equal(fgs.BufferedGroups[1].Images[0].EffectiveTimeKey) == false {
+        t.Fatalf("Time-key not recovered correctly.")
+    }
+
+    b := new(bytes.Buffer)
+    fgs.Dump(b, true)
+
+    if strings.Contains(b.String(), "FILEPATH=[3.jpg]") == false {
+        t.Fatalf("Dump does not include the buffered images:\n%s", b.String())
+    }
+}
@@ -0,0 +1,224 @@
+package geoautogroup
+
+import (
+    "bytes"
+    "fmt"
+    "image"
+    "os"
+    "path"
+
+    "crypto/sha1"
+    "encoding/hex"
+    "image/jpeg"
+    "io/ioutil"
+
+    _ "image/png"
+
+    "github.com/dsoprea/go-exif"
+    "github.com/dsoprea/go-logging"
+    "golang.org/x/image/draw"
+)
+
+const (
+    // ThumbnailQuality is the JPEG quality of the thumbnails.
+    ThumbnailQuality = 85
+)
+
+// Values of the EXIF "Orientation" tag. The first is the normal orientation.
+const (
+    orientationNormal                   = 1
+    orientationFlipHorizontal           = 2
+    orientationRotate180                = 3
+    orientationFlipVertical             = 4
+    orientationTranspose                = 5
+    orientationRotate90Clockwise        = 6
+    orientationTransverse               = 7
+    orientationRotate90CounterClockwise = 8
+)
+
+// getExifOrientation returns the EXIF orientation of the image or
+// `orientationNormal` if it doesn't have one.
+func getExifOrientation(data []byte) int {
+    rawExif, err := exif.SearchAndExtractExif(data)
+    if err != nil {
+        return orientationNormal
+    }
+
+    im := exif.NewIfdMappingWithStandard()
+    ti := exif.NewTagIndex()
+
+    _, index, err := exif.Collect(im, ti, rawExif)
+    if err != nil {
+        return orientationNormal
+    }
+
+    results, err := index.RootIfd.FindTagWithName("Orientation")
+    if err != nil || len(results) == 0 {
+        return orientationNormal
+    }
+
+    value, err := index.RootIfd.TagValue(results[0])
+    if err != nil {
+        return orientationNormal
+    }
+
+    if values, ok := value.([]uint16); ok == true && len(values) > 0 {
+        orientation := int(values[0])
+        if orientation >= orientationNormal && orientation <= orientationRotate90CounterClockwise {
+            return orientation
+        }
+    }
+
+    return orientationNormal
+}
+
+// applyOrientation returns the image as it should be displayed given its EXIF
+// orientation.
+func applyOrientation(src image.Image, orientation int) image.Image {
+    if orientation == orientationNormal {
+        return src
+    }
+
+    b := src.Bounds()
+    width, height := b.Dx(), b.Dy()
+
+    // Orientations 5 through 8 swap the dimensions.
+    isTransposed := orientation >= orientationTranspose
+
+    var dst *image.RGBA
+    if isTransposed == true {
+        dst = image.NewRGBA(image.Rect(0, 0, height, width))
+    } else {
+        dst = image.NewRGBA(image.Rect(0, 0, width, height))
+    }
+
+    for y := 0; y < height; y++ {
+        for x := 0; x < width; x++ {
+            var dx, dy int
+
+            switch orientation {
+            case orientationFlipHorizontal:
+                dx, dy = width-1-x, y
+            case orientationRotate180:
+                dx, dy = width-1-x, height-1-y
+            case orientationFlipVertical:
+                dx, dy = x, height-1-y
+            case orientationTranspose:
+                dx, dy = y, x
+            case orientationRotate90Clockwise:
+                dx, dy = height-1-y, x
+            case orientationTransverse:
+                dx, dy = height-1-y, width-1-x
+            case orientationRotate90CounterClockwise:
+                dx, dy = y, width-1-x
+            }
+
+            dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+        }
+    }
+
+    return dst
+}
+
+// ThumbnailCache writes thumbnails of images into a directory. Thumbnails are
+// named by the hash of the image's content so that they are shared by
+// identical images and don't have to be regenerated on the next run.
+type ThumbnailCache struct {
+    path         string
+    maximumWidth int
+}
+
+func NewThumbnailCache(path string, maximumWidth int) *ThumbnailCache {
+    return &ThumbnailCache{
+        path:         path,
+        maximumWidth: maximumWidth,
+    }
+}
+
+// Get returns the file-path of the thumbnail of the given image, creating it if
+// necessary. The thumbnail is a JPEG that has had the EXIF orientation applied
+// and is no wider than the maximum width (smaller images aren't enlarged).
+func (tc *ThumbnailCache) Get(imageFilepath string) (thumbnailFilepath string, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    data, err := ioutil.ReadFile(imageFilepath)
+    log.PanicIf(err)
+
+    h := sha1.New()
+    h.Write(data)
+
+    digest := hex.EncodeToString(h.Sum(nil))
+
+    // Shard by the leading bytes of the hash to keep the directories small.
+    thumbnailPath := path.Join(tc.path, digest[:2])
+    thumbnailFilepath = path.Join(thumbnailPath, fmt.Sprintf("%s-%d.jpg", digest, tc.maximumWidth))
+
+    if _, err := os.Stat(thumbnailFilepath); err == nil {
+        return thumbnailFilepath, nil
+    } else if os.IsNotExist(err) == false {
+        log.Panic(err)
+    }
+
+    src, _, err := image.Decode(bytes.NewReader(data))
+    log.PanicIf(err)
+
+    orientation := getExifOrientation(data)
+
+    // Scale first and then orient since orienting is per-pixel. The maximum
+    // width applies to the image as it's displayed, so the dimensions are
+    // swapped for the orientations that transpose it.
+
+    b := src.Bounds()
+    width, height := b.Dx(), b.Dy()
+
+    isTransposed := orientation >= orientationTranspose
+    if isTransposed == true {
+        width, height = height, width
+    }
+
+    if width > tc.maximumWidth {
+        height = height * tc.maximumWidth / width
+        if height < 1 {
+            height = 1
+        }
+
+        width = tc.maximumWidth
+    }
+
+    if isTransposed == true {
+        width, height = height, width
+    }
+
+    scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+    draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, b, draw.Src, nil)
+
+    dst := applyOrientation(scaled, orientation)
+
+    err = os.MkdirAll(thumbnailPath, 0755)
+    log.PanicIf(err)
+
+    // Write to a temporary file first so that an interrupted run doesn't leave
+    // a truncated thumbnail in the cache.
+
+    f, err := ioutil.TempFile(thumbnailPath, ".thumbnail")
+    log.PanicIf(err)
+
+    tempFilepath := f.Name()
+
+    err = jpeg.Encode(f, dst, &jpeg.Options{Quality: ThumbnailQuality})
+    f.Close()
+
+    if err != nil {
+        os.Remove(tempFilepath)
+        log.Panic(err)
+    }
+
+    err = os.Rename(tempFilepath, thumbnailFilepath)
+    log.PanicIf(err)
+
+    return thumbnailFilepath, nil
+}
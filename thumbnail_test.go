@@ -0,0 +1,75 @@
+package geoautogroup
+
+import (
+    "image"
+    "os"
+    "testing"
+
+    "image/color"
+    "image/jpeg"
+    "io/ioutil"
+    "path/filepath"
+
+    "github.com/dsoprea/go-logging"
+)
+
+func TestApplyOrientation_Rotate90Clockwise(t *testing.T) {
+    src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+    src.Set(0, 0, color.RGBA{R: 0xff, A: 0xff})
+
+    dst := applyOrientation(src, orientationRotate90Clockwise)
+
+    b := dst.Bounds()
+    if b.Dx() != 2 || b.Dy() != 4 {
+        t.Fatalf("Dimensions not swapped: %v", b)
+    }
+
+    // The top-left corner moves to the top-right.
+    r, _, _, _ := dst.At(1, 0).RGBA()
+    if r != 0xffff {
+        t.Fatalf("Pixel not rotated.")
+    }
+}
+
+func TestThumbnailCache_Get(t *testing.T) {
+    tempPath, err := ioutil.TempDir("", "")
+    log.PanicIf(err)
+
+    defer os.RemoveAll(tempPath)
+
+    imageFilepath := filepath.Join(tempPath, "image.jpg")
+
+    f, err := os.Create(imageFilepath)
+    log.PanicIf(err)
+
+    err = jpeg.Encode(f, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil)
+    log.PanicIf(err)
+
+    f.Close()
+
+    tc := NewThumbnailCache(filepath.Join(tempPath, "thumbnails"), 10)
+
+    thumbnailFilepath, err := tc.Get(imageFilepath)
+    log.PanicIf(err)
+
+    g, err := os.Open(thumbnailFilepath)
+    log.PanicIf(err)
+
+    defer g.Close()
+
+    thumbnail, err := jpeg.Decode(g)
+    log.PanicIf(err)
+
+    if thumbnail.Bounds().Dx() != 10 || thumbnail.Bounds().Dy() != 5 {
+        t.Fatalf("Thumbnail dimensions not correct: %v", thumbnail.Bounds())
+    }
+
+    // The second request should be served from the cache.
+
+    cachedFilepath, err := tc.Get(imageFilepath)
+    log.PanicIf(err)
+
+    if cachedFilepath != thumbnailFilepath {
+        t.Fatalf("Cached thumbnail not returned: [%s] != [%s]", cachedFilepath, thumbnailFilepath)
+    }
+}
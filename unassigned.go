@@ -0,0 +1,270 @@
+package geoautogroup
+
+import (
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/dsoprea/go-logging"
+    "github.com/golang/geo/s2"
+
+    "github.com/dsoprea/go-geographic-attractor"
+    "github.com/dsoprea/go-geographic-attractor/index"
+    "github.com/dsoprea/go-geographic-index"
+)
+
+const (
+    // earthRadiusKm is the mean radius of the Earth.
+    earthRadiusKm = 6371.0
+)
+
+var (
+    ErrSkipReasonNotValid = errors.New("skip reason not valid")
+)
+
+// SkipReason is the reason that an image could not be grouped.
+type SkipReason string
+
+const (
+    SkipReasonNoNearLocationRecord SkipReason = "no_near_location_record"
+    SkipReasonLocationTooFar       SkipReason = "location_too_old"
+    SkipReasonNoNearCity           SkipReason = "no_near_city"
+)
+
+var (
+    skipReasonDescriptions = map[SkipReason]string{
+        SkipReasonNoNearLocationRecord: "no matching/near location record",
+        SkipReasonLocationTooFar:       "matched location record too old",
+        SkipReasonNoNearCity:           "no near city",
+    }
+)
+
+// Description returns a human-readable description of the reason.
+func (sr SkipReason) Description() string {
+    if description, found := skipReasonDescriptions[sr]; found == true {
+        return description
+    }
+
+    return string(sr)
+}
+
+// ParseSkipReason accepts either a reason code or its description (as written
+// to older unassigned lists).
+func ParseSkipReason(phrase string) (sr SkipReason, err error) {
+    for sr, description := range skipReasonDescriptions {
+        if phrase == string(sr) || phrase == description {
+            return sr, nil
+        }
+    }
+
+    return "", ErrSkipReasonNotValid
+}
+
+// UnassignedDiagnostics describes what was known about an image when it could
+// not be grouped.
+type UnassignedDiagnostics struct {
+    // LocationMatchStrategy is the location-matching strategy that was used.
+    LocationMatchStrategy string
+
+    // AdjacentImageWindow is the window that we looked for located images in.
+    // This is zero if we didn't look.
+    AdjacentImageWindow time.Duration
+
+    // LocationBefore is the last location record at or before the image. It
+    // is nil if there isn't one.
+    LocationBefore *geoindex.GeographicRecord
+
+    // LocationAfter is the first location record at or after the image. It
+    // is nil if there isn't one.
+    LocationAfter *geoindex.GeographicRecord
+
+    // NearestCity is the city nearest to the coordinates of the image or, if
+    // the image doesn't have coordinates, to the location record before it.
+    // This is nil if there weren't any coordinates or no city was found.
+    NearestCity *geoattractor.CityRecord
+
+    // NearestCityDistanceKm is the distance to `NearestCity`.
+    NearestCityDistanceKm float64
+}
+
+// getDistanceKm returns the great-circle distance between two points.
+func getDistanceKm(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+    ll1 := s2.LatLngFromDegrees(latitude1, longitude1)
+    ll2 := s2.LatLngFromDegrees(latitude2, longitude2)
+
+    return ll1.Distance(ll2).Radians() * earthRadiusKm
+}
+
+// getUnassignedDiagnostics collects the diagnostics for the given image.
+func (fg *FindGroups) getUnassignedDiagnostics(gr *geoindex.GeographicRecord) (ud UnassignedDiagnostics, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    ud = UnassignedDiagnostics{
+        LocationMatchStrategy: fg.locationMatchStrategy,
+        AdjacentImageWindow:   fg.adjacentImageWindow,
+    }
+
+    ud.LocationBefore, ud.LocationAfter = NearestLocationRecords(fg.locationTs, gr.Timestamp, gr.Timestamp)
+
+    if fg.cityIndex == nil {
+        return ud, nil
+    }
+
+    var latitude, longitude float64
+    if gr.HasGeographic == true {
+        latitude = gr.Latitude
+        longitude = gr.Longitude
+    } else if ud.LocationBefore != nil {
+        latitude = ud.LocationBefore.Latitude
+        longitude = ud.LocationBefore.Longitude
+    } else {
+        return ud, nil
+    }
+
+    _, _, cr, err := fg.cityIndex.Nearest(latitude, longitude, false)
+    if err != nil {
+        if log.Is(err, geoattractorindex.ErrNoNearestCity) == true {
+            return ud, nil
+        }
+
+        log.Panic(err)
+    }
+
+    ud.NearestCity = &cr
+    ud.NearestCityDistanceKm = getDistanceKm(latitude, longitude, cr.Latitude, cr.Longitude)
+
+    return ud, nil
+}
+
+// UnassignedReportEntry is the flattened information about an image that
+// could not be grouped. This is what we write to the unassigned report.
+type UnassignedReportEntry struct {
+    Filepath              string  `json:"filepath"`
+    Reason                string  `json:"reason"`
+    ReasonDescription     string  `json:"reason_description"`
+    Timestamp             string  `json:"timestamp"`
+    CameraModel           string  `json:"camera_model"`
+    HasGeographic         bool    `json:"has_geographic"`
+    Latitude              float64 `json:"latitude,omitempty"`
+    Longitude             float64 `json:"longitude,omitempty"`
+    LocationMatchStrategy string  `json:"location_match_strategy"`
+    AdjacentImageWindow   string  `json:"adjacent_image_window,omitempty"`
+    LocationBeforeTime    string  `json:"location_before_time,omitempty"`
+    LocationBeforeDelta   string  `json:"location_before_delta,omitempty"`
+    LocationBeforeSource  string  `json:"location_before_source,omitempty"`
+    LocationAfterTime     string  `json:"location_after_time,omitempty"`
+    LocationAfterDelta    string  `json:"location_after_delta,omitempty"`
+    LocationAfterSource   string  `json:"location_after_source,omitempty"`
+    NearestCity           string  `json:"nearest_city,omitempty"`
+    NearestCityDistanceKm float64 `json:"nearest_city_distance_km,omitempty"`
+}
+
+// UnassignedReportColumns are the column names for the CSV form of the
+// report, in the same order as `UnassignedReportEntry.Row()`.
+var UnassignedReportColumns = []string{
+    "filepath",
+    "reason",
+    "reason_description",
+    "timestamp",
+    "camera_model",
+    "has_geographic",
+    "latitude",
+    "longitude",
+    "location_match_strategy",
+    "adjacent_image_window",
+    "location_before_time",
+    "location_before_delta",
+    "location_before_source",
+    "location_after_time",
+    "location_after_delta",
+    "location_after_source",
+    "nearest_city",
+    "nearest_city_distance_km",
+}
+
+// Row returns the entry as a CSV row.
+func (ure UnassignedReportEntry) Row() []string {
+    latitude := ""
+    longitude := ""
+    if ure.HasGeographic == true {
+        latitude = fmt.Sprintf("%.6f", ure.Latitude)
+        longitude = fmt.Sprintf("%.6f", ure.Longitude)
+    }
+
+    distance := ""
+    if ure.NearestCity != "" {
+        distance = fmt.Sprintf("%.3f", ure.NearestCityDistanceKm)
+    }
+
+    return []string{
+        ure.Filepath,
+        ure.Reason,
+        ure.ReasonDescription,
+        ure.Timestamp,
+        ure.CameraModel,
+        fmt.Sprintf("%v", ure.HasGeographic),
+        latitude,
+        longitude,
+        ure.LocationMatchStrategy,
+        ure.AdjacentImageWindow,
+        ure.LocationBeforeTime,
+        ure.LocationBeforeDelta,
+        ure.LocationBeforeSource,
+        ure.LocationAfterTime,
+        ure.LocationAfterDelta,
+        ure.LocationAfterSource,
+        ure.NearestCity,
+        distance,
+    }
+}
+
+// ReportEntry flattens the record for the unassigned report.
+func (ur UnassignedRecord) ReportEntry() UnassignedReportEntry {
+    gr := ur.Geographic
+    ud := ur.Diagnostics
+
+    ure := UnassignedReportEntry{
+        Filepath:              gr.Filepath,
+        Reason:                string(ur.Reason),
+        ReasonDescription:     ur.Reason.Description(),
+        Timestamp:             gr.Timestamp.Format(time.RFC3339),
+        HasGeographic:         gr.HasGeographic,
+        LocationMatchStrategy: ud.LocationMatchStrategy,
+    }
+
+    if im, ok := gr.Metadata.(geoindex.ImageMetadata); ok == true {
+        ure.CameraModel = im.CameraModel
+    }
+
+    if gr.HasGeographic == true {
+        ure.Latitude = gr.Latitude
+        ure.Longitude = gr.Longitude
+    }
+
+    if ud.AdjacentImageWindow != 0 {
+        ure.AdjacentImageWindow = ud.AdjacentImageWindow.String()
+    }
+
+    if ud.LocationBefore != nil {
+        ure.LocationBeforeTime = ud.LocationBefore.Timestamp.Format(time.RFC3339)
+        ure.LocationBeforeDelta = gr.Timestamp.Sub(ud.LocationBefore.Timestamp).String()
+        ure.LocationBeforeSource = ud.LocationBefore.Filepath
+    }
+
+    if ud.LocationAfter != nil {
+        ure.LocationAfterTime = ud.LocationAfter.Timestamp.Format(time.RFC3339)
+        ure.LocationAfterDelta = ud.LocationAfter.Timestamp.Sub(gr.Timestamp).String()
+        ure.LocationAfterSource = ud.LocationAfter.Filepath
+    }
+
+    if ud.NearestCity != nil {
+        ure.NearestCity = ud.NearestCity.CityAndProvinceState()
+        ure.NearestCityDistanceKm = ud.NearestCityDistanceKm
+    }
+
+    return ure
+}
@@ -0,0 +1,72 @@
+package geoautogroup
+
+import (
+    "testing"
+    "time"
+
+    "github.com/dsoprea/go-geographic-index"
+    "github.com/dsoprea/go-logging"
+)
+
+func TestParseSkipReason(t *testing.T) {
+    sr, err := ParseSkipReason("no_near_city")
+    log.PanicIf(err)
+
+    if sr != SkipReasonNoNearCity {
+        t.Fatalf("Code not parsed correctly: [%s]", sr)
+    }
+
+    // Older unassigned lists have the description.
+    sr, err = ParseSkipReason("matched location record too old")
+    log.PanicIf(err)
+
+    if sr != SkipReasonLocationTooFar {
+        t.Fatalf("Description not parsed correctly: [%s]", sr)
+    }
+
+    _, err = ParseSkipReason("some reason")
+    if err != ErrSkipReasonNotValid {
+        t.Fatalf("Expected failure for unknown reason: %v", err)
+    }
+}
+
+func TestFindGroups_AddUnassigned_Diagnostics(t *testing.T) {
+    locationIndex := geoindex.NewTimeIndex()
+
+    beforeGr := geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc, true, chicagoCoordinates[0], chicagoCoordinates[1], nil)
+    locationIndex.AddWithRecord(beforeGr)
+
+    afterGr := geoindex.NewGeographicRecord(geoindex.SourceGeographicGpx, "file.gpx", epochUtc.Add(time.Hour*20), true, chicagoCoordinates[0], chicagoCoordinates[1], nil)
+    locationIndex.AddWithRecord(afterGr)
+
+    fg := NewFindGroups(locationIndex.Series(), nil, nil)
+
+    im := geoindex.ImageMetadata{
+        CameraModel: "some model",
+    }
+
+    imageGr := geoindex.NewGeographicRecord(geoindex.SourceImageJpeg, "image.jpg", epochUtc.Add(time.Hour*12), false, 0, 0, im)
+
+    fg.addUnassigned(imageGr, SkipReasonLocationTooFar)
+
+    ur := fg.UnassignedRecords()[0]
+    ud := ur.Diagnostics
+
+    if ud.LocationMatchStrategy != LocationMatchStrategyBestGuess {
+        t.Fatalf("Strategy not correct: [%s]", ud.LocationMatchStrategy)
+    } else if ud.LocationBefore != beforeGr || ud.LocationAfter != afterGr {
+        t.Fatalf("Nearest location records not correct: %v %v", ud.LocationBefore, ud.LocationAfter)
+    }
+
+    ure := ur.ReportEntry()
+
+    if ure.Reason != "location_too_old" || ure.ReasonDescription != "matched location record too old" {
+        t.Fatalf("Reason not correct: [%s] [%s]", ure.Reason, ure.ReasonDescription)
+    } else if ure.CameraModel != "some model" {
+        t.Fatalf("Camera model not correct: [%s]", ure.CameraModel)
+    } else if ure.LocationBeforeDelta != "12h0m0s" || ure.LocationAfterDelta != "8h0m0s" {
+        t.Fatalf("Deltas not correct: [%s] [%s]", ure.LocationBeforeDelta, ure.LocationAfterDelta)
+    } else if ure.NearestCity != "" {
+        t.Fatalf("Expected no nearest city without a city index: [%s]", ure.NearestCity)
+    }
+}
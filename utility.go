@@ -1,7 +1,6 @@
 package geoautogroup
 
 import (
-    "bytes"
     "errors"
     "fmt"
     "io"
@@ -11,14 +10,12 @@ import (
     "time"
 
     "crypto/sha1"
-    "encoding/csv"
     "encoding/gob"
 
     "github.com/dsoprea/go-geographic-attractor/index"
     "github.com/dsoprea/go-geographic-attractor/parse"
     "github.com/dsoprea/go-geographic-index"
     "github.com/dsoprea/go-logging"
-    "github.com/dsoprea/go-time-index"
     "github.com/dsoprea/time-to-go"
     "gopkg.in/cheggaaa/pb.v1"
 )
@@ -119,8 +116,13 @@ func GetCityIndex(cityKvFilepath, countriesFilepath, citiesFilepath string, coun
     return ci, nil
 }
 
-// GetImageTimeIndex load an index with images.
-func GetImageTimeIndex(paths []string, imageTimestampSkew time.Duration, cameraModels []string, beVerbose bool) (ti *geoindex.TimeIndex, err error) {
+// GetImageTimeIndex load an index with images. The paths are walked once and
+// the images are then parsed by `concurrency` workers (one per CPU if zero). If
+// `imc` is not nil, images that haven't changed since the last run will be
+// loaded from the cache rather than parsed and the entries for images that no
+// longer exist will be dropped from it. If `ios` is not nil, overrides will
+// be applied to the images that match them. `it` may be nil.
+func GetImageTimeIndex(paths []string, imageTimestampSkew time.Duration, cameraModels []string, concurrency int, imc *ImageMetadataCache, ios *ImageOverrides, it *ImageTracer, beVerbose bool) (ti *geoindex.TimeIndex, err error) {
     defer func() {
         if state := recover(); state != nil {
             err = log.Wrap(state.(error))
@@ -128,46 +130,60 @@ func GetImageTimeIndex(paths []string, imageTimestampSkew time.Duration, cameraM
         }
     }()
 
-    imageFileCount, err := CountImageFiles(paths)
+    imageFilepaths, err := FindImageFiles(paths)
     log.PanicIf(err)
 
     ti = geoindex.NewTimeIndex()
-    gc := geoindex.NewGeographicCollector(ti, nil)
 
     var imageBar *pb.ProgressBar
     if beVerbose == true {
-        imageBar = pb.New(imageFileCount)
+        imageBar = pb.New(len(imageFilepaths))
         imageBar.Prefix("Loading images ")
         imageBar.SetMaxWidth(100)
         imageBar.Start()
     }
 
-    progressCb := func(filepath string) (err error) {
+    progressCb := func(filepath string) {
         if imageBar != nil {
             imageBar.Increment()
         }
-
-        return nil
     }
 
-    gc.SetFileProcessedCallback(progressCb)
+    il := NewImageLoader(imageTimestampSkew, concurrency)
 
-    err = geoindex.RegisterImageFileProcessors(gc, imageTimestampSkew, nil)
-    log.PanicIf(err)
+    if imc != nil {
+        il.SetCache(imc)
+    }
 
-    for _, scanPath := range paths {
-        err := gc.ReadFromPath(scanPath)
-        log.PanicIf(err)
+    if ios != nil {
+        il.SetOverrides(ios)
     }
 
+    il.SetImageTracer(it)
+
+    err = il.Load(imageFilepaths, ti, progressCb)
+    log.PanicIf(err)
+
     if imageBar != nil {
         imageBar.Finish()
     }
 
+    if imc != nil {
+        removed, err := imc.Prune()
+        log.PanicIf(err)
+
+        if removed > 0 {
+            utilityLogger.Infof(nil, "Dropped (%d) image-cache entries for images that no longer exist.", removed)
+        }
+    }
+
     return ti, nil
 }
 
-// GetLocationTimeIndex loads/recovers an index with all found locations.
+// GetLocationTimeIndex loads/recovers an index with all found locations. The
+// database stores one series per data file. Only files that were added or
+// changed are loaded, and the series of files that changed or were removed are
+// dropped.
 func GetLocationTimeIndex(paths []string, locationsDatabaseFilepath string, beVerbose bool) (ti *geoindex.TimeIndex, dbAlreadyExists, dbUpdated bool, err error) {
     defer func() {
         if state := recover(); state != nil {
@@ -205,79 +221,81 @@ func GetLocationTimeIndex(paths []string, locationsDatabaseFilepath string, beVe
         log.Panicf("either location data-paths or an existing location database must be given")
     }
 
-    var streamReader *timetogo.StreamReader
-    var streamIterator *timetogo.Iterator
-    var streamSeriesDataSha1 []byte
-    var existingSisi timetogo.StreamIndexedSequenceInfo
-
     // We were given a database and it already exists, read the state of the
-    // data from it.
-    if dbAlreadyExists == true {
-        streamReader = timetogo.NewStreamReader(locationStream)
+    // data from it. There is one series per data file.
 
+    var existing []locationDatabaseSeries
+    if dbAlreadyExists == true {
         var err error
 
-        streamIterator, err = timetogo.NewIterator(streamReader)
+        existing, err = readLocationDatabase(locationStream)
         if err != nil {
             if log.Is(err, io.EOF) == true {
                 dbAlreadyExists = false
-            } else {
+            } else if log.Is(err, ErrLocationTimeIndexChecksumFail) == true || hasSources == false {
                 utilityLogger.Errorf(nil, err, "There was an issue reading your location database: [%s]. If it is corrupted, please delete the existing one and provide your location data-sources to this command.", locationsDatabaseFilepath)
                 fmt.Printf("There was an issue reading your location database [%s]: [%s]. If it is corrupted, please delete the existing one and provide your location data-sources to this command.\n", locationsDatabaseFilepath, err.Error())
 
                 log.Panic(err)
-            }
-        } else {
-            // NOTE(dustin): !! We don't currently have a plan for cutting our location time-series into separate pieces stored in the stream. For now, just store and retrieve the first.
+            } else {
+                // This is most likely a database written in an older format.
+                // We have the data sources, so just rebuild it.
 
-            count_ := streamIterator.Count()
-            if count_ < 1 {
-                log.Panicf("location database does not represent at least one series: (%d)", count_)
-            } else if count_ > 1 {
-                utilityLogger.Warningf(nil, "More than one series is in the stream, which shouldn't be the case. Just taking the first.")
+                utilityLogger.Warningf(nil, "Location database could not be decoded and will be rebuilt from the data sources: [%s]", err.Error())
+                existing = nil
             }
+        }
 
-            existingSisi = streamIterator.SeriesInfo(0)
-
-            // If we have a database but no data files.
-
-            ts := make(timeindex.TimeSlice, 0)
-            gsodd := timetogo.NewGobSingleObjectDecoderDatasource(&ts)
-
-            existingSf, _, checksumOk, err := streamReader.ReadSeriesWithIndexedInfo(existingSisi, gsodd)
+        // No data sources, so what we have is far as we can go.
+        if dbAlreadyExists == true && hasSources == false {
+            ti, err = mergeLocationDatabaseSeries(existing)
             log.PanicIf(err)
 
-            streamSeriesDataSha1 = existingSf.SourceSha1()
+            utilityLogger.Debugf(nil, "Database has been read and checked, and no data sources were given. Returning data.")
+            return ti, dbAlreadyExists, false, nil
+        }
+    }
 
-            if checksumOk != true {
-                log.PanicIf(ErrLocationTimeIndexChecksumFail)
-            }
+    // If we get here, we have data-paths but not necessarily an existing
+    // location database.
 
-            ti = geoindex.NewTimeIndexFromSlice(ts)
+    // Build a manifest for the current data. We only hash the files whose size
+    // or modification-time no longer match what we stored last time.
 
-            // No data sources, so what we have is far as we can go.
-            if hasSources == false {
-                utilityLogger.Debugf(nil, "Database has been read and checked, and no data sources were given. Returning data.")
-                return ti, dbAlreadyExists, false, nil
-            }
+    existingManifest := make([]locationSourceFile, 0)
+    hasLegacySeries := false
+    for _, lds := range existing {
+        if lds.isLegacy() == true {
+            hasLegacySeries = true
+            continue
         }
+
+        existingManifest = append(existingManifest, lds.Source)
     }
 
-    // If we get here, we have data-paths but not necessarily an existing
-    // location database.
+    dataFilepaths, err := FindDataFiles(paths)
+    log.PanicIf(err)
 
-    // Generate SHA1 for current data if current data was given.
-    filesSha1, err := GetSha1ForPaths(paths)
+    manifest, rehashed, err := getLocationSourceManifest(dataFilepaths, existingManifest)
     log.PanicIf(err)
 
+    utilityLogger.Debugf(nil, "(%d) of (%d) location data files needed to be hashed.", rehashed, len(manifest))
+
+    stale, load := diffLocationSourceManifests(existingManifest, manifest)
+
+    // Files that were touched but didn't change still need their new stat
+    // info saved or they'll be hashed again on every run.
+    statChanged := hasLocationSourceStatChanges(existingManifest, manifest)
+
     if dbAlreadyExists == false {
         utilityLogger.Debugf(nil, "Data sources were given and match, and no database exists. Database will be created.")
     } else {
-        if bytes.Compare(streamSeriesDataSha1, filesSha1) == 0 {
+        if len(stale) == 0 && len(load) == 0 && hasLegacySeries == false && statChanged == false {
             // We have data-sources and a database, and they both match. Return
-            // what we already have. The update process would be a no-op, but
-            // in order for us to get to that step we'd have to load the
-            // location-index, which could be very expensive.
+            // what we already have.
+
+            ti, err = mergeLocationDatabaseSeries(existing)
+            log.PanicIf(err)
 
             utilityLogger.Debugf(nil, "Database has been read and checked. Data sources were given and match. Returning data.")
             return ti, dbAlreadyExists, false, nil
@@ -288,75 +306,76 @@ func GetLocationTimeIndex(paths []string, locationsDatabaseFilepath string, beVe
 
     // The data on the disk and the database *do not* match.
 
-    // Load location-index from data sources.
+    // Carry over the series for the files that didn't change and only load
+    // the files that were added or changed.
 
-    dataFileCount, err := CountDataFiles(paths)
-    log.PanicIf(err)
+    utilityLogger.Debugf(nil, "Location data files: (%d) to load, (%d) changed or removed.", len(load), len(stale))
 
-    ti = geoindex.NewTimeIndex()
-    gc := geoindex.NewGeographicCollector(ti, nil)
+    manifestIndex := make(map[string]locationSourceFile)
+    for _, lsf := range manifest {
+        manifestIndex[lsf.Filepath] = lsf
+    }
+
+    current := make([]locationDatabaseSeries, 0, len(manifest))
+    for _, lds := range existing {
+        if lds.isLegacy() == true {
+            continue
+        } else if _, found := stale[lds.Source.Filepath]; found == true {
+            continue
+        }
+
+        // Pick up the current stat info.
+        lds.Source = manifestIndex[lds.Source.Filepath]
+
+        current = append(current, lds)
+    }
 
     var dataBar *pb.ProgressBar
     if beVerbose == true {
-        dataBar = pb.New(dataFileCount)
+        dataBar = pb.New(len(load))
         dataBar.Prefix("Loading location data ")
         dataBar.SetMaxWidth(100)
         dataBar.Start()
     }
 
-    progressCb := func(filepath string) (err error) {
+    for _, dataFilepath := range load {
+        lds, err := loadLocationSeries(manifestIndex[dataFilepath])
+        log.PanicIf(err)
+
+        current = append(current, lds)
+
         if dataBar != nil {
             dataBar.Increment()
         }
-
-        return nil
-    }
-
-    gc.SetFileProcessedCallback(progressCb)
-
-    err = geoindex.RegisterDataFileProcessors(gc)
-    log.PanicIf(err)
-
-    for _, dataPath := range paths {
-        err := gc.ReadFromPath(dataPath)
-        log.PanicIf(err)
     }
 
     if dataBar != nil {
         dataBar.Finish()
     }
 
-    // Create/update the series data.
-
-    ts := ti.Series()
-
-    sf := timetogo.NewSeriesFooter1(
-        ts[0].Time,
-        ts[len(ts)-1].Time,
-        uint64(len(ts)),
-        filesSha1)
+    ti, err = mergeLocationDatabaseSeries(current)
+    log.PanicIf(err)
 
-    gsoed := timetogo.NewGobSingleObjectEncoderDatasource(ts)
+    if hasDatabase == false {
+        return ti, false, false, nil
+    }
 
-    updater := timetogo.NewUpdater(locationStream, gsoed)
-    updater.AddSeries(sf)
+    // Create/update the series data. Series that we carried over will be
+    // skipped, series that we loaded will be added, and series for changed or
+    // removed files will be dropped.
 
-    totalSize, stats, err := updater.Write()
+    totalSize, adds, skips, drops, err := writeLocationDatabase(locationStream, current)
     log.PanicIf(err)
 
-    if stats.Skips != 0 {
-        log.Panicf("update operation reported skips but shouldn't have: (%d)", stats.Skips)
-    } else if stats.Adds != 1 {
-        log.Panicf("update operation did not report any adds")
+    if adds < len(load) {
+        log.Panicf("update operation reported fewer adds than files loaded: (%d) < (%d)", adds, len(load))
     }
 
-    if stats.Drops > 0 && dbAlreadyExists == false {
+    if drops > 0 && dbAlreadyExists == false {
         log.Panicf("update operation stated that there was a drop but there was no existing DB")
-    } else if stats.Drops == 0 && dbAlreadyExists == true {
-        log.Panicf("update operation stated that there were no drops but there *was* an existing DB and at this stage it *must* be different than our data")
     }
 
-    utilityLogger.Debugf(nil, "Update complete. Location database is (%d) bytes.", totalSize)
+    utilityLogger.Debugf(nil, "Update complete. Location database is (%d) bytes. ADDS=(%d) SKIPS=(%d) DROPS=(%d)", totalSize, adds, skips, drops)
 
     return ti, dbAlreadyExists, true, nil
 }
@@ -405,17 +424,10 @@ func CountImageFiles(paths []string) (count int, err error) {
         }
     }()
 
-    gc := geoindex.NewGeographicCollector(nil, nil)
-
-    err = geoindex.RegisterImageFileProcessors(gc, 0, nil)
+    imageFilepaths, err := FindImageFiles(paths)
     log.PanicIf(err)
 
-    for _, scanPath := range paths {
-        err := gc.ReadFromPath(scanPath)
-        log.PanicIf(err)
-    }
-
-    return gc.VisitedCount(), nil
+    return len(imageFilepaths), nil
 }
 
 func CountDataFiles(paths []string) (count int, err error) {
@@ -444,66 +456,6 @@ func GetCondensedDatetime(t time.Time) string {
     return fmt.Sprintf("%d%02d%02d-%02d%02d%02d", t.Year(), int(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second())
 }
 
-// LoadLocationListFile allows the user to provide a custom list of locations
-// and timestamps. This can be used to patch buggy location data.
-func LoadLocationListFile(ci *geoattractorindex.CityIndex, filepath string, r io.Reader, ti *geoindex.TimeIndex) (recordsCount int, err error) {
-    defer func() {
-        if state := recover(); state != nil {
-            err = log.Wrap(state.(error))
-        }
-    }()
-
-    c := csv.NewReader(r)
-
-    c.Comment = '#'
-    c.FieldsPerRecord = 3
-
-    for i := 0; ; i++ {
-        record, err := c.Read()
-        if err != nil {
-            if err == io.EOF {
-                break
-            }
-
-            log.Panic(err)
-        }
-
-        sourceName := record[0]
-        id := record[1]
-        timestampPhrase := record[2]
-
-        timestamp, err := time.Parse(time.RFC3339, timestampPhrase)
-        if err != nil {
-            log.Panicf("Could not parse [%s]: %s", timestampPhrase, err)
-        }
-
-        cr, err := ci.GetById(sourceName, id)
-        if err != nil {
-            if err == geoattractorindex.ErrNotFound {
-                log.Panicf("Could not find record from source [%s] with ID [%s].", sourceName, id)
-            }
-
-            log.Panic(err)
-        }
-
-        gr := geoindex.NewGeographicRecord(
-            GeographicSourceListfile,
-            filepath,
-            timestamp,
-            true,
-            cr.Latitude,
-            cr.Longitude,
-            nil)
-
-        err = ti.AddWithRecord(gr)
-        log.PanicIf(err)
-
-        recordsCount++
-    }
-
-    return recordsCount, nil
-}
-
 func init() {
     gob.Register(map[string]interface{}{})
 }
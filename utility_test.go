@@ -2,6 +2,7 @@ package geoautogroup
 
 import (
     "bytes"
+    "os"
     "path"
     "testing"
     "time"
@@ -89,7 +90,7 @@ func TestGetImageTimeIndex(t *testing.T) {
         path.Join(testAssetsPath, "test_sources_path1"),
     }
 
-    ti, err := GetImageTimeIndex(paths, time.Duration(0), nil, false)
+    ti, err := GetImageTimeIndex(paths, time.Duration(0), nil, 0, nil, nil, nil, false)
     log.PanicIf(err)
 
     ts := ti.Series()
@@ -375,3 +376,102 @@ func TestGetLocationTimeIndex_JustDataSources_Update_WithChange(t *testing.T) {
         t.Fatalf("Last timestamp not correct: (%d)", last)
     }
 }
+
+func TestGetLocationTimeIndex_JustDataSources_Update_TouchedOnly(t *testing.T) {
+    defer func() {
+        if state := recover(); state != nil {
+            err := log.Wrap(state.(error))
+            log.PrintError(err)
+
+            t.Fatalf("Test failed.")
+        }
+    }()
+
+    // Copy the data file somewhere that we can touch it.
+
+    sourcesPath, err := ioutil.TempDir("", "")
+    log.PanicIf(err)
+
+    defer os.RemoveAll(sourcesPath)
+
+    data, err := ioutil.ReadFile(path.Join(testAssetsPath, "test_sources_path2", "19020100_Portugal.CSV.gpx"))
+    log.PanicIf(err)
+
+    dataFilepath := path.Join(sourcesPath, "19020100_Portugal.CSV.gpx")
+
+    err = ioutil.WriteFile(dataFilepath, data, 0644)
+    log.PanicIf(err)
+
+    paths := []string{
+        sourcesPath,
+    }
+
+    f, err := ioutil.TempFile("", "")
+    log.PanicIf(err)
+
+    defer f.Close()
+
+    filepath := f.Name()
+
+    _, _, dbUpdated, err := GetLocationTimeIndex(paths, filepath, false)
+    log.PanicIf(err)
+
+    if dbUpdated == false {
+        t.Fatalf("DB is supposed to have changed.")
+    }
+
+    // Update the modification-time without changing the content. The file will
+    // be hashed again and the database should be updated with the new stat
+    // info.
+
+    future := time.Now().Add(time.Hour)
+
+    err = os.Chtimes(dataFilepath, future, future)
+    log.PanicIf(err)
+
+    ti, dbAlreadyExists, dbUpdated, err := GetLocationTimeIndex(paths, filepath, false)
+    log.PanicIf(err)
+
+    if dbAlreadyExists == false {
+        t.Fatalf("DB is supposed to already exist.")
+    } else if dbUpdated == false {
+        t.Fatalf("DB is supposed to have been updated with the new stat info.")
+    }
+
+    ts := ti.Series()
+
+    if len(ts) != 215 {
+        t.Fatalf("The record count is not correct: (%d)", len(ts))
+    }
+
+    // Nothing should need to be hashed on the next run.
+
+    g, err := os.Open(filepath)
+    log.PanicIf(err)
+
+    defer g.Close()
+
+    stored, err := readLocationDatabase(g)
+    log.PanicIf(err)
+
+    storedManifest := make([]locationSourceFile, len(stored))
+    for i, lds := range stored {
+        storedManifest[i] = lds.Source
+    }
+
+    _, rehashed, err := getLocationSourceManifest([]string{dataFilepath}, storedManifest)
+    log.PanicIf(err)
+
+    if rehashed != 0 {
+        t.Fatalf("Expected no files to be hashed again: (%d)", rehashed)
+    }
+
+    ti, _, dbUpdated, err = GetLocationTimeIndex(paths, filepath, false)
+    log.PanicIf(err)
+
+    if dbUpdated == true {
+        t.Fatalf("DB is supposed to not have changed.")
+    } else if len(ti.Series()) != 215 {
+        t.Fatalf("The record count is not correct on the second rerun: (%d)", len(ti.Series()))
+    }
+}
@@ -0,0 +1,170 @@
+package geoautogroup
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "math"
+    "os"
+    "strings"
+
+    "encoding/xml"
+
+    "github.com/dsoprea/go-logging"
+)
+
+const (
+    // XmpSidecarExtension is the extension of the sidecar files. It is added
+    // to the full filename of the image (e.g. "IMG_1.JPG.xmp") rather than
+    // replacing its extension so that we never write to the sidecar of a RAW
+    // file that shares the image's name (e.g. "IMG_1.CR2" uses "IMG_1.xmp").
+    XmpSidecarExtension = ".xmp"
+)
+
+// ImageSidecar is the information that we write to an image's XMP sidecar.
+type ImageSidecar struct {
+    HasLocation bool
+    Latitude    float64
+    Longitude   float64
+
+    City    string
+    Country string
+
+    // Group and Trip are written as the location name and as keywords.
+    Group string
+    Trip  string
+}
+
+// XmpSidecarFilepath returns the file-path of the sidecar for the given image.
+func XmpSidecarFilepath(imageFilepath string) string {
+    return imageFilepath + XmpSidecarExtension
+}
+
+// formatXmpCoordinate formats a coordinate as the XMP EXIF schema expects:
+// "DDD,MM.mmmmmmK".
+func formatXmpCoordinate(value float64, positiveRef, negativeRef string) string {
+    ref := positiveRef
+    if value < 0 {
+        ref = negativeRef
+        value = -value
+    }
+
+    // Round to the precision that we print at before splitting so that rounding
+    // up carries into the degrees rather than printing sixty minutes.
+    totalMicrominutes := int64(math.Round(value * 60 * 1e6))
+
+    degrees := totalMicrominutes / (60 * 1e6)
+    microminutes := totalMicrominutes - degrees*60*1e6
+
+    return fmt.Sprintf("%d,%d.%06d%s", degrees, microminutes/1e6, microminutes%1e6, ref)
+}
+
+func escapeXml(s string) string {
+    b := new(bytes.Buffer)
+
+    err := xml.EscapeText(b, []byte(s))
+    log.PanicIf(err)
+
+    return b.String()
+}
+
+// WriteXmp writes the sidecar as an XMP packet.
+func (is ImageSidecar) WriteXmp(w io.Writer) (err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    attributes := make([]string, 0)
+
+    if is.HasLocation == true {
+        attributes = append(attributes,
+            fmt.Sprintf("exif:GPSLatitude=\"%s\"", formatXmpCoordinate(is.Latitude, "N", "S")),
+            fmt.Sprintf("exif:GPSLongitude=\"%s\"", formatXmpCoordinate(is.Longitude, "E", "W")),
+            "exif:GPSVersionID=\"2.2.0.0\"")
+    }
+
+    if is.City != "" {
+        attributes = append(attributes, fmt.Sprintf("photoshop:City=\"%s\"", escapeXml(is.City)))
+    }
+
+    if is.Country != "" {
+        attributes = append(attributes, fmt.Sprintf("photoshop:Country=\"%s\"", escapeXml(is.Country)))
+    }
+
+    if is.Group != "" {
+        attributes = append(attributes, fmt.Sprintf("Iptc4xmpCore:Location=\"%s\"", escapeXml(is.Group)))
+    }
+
+    keywords := make([]string, 0)
+    for _, keyword := range []string{is.Group, is.Trip} {
+        if keyword != "" {
+            keywords = append(keywords, fmt.Sprintf("     <rdf:li>%s</rdf:li>\n", escapeXml(keyword)))
+        }
+    }
+
+    _, err = fmt.Fprintf(w, "<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+    log.PanicIf(err)
+
+    _, err = fmt.Fprintf(w, "<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+    log.PanicIf(err)
+
+    _, err = fmt.Fprintf(w, " <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+    log.PanicIf(err)
+
+    _, err = fmt.Fprintf(w, "  <rdf:Description rdf:about=\"\"\n    xmlns:exif=\"http://ns.adobe.com/exif/1.0/\"\n    xmlns:photoshop=\"http://ns.adobe.com/photoshop/1.0/\"\n    xmlns:Iptc4xmpCore=\"http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/\"\n    xmlns:dc=\"http://purl.org/dc/elements/1.1/\"")
+    log.PanicIf(err)
+
+    for _, attribute := range attributes {
+        _, err = fmt.Fprintf(w, "\n    %s", attribute)
+        log.PanicIf(err)
+    }
+
+    _, err = fmt.Fprintf(w, ">\n")
+    log.PanicIf(err)
+
+    if len(keywords) > 0 {
+        _, err = fmt.Fprintf(w, "   <dc:subject>\n    <rdf:Bag>\n%s    </rdf:Bag>\n   </dc:subject>\n", strings.Join(keywords, ""))
+        log.PanicIf(err)
+    }
+
+    _, err = fmt.Fprintf(w, "  </rdf:Description>\n </rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>\n")
+    log.PanicIf(err)
+
+    return nil
+}
+
+// WriteXmpSidecar writes the sidecar for the given image. If `overwrite` is
+// false and there's already a sidecar, it is left alone and `written` is
+// false.
+func (is ImageSidecar) WriteXmpSidecar(imageFilepath string, overwrite bool) (sidecarFilepath string, written bool, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    sidecarFilepath = XmpSidecarFilepath(imageFilepath)
+
+    flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+    if overwrite == false {
+        flags = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+    }
+
+    f, err := os.OpenFile(sidecarFilepath, flags, 0644)
+    if err != nil {
+        if os.IsExist(err) == true {
+            return sidecarFilepath, false, nil
+        }
+
+        log.Panic(err)
+    }
+
+    defer f.Close()
+
+    err = is.WriteXmp(f)
+    log.PanicIf(err)
+
+    return sidecarFilepath, true, nil
+}
@@ -0,0 +1,109 @@
+package geoautogroup
+
+import (
+    "bytes"
+    "os"
+    "strings"
+    "testing"
+
+    "io/ioutil"
+    "path/filepath"
+
+    "github.com/dsoprea/go-logging"
+)
+
+func TestXmpSidecarFilepath(t *testing.T) {
+    sidecarFilepath := XmpSidecarFilepath("/a/b/IMG_0001.JPG")
+    if sidecarFilepath != "/a/b/IMG_0001.JPG.xmp" {
+        t.Fatalf("Sidecar file-path not correct: [%s]", sidecarFilepath)
+    }
+}
+
+func TestFormatXmpCoordinate(t *testing.T) {
+    latitude := formatXmpCoordinate(41.85003, "N", "S")
+    if latitude != "41,51.001800N" {
+        t.Fatalf("Latitude not correct: [%s]", latitude)
+    }
+
+    longitude := formatXmpCoordinate(-87.65005, "E", "W")
+    if longitude != "87,39.003000W" {
+        t.Fatalf("Longitude not correct: [%s]", longitude)
+    }
+    // Rounds up into the next degree.
+    latitude = formatXmpCoordinate(41.99999999999, "N", "S")
+    if latitude != "42,0.000000N" {
+        t.Fatalf("Rounding not carried into the degrees: [%s]", latitude)
+    }
+}
+
+func TestImageSidecar_WriteXmp(t *testing.T) {
+    is := ImageSidecar{
+        HasLocation: true,
+        Latitude:    41.85003,
+        Longitude:   -87.65005,
+        City:        "Chicago, Illinois",
+        Country:     "United States",
+        Group:       "Chicago & Friends",
+        Trip:        "2018-06-02 Chicago",
+    }
+
+    b := new(bytes.Buffer)
+
+    err := is.WriteXmp(b)
+    log.PanicIf(err)
+
+    xmp := b.String()
+
+    expectedPhrases := []string{
+        "exif:GPSLatitude=\"41,51.001800N\"",
+        "exif:GPSLongitude=\"87,39.003000W\"",
+        "photoshop:City=\"Chicago, Illinois\"",
+        "photoshop:Country=\"United States\"",
+        "Iptc4xmpCore:Location=\"Chicago &amp; Friends\"",
+        "<rdf:li>2018-06-02 Chicago</rdf:li>",
+    }
+
+    for _, phrase := range expectedPhrases {
+        if strings.Contains(xmp, phrase) == false {
+            t.Fatalf("XMP does not contain [%s]:\n%s", phrase, xmp)
+        }
+    }
+}
+
+func TestImageSidecar_WriteXmpSidecar_NoOverwrite(t *testing.T) {
+    tempPath, err := ioutil.TempDir("", "")
+    log.PanicIf(err)
+
+    defer os.RemoveAll(tempPath)
+
+    imageFilepath := filepath.Join(tempPath, "image.jpg")
+    sidecarFilepath := XmpSidecarFilepath(imageFilepath)
+
+    err = ioutil.WriteFile(sidecarFilepath, []byte("existing"), 0644)
+    log.PanicIf(err)
+
+    is := ImageSidecar{
+        City: "Chicago",
+    }
+
+    _, written, err := is.WriteXmpSidecar(imageFilepath, false)
+    log.PanicIf(err)
+
+    if written != false {
+        t.Fatalf("Existing sidecar should not have been overwritten.")
+    }
+
+    data, err := ioutil.ReadFile(sidecarFilepath)
+    log.PanicIf(err)
+
+    if string(data) != "existing" {
+        t.Fatalf("Existing sidecar was changed.")
+    }
+
+    _, written, err = is.WriteXmpSidecar(imageFilepath, true)
+    log.PanicIf(err)
+
+    if written != true {
+        t.Fatalf("Sidecar should have been overwritten.")
+    }
+}